@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/anomaly"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/kodek/sce-greenbutton/pkg/ev"
+	"github.com/kodek/sce-greenbutton/pkg/report"
+	"github.com/kodek/sce-greenbutton/pkg/server"
+)
+
+func runSummary(doc *report.Document, stderr io.Writer) error {
+	meters, _, err := readInputs(doc, stderr)
+	if err != nil {
+		return err
+	}
+	return forEachMeter(doc, meters, func(s *report.Section, csv csvparser.CsvFile) error {
+		hours, days, err := hoursAndDays(csv)
+		if err != nil {
+			return err
+		}
+		if s.Dataset, err = report.NewDataset(csv, hours, days); err != nil {
+			return err
+		}
+		if *weatherFilePath != "" {
+			if s.Weather, err = weatherModel(days); err != nil {
+				return err
+			}
+		}
+		if *anomalies || *evSessions {
+			plan, err := findPlan(*selectedPlan)
+			if err != nil {
+				return err
+			}
+			if *anomalies {
+				s.Anomalies = report.NewAnomalies(anomaly.Detect(days, plan, anomaly.DefaultOptions()))
+			}
+			if *evSessions {
+				s.EvSessions = report.NewEvSessions(ev.DetectSessions(csv, plan, ev.DefaultOptions()))
+			}
+		}
+		if *solarHealth {
+			if s.Solar, err = solarHealthReport(days); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func runBill(doc *report.Document, stderr io.Writer) error {
+	plan, err := findPlan(*selectedPlan)
+	if err != nil {
+		return err
+	}
+	meters, _, err := readInputs(doc, stderr)
+	if err != nil {
+		return err
+	}
+	err = forEachMeter(doc, meters, func(s *report.Section, csv csvparser.CsvFile) error {
+		_, days, err := hoursAndDays(csv)
+		if err != nil {
+			return err
+		}
+		s.Bills = []report.Bill{report.NewBill(days, plan, true)}
+		if wantCharts() {
+			s.Profile = report.NewProfile(days)
+			s.Heatmap = report.NewHeatmap(days)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if *senseFilePath != "" {
+		doc.Devices, err = deviceCosts(*senseFilePath, plan)
+	}
+	return err
+}
+
+func runCompare(doc *report.Document, stderr io.Writer) error {
+	meters, _, err := readInputs(doc, stderr)
+	if err != nil {
+		return err
+	}
+	return forEachMeter(doc, meters, func(s *report.Section, csv csvparser.CsvFile) error {
+		_, days, err := hoursAndDays(csv)
+		if err != nil {
+			return err
+		}
+		s.Domestic = report.NewDomesticBill(costcalculator.CalculateDomesticForDays(days))
+		for _, plan := range touPlans() {
+			s.Bills = append(s.Bills, report.NewBill(days, plan, wantCharts()))
+		}
+		if wantCharts() {
+			s.Profile = report.NewProfile(days)
+			s.Heatmap = report.NewHeatmap(days)
+		}
+		if *monteCarloSamples > 0 {
+			s.Uncertainty, err = planUncertainty(days)
+		}
+		return err
+	})
+}
+
+func runProfile(doc *report.Document, stderr io.Writer) error {
+	meters, _, err := readInputs(doc, stderr)
+	if err != nil {
+		return err
+	}
+	return forEachMeter(doc, meters, func(s *report.Section, csv csvparser.CsvFile) error {
+		_, days, err := hoursAndDays(csv)
+		if err != nil {
+			return err
+		}
+		s.Profile = report.NewProfile(days)
+		if *usageCharts {
+			plan, err := findPlan(*selectedPlan)
+			if err != nil {
+				return err
+			}
+			s.UsageCharts = report.NewUsageCharts(days, plan)
+		}
+		if wantCharts() {
+			s.Heatmap = report.NewHeatmap(days)
+		}
+		return nil
+	})
+}
+
+// validationError is returned when validate finds problems. The report is still written.
+type validationError struct {
+	problems int
+}
+
+func (e *validationError) Error() string {
+	return fmt.Sprintf("found %d problems", e.problems)
+}
+
+func runValidate(doc *report.Document, stderr io.Writer) error {
+	meters, conflicts, err := readInputs(doc, stderr)
+	if err != nil {
+		return err
+	}
+	problems := len(conflicts)
+	for _, m := range meters {
+		quality := report.NewQuality(analyzer.CheckQuality(m.Rows), len(m.Rows))
+		doc.Meters = append(doc.Meters, report.Section{Meter: m.Meter.String(), Quality: quality})
+		problems += quality.Problems()
+	}
+	if problems > 0 {
+		return &validationError{problems: problems}
+	}
+	return nil
+}
+
+func runSimulate(doc *report.Document, stderr io.Writer) error {
+	electrify := *heatPumpHeatLoss > 0 || *waterHeaterDailyKwh > 0
+	if !electrify && !*sizing && *quoteCost <= 0 {
+		return usageErrorf("nothing to simulate; set --heat_pump_heat_loss, --water_heater_daily_kwh, --sizing or --quote_cost")
+	}
+	if *heatPumpHeatLoss > 0 && *weatherFilePath == "" {
+		return usageErrorf("--heat_pump_heat_loss requires --weather_file_path")
+	}
+	if *allElectric {
+		if err := costcalculator.CheckServiceType(costcalculator.AllElectricService); err != nil {
+			return usageErrorf("--all_electric: %v", err)
+		}
+	}
+	meters, _, err := readInputs(doc, stderr)
+	if err != nil {
+		return err
+	}
+	return forEachMeter(doc, meters, func(s *report.Section, csv csvparser.CsvFile) error {
+		hours, days, err := hoursAndDays(csv)
+		if err != nil {
+			return err
+		}
+		if electrify {
+			if s.Electrification, err = electrification(hours); err != nil {
+				return err
+			}
+		}
+		if *sizing {
+			if s.Sizing, err = sizingReport(hours); err != nil {
+				return err
+			}
+		}
+		if *quoteCost > 0 {
+			s.Projection, err = projection(hours, days)
+		}
+		return err
+	})
+}
+
+// runServe serves the API until the server fails. It doesn't add to the document.
+func runServe(_ *report.Document, stderr io.Writer) error {
+	opts := server.DefaultOptions(touPlans())
+	if *lenient {
+		opts.ParseOptions.Mode = csvparser.Lenient
+	}
+	opts.ParseOptions.MaxErrors = *maxParseErrors
+	_, _ = fmt.Fprintf(stderr, "Serving on http://%s/\n", *listenAddress)
+	return http.ListenAndServe(*listenAddress, server.New(opts))
+}
+
+// readInputs parses and merges the downloads given by --input_file_path and splits them by meter. Malformed lines and
+// conflicting readings are reported on stderr, and the conflicts are added to the document.
+func readInputs(doc *report.Document, stderr io.Writer) ([]analyzer.MeterFile, []csvparser.Conflict, error) {
+	if len(inputFilePaths) == 0 {
+		return nil, nil, usageErrorf("must specify --input_file_path")
+	}
+	parseOptions := csvparser.DefaultParseOptions()
+	if *lenient {
+		parseOptions.Mode = csvparser.Lenient
+	}
+	parseOptions.MaxErrors = *maxParseErrors
+	csv, conflicts, err := loadInputs(inputFilePaths, parseOptions, stderr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(csv) == 0 {
+		return nil, nil, fmt.Errorf("no readings in %s", inputFilePaths.String())
+	}
+	for _, c := range conflicts {
+		_, _ = fmt.Fprintf(stderr, "Conflicting readings for %s at %s. Kept %.3f kWh from %s out of %+v\n", c.Meter, c.StartTime, c.Chosen.UsageKwh, c.Chosen.Source, c.Values)
+	}
+	if len(conflicts) > 0 {
+		doc.Conflicts = report.NewConflicts(conflicts)
+	}
+	return analyzer.SplitByMeter(csv), conflicts, nil
+}
+
+// forEachMeter adds a section for each meter to the document and calls fn with it and the meter's prepared
+// readings. With several meters, it adds one more section with the readings of all of them added up.
+func forEachMeter(doc *report.Document, meters []analyzer.MeterFile, fn func(s *report.Section, csv csvparser.CsvFile) error) error {
+	prepared := make([]analyzer.MeterFile, 0, len(meters))
+	for _, m := range meters {
+		s := report.Section{Meter: m.Meter.String()}
+		rows, err := prepareReadings(&s, m.Rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(&s, rows); err != nil {
+			return err
+		}
+		doc.Meters = append(doc.Meters, s)
+		prepared = append(prepared, analyzer.MeterFile{Meter: m.Meter, Rows: rows})
+	}
+	if len(meters) > 1 {
+		s := report.Section{Meter: "All meters", Combined: true}
+		if err := fn(&s, analyzer.CombineMeters(prepared)); err != nil {
+			return err
+		}
+		doc.Meters = append(doc.Meters, s)
+	}
+	return nil
+}
+
+// prepareReadings records the data quality of a single meter's readings in the section and applies the quality
+// filters and imputation requested by flags.
+func prepareReadings(s *report.Section, csv csvparser.CsvFile) (csvparser.CsvFile, error) {
+	s.Quality = report.NewQuality(analyzer.CheckQuality(csv), len(csv))
+	if *minReadingQuality != "" {
+		minQuality, err := csvparser.ParseReadingQuality(*minReadingQuality)
+		if err != nil {
+			return nil, usageErrorf("%v", err)
+		}
+		if *reestimateLowQuality {
+			strategy := analyzer.ImputeLinear
+			if *impute != "" {
+				strategy, err = analyzer.ParseImputationStrategy(*impute)
+				if err != nil {
+					return nil, usageErrorf("%v", err)
+				}
+			}
+			csv = analyzer.ReestimateBelowQuality(csv, minQuality, strategy)
+			s.Notes = append(s.Notes, fmt.Sprintf("Re-estimated readings below quality '%s'.", minQuality))
+		} else {
+			csv = analyzer.FilterByQuality(csv, minQuality)
+			s.Notes = append(s.Notes, fmt.Sprintf("Excluded readings below quality '%s'.", minQuality))
+		}
+	}
+	if *impute != "" {
+		strategy, err := analyzer.ParseImputationStrategy(*impute)
+		if err != nil {
+			return nil, usageErrorf("%v", err)
+		}
+		csv, _ = analyzer.FillGaps(csv, strategy)
+		s.Notes = append(s.Notes, fmt.Sprintf("Filled missing readings with strategy '%s'.", *impute))
+	}
+	return csv, nil
+}
+
+// hoursAndDays aggregates the readings into hours and days.
+func hoursAndDays(csv csvparser.CsvFile) ([]analyzer.UsageHour, []analyzer.UsageDay, error) {
+	hours, err := analyzer.AggregateIntoHourWindows(csv)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(hours) == 0 {
+		return nil, nil, fmt.Errorf("no readings")
+	}
+	days, err := analyzer.SplitByDay(hours)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hours, days, nil
+}
+
+// wantCharts returns whether the output is charted, in which case the bill, compare and profile commands add the
+// monthly statements, load shapes and hourly usage that the charts need.
+func wantCharts() bool {
+	return *outputFormat == string(report.HTML)
+}
+
+// touPlans returns the plans to compare, with the demand charges requested by flags.
+func touPlans() []costcalculator.TouPlan {
+	plans := []costcalculator.TouPlan{costcalculator.NewTouDAPlan(), costcalculator.NewTouDPrime(), costcalculator.NewTouD58()}
+	if *demandChargePerKw == 0 && *onPeakDemandChargePerKw == 0 {
+		return plans
+	}
+	for i := range plans {
+		plans[i] = costcalculator.WithDemandCharges(plans[i], *demandChargePerKw, *onPeakDemandChargePerKw)
+	}
+	return plans
+}
+
+// findPlan returns the plan with the given name.
+func findPlan(name string) (costcalculator.TouPlan, error) {
+	for _, p := range touPlans() {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, usageErrorf("unknown plan '%s'", name)
+}
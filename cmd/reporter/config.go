@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+)
+
+// loadConfig reads a JSON object of default flag values, keyed by flag name. For example:
+//
+//	{"plan": "TOU-D-5-8PM", "use_medical_baseline": false, "input_file_path": ["2020.csv", "2021.csv"]}
+func loadConfig(path string) (map[string]interface{}, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	if err := json.Unmarshal(file, &values); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// applyConfig sets the flags of fs that were not given on the command line to the values from the config. Values for
+// flags that belong to other subcommands are ignored, so a single config can be shared by all of them.
+func applyConfig(fs *flag.FlagSet, values map[string]interface{}) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if flag.CommandLine.Lookup(name) == nil {
+			return fmt.Errorf("config has unknown flag '%s'", name)
+		}
+		f := fs.Lookup(name)
+		if f == nil || explicit[name] {
+			continue
+		}
+		settings, err := configValues(values[name])
+		if err != nil {
+			return fmt.Errorf("config value of '%s': %w", name, err)
+		}
+		for _, s := range settings {
+			if err := f.Value.Set(s); err != nil {
+				return fmt.Errorf("config value of '%s': %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configValues converts a JSON value to flag values. Lists set a repeatable flag once per element.
+func configValues(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case bool:
+		return []string{strconv.FormatBool(v)}, nil
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}, nil
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, e := range v {
+			values, err := configValues(e)
+			if err != nil {
+				return nil, err
+			}
+			if len(values) != 1 {
+				return nil, fmt.Errorf("nested lists are not supported")
+			}
+			out = append(out, values[0])
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported value %v", v)
+}
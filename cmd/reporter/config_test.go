@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig_InvalidJson_Error(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"plan": `), 0644))
+
+	_, err := loadConfig(path)
+
+	assert.Error(t, err)
+}
+
+func TestApplyConfig_CommandLineTakesPrecedence(t *testing.T) {
+	resetFlags(t)
+	fs := newFlagSet(findCommand("bill"), ioutil.Discard)
+	assert.NoError(t, fs.Parse([]string{"--plan", "TOU-D-A"}))
+
+	err := applyConfig(fs, map[string]interface{}{"plan": "TOU-D-5-8PM", "demand_charge_per_kw": 12.5, "use_medical_baseline": false})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "TOU-D-A", *selectedPlan)
+	assert.Equal(t, 12.5, *demandChargePerKw)
+	assert.Equal(t, "false", flag.Lookup("use_medical_baseline").Value.String())
+}
+
+func TestApplyConfig_FlagOfOtherCommand_Ignored(t *testing.T) {
+	resetFlags(t)
+	fs := newFlagSet(findCommand("profile"), ioutil.Discard)
+
+	err := applyConfig(fs, map[string]interface{}{"sizing": true})
+
+	assert.NoError(t, err)
+	assert.False(t, *sizing)
+}
+
+func TestApplyConfig_UnknownFlag_Error(t *testing.T) {
+	resetFlags(t)
+	fs := newFlagSet(findCommand("profile"), ioutil.Discard)
+
+	err := applyConfig(fs, map[string]interface{}{"palm": "TOU-D-A"})
+
+	assert.EqualError(t, err, "config has unknown flag 'palm'")
+}
+
+func TestApplyConfig_InvalidValue_Error(t *testing.T) {
+	resetFlags(t)
+	fs := newFlagSet(findCommand("bill"), ioutil.Discard)
+
+	err := applyConfig(fs, map[string]interface{}{"demand_charge_per_kw": "lots"})
+
+	assert.Error(t, err)
+}
+
+func TestApplyConfig_List_SetsRepeatedFlag(t *testing.T) {
+	resetFlags(t)
+	fs := newFlagSet(findCommand("profile"), ioutil.Discard)
+
+	err := applyConfig(fs, map[string]interface{}{"input_file_path": []interface{}{"a.csv", "b.csv"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, stringList{"a.csv", "b.csv"}, inputFilePaths)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/kodek/sce-greenbutton/pkg/weather"
+)
+
+// Flags are registered on flag.CommandLine, next to the ones defined by the library packages. Each subcommand
+// copies the ones it accepts into its own flag set.
+
+var inputFilePaths stringList
+
+func init() {
+	flag.Var(&inputFilePaths, "input_file_path", "Path to input CSV or XML file from GreenButton, or to a directory of them. May be gzip compressed, and CSV files may be zip compressed. Repeat the flag or separate with commas to merge several downloads.")
+	// --anomaly_plan was the name of --plan before EV sessions and bills shared it.
+	flag.Var(flag.Lookup("plan").Value, "anomaly_plan", "Deprecated alias of --plan.")
+}
+
+var configPath = flag.String("config", "", "Optional path to a JSON file with default flag values, keyed by flag name. Flags given on the command line take precedence.")
+var outputFormat = flag.String("format", "text", "Output format: text, json, csv or html. The JSON schema is documented in pkg/report. The HTML page is self-contained and charts the bills and usage.")
+var lenient = flag.Bool("lenient", false, "Skip malformed data lines instead of failing. Lines with an unknown reading quality are kept as actual readings.")
+var maxParseErrors = flag.Int("max_parse_errors", 0, "Fail after this many malformed data lines. 0 means no limit.")
+var impute = flag.String("impute", "", "If set, fills missing readings before calculating. One of zero, linear or prior_week.")
+var minReadingQuality = flag.String("min_reading_quality", "", "If set, readings worse than this quality (missing, estimated, actual or validated) are excluded.")
+var reestimateLowQuality = flag.Bool("reestimate_low_quality", false, "Re-estimate readings excluded by --min_reading_quality instead of dropping them. Uses the --impute strategy, or linear if unset.")
+var demandChargePerKw = flag.Float64("demand_charge_per_kw", 0, "Optional demand charge in $/kW of the monthly maximum demand, added to every plan.")
+var onPeakDemandChargePerKw = flag.Float64("on_peak_demand_charge_per_kw", 0, "Optional demand charge in $/kW of the monthly maximum on-peak demand, added to every plan.")
+var weatherFilePath = flag.String("weather_file_path", "", "Optional path to a CSV of hourly or daily temperatures. When set, prints a weather-normalized usage model.")
+var weatherBaselineEnd = flag.String("weather_baseline_end", "", "Optional date (YYYY-MM-DD). When set with --weather_file_path, the model is fitted on the days before it and weather-adjusted savings are reported for the days after.")
+var anomalies = flag.Bool("anomalies", false, "Print hours and days with unusual usage.")
+var evSessions = flag.Bool("ev_sessions", false, "Print likely EV charging sessions and the savings from moving them to the cheapest hours.")
+var selectedPlan = flag.String("plan", "TOU-D-PRIME", "Plan used to price bills, anomalies, EV charging sessions and projections, and whose TOU periods color the profile charts.")
+
+var usageCharts = flag.Bool("charts", false, "Print terminal charts of the monthly and daily usage and a heatmap of the average week, colored by the TOU periods of --plan.")
+var solarHealth = flag.Bool("solar_health", false, "Print days and weeks with unusually low solar production, and the yearly degradation.")
+var heatPumpHeatLoss = flag.Float64("heat_pump_heat_loss", 0, "If set, simulates a heat pump for a house that loses this many BTU/h per °F below the balance point. Requires --weather_file_path.")
+var heatPumpBalancePoint = flag.Float64("heat_pump_balance_point", weather.BaseTemperatureF, "Outdoor temperature in °F below which the simulated heat pump heats.")
+var waterHeaterDailyKwh = flag.Float64("water_heater_daily_kwh", 0, "If set, simulates a heat pump water heater delivering this much heat per day, in kWh.")
+var waterHeaterCop = flag.Float64("water_heater_cop", 3.5, "COP of the simulated heat pump water heater.")
+var waterHeaterHours = flag.String("water_heater_hours", "10,11,12,13,14", "Comma-separated hours of the day in which the simulated water heater runs.")
+var allElectric = flag.Bool("all_electric", false, "Bill the simulated electrification with the all-electric baseline allocation.")
+var sizing = flag.Bool("sizing", false, "Search solar and battery sizes and print the best by net present value and payback.")
+var pvCostPerKw = flag.Float64("pv_cost_per_kw", 3000, "Installed solar cost in $/kW for --sizing.")
+var batteryCostPerKwh = flag.Float64("battery_cost_per_kwh", 1000, "Installed battery cost in $/kWh for --sizing.")
+var incentiveShare = flag.Float64("incentive_share", 0.3, "Fraction of the system cost covered by incentives for --sizing.")
+var systemLifetimeYears = flag.Int("system_lifetime_years", 25, "System lifetime in years for --sizing.")
+var netBillingExportRate = flag.Float64("net_billing_export_rate", 0, "If set, --sizing credits exports at this $/kWh rate, as under the Net Billing Tariff, instead of NEM 2.")
+var quoteCost = flag.Float64("quote_cost", 0, "If set, projects the cash flows of an installer quote with this upfront cost after incentives, sized by --quote_pv_kw and --quote_battery_kwh.")
+var quotePvKw = flag.Float64("quote_pv_kw", 0, "Solar size in kW of the quote.")
+var quoteBatteryKwh = flag.Float64("quote_battery_kwh", 0, "Battery size in kWh of the quote.")
+var energyEscalation = flag.Float64("energy_escalation", 0.04, "Yearly growth of energy rates for --quote_cost.")
+var fixedChargeEscalation = flag.Float64("fixed_charge_escalation", 0.03, "Yearly growth of the fixed charges for --quote_cost.")
+var nbcEscalation = flag.Float64("nbc_escalation", 0.04, "Yearly growth of the non-bypassable charges for --quote_cost.")
+var pvDegradation = flag.Float64("pv_degradation", 0.005, "Yearly loss of solar production for --quote_cost.")
+var discountRate = flag.Float64("discount_rate", 0.05, "Discount rate for --quote_cost.")
+var monteCarloSamples = flag.Int("monte_carlo_samples", 0, "If set, resamples the historical days this many times and prints how confident the plan ranking is.")
+var listenAddress = flag.String("listen", "localhost:8080", "Address on which serve listens for HTTP requests.")
+var senseFilePath = flag.String("sense_file_path", "", "Optional path to a Sense data export. When set, prints a per-device cost ranking.")
+
+// commonFlags are accepted by every command.
+var commonFlags = []string{"config", "format"}
+
+// inputFlags select and parse the Green Button downloads.
+var inputFlags = []string{"input_file_path", "lenient", "max_parse_errors"}
+
+// readingFlags clean up the readings before they are analyzed.
+var readingFlags = []string{"impute", "min_reading_quality", "reestimate_low_quality"}
+
+// pricingFlags change how plans are billed. Some are defined by the costcalculator package.
+var pricingFlags = []string{"demand_charge_per_kw", "on_peak_demand_charge_per_kw", "baseline_region", "use_medical_baseline",
+	"all_electric_summer_daily_allocation", "all_electric_winter_daily_allocation"}
+
+// newFlagSet returns a flag set for a subcommand with the named flags from flag.CommandLine. The flags share their
+// values with flag.CommandLine.
+func newFlagSet(cmd *command, output io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(output)
+	for _, name := range cmd.flags {
+		f := flag.CommandLine.Lookup(name)
+		if f == nil {
+			panic(fmt.Sprintf("command %s uses undefined flag --%s", cmd.name, name))
+		}
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: reporter %s [flags]\n\n%s\n\nFlags:\n", cmd.name, cmd.description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// concat joins flag name lists.
+func concat(lists ...[]string) []string {
+	out := make([]string, 0)
+	for _, l := range lists {
+		out = append(out, l...)
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+)
+
+// stringList is a flag that can be repeated or given a comma-separated list.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// inputExtensions are the file types read from input directories.
+var inputExtensions = []string{".csv", ".xml", ".gz", ".zip"}
+
+// expandInputPaths replaces each directory with the Green Button files it contains.
+func expandInputPaths(paths []string) ([]string, error) {
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			out = append(out, p)
+			continue
+		}
+		entries, err := ioutil.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		found := make([]string, 0)
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			for _, ext := range inputExtensions {
+				if strings.HasSuffix(strings.ToLower(e.Name()), ext) {
+					found = append(found, filepath.Join(p, e.Name()))
+					break
+				}
+			}
+		}
+		sort.Strings(found)
+		out = append(out, found...)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no input files found in %s", strings.Join(paths, ", "))
+	}
+	return out, nil
+}
+
+// loadInputs parses every input file and merges them into a single file. Overlapping readings from newer files win.
+// Malformed lines skipped in Lenient mode are reported on stderr.
+func loadInputs(paths []string, opts csvparser.ParseOptions, stderr io.Writer) (csvparser.CsvFile, []csvparser.Conflict, error) {
+	expanded, err := expandInputPaths(paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	sources := make([]csvparser.Source, 0, len(expanded))
+	for _, p := range expanded {
+		rows, err := loadInput(p, opts, stderr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to read %s: %w", p, err)
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		sources = append(sources, csvparser.Source{
+			Name:       p,
+			Rows:       rows,
+			Downloaded: info.ModTime(),
+		})
+	}
+	if len(sources) == 1 {
+		return sources[0].Rows, nil, nil
+	}
+	merged, conflicts := csvparser.Merge(sources)
+	return merged, conflicts, nil
+}
+
+func loadInput(path string, opts csvparser.ParseOptions, stderr io.Writer) (csvparser.CsvFile, error) {
+	file, err := csvparser.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	rows, warnings, err := csvparser.ParseDownload(file, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		if warning.Recovered != nil {
+			_, _ = fmt.Fprintf(stderr, "Kept malformed line in %s as an actual reading: %s\n", path, warning)
+			continue
+		}
+		_, _ = fmt.Fprintf(stderr, "Skipped malformed line in %s: %s\n", path, warning)
+	}
+	return rows, nil
+}
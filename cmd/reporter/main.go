@@ -1,94 +1,188 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
-	"text/tabwriter"
 
-	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/report"
+)
 
-	"github.com/kodek/sce-greenbutton/pkg/analyzer"
-	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+// Exit codes.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	// exitUsage is returned for unknown commands, bad flags and missing required flags.
+	exitUsage = 2
 )
 
-var inputFilePath = flag.String("input_file_path", "", "Path to input CSV file from GreenButton.")
+// command is a reporter subcommand.
+type command struct {
+	name        string
+	description string
+	// flags are the names of the flags from flag.CommandLine that the command accepts.
+	flags []string
+	// run adds the command's output to the document. Progress and warnings go to stderr.
+	run func(doc *report.Document, stderr io.Writer) error
+}
+
+var commands = []*command{
+	{
+		name:        "summary",
+		description: "Prints the dataset summary and peak demand. Optionally prints weather, anomaly, EV and solar analyses.",
+		flags: concat(commonFlags, inputFlags, readingFlags, pricingFlags, []string{"plan", "anomaly_plan", "weather_file_path",
+			"weather_baseline_end", "anomalies", "ev_sessions", "solar_health"}),
+		run: runSummary,
+	},
+	{
+		name:        "bill",
+		description: "Prints the bill of a single plan, chosen with --plan.",
+		flags:       concat(commonFlags, inputFlags, readingFlags, pricingFlags, []string{"plan", "sense_file_path"}),
+		run:         runBill,
+	},
+	{
+		name:        "compare",
+		description: "Prints the bills of every plan side by side.",
+		flags:       concat(commonFlags, inputFlags, readingFlags, pricingFlags, []string{"monte_carlo_samples"}),
+		run:         runCompare,
+	},
+	{
+		name:        "profile",
+		description: "Prints the baseload, the evening ramp and the average load shape by season and day type. Optionally charts the usage in the terminal.",
+		flags:       concat(commonFlags, inputFlags, readingFlags, []string{"charts", "plan"}),
+		run:         runProfile,
+	},
+	{
+		name:        "validate",
+		description: "Checks the downloads for gaps, duplicate timestamps, partial days and conflicting readings. Exits with a non-zero code if any are found.",
+		flags:       concat(commonFlags, inputFlags),
+		run:         runValidate,
+	},
+	{
+		name:        "simulate",
+		description: "Simulates electrification, solar and battery sizing, or an installer quote.",
+		flags: concat(commonFlags, inputFlags, readingFlags, pricingFlags, []string{"plan", "weather_file_path",
+			"heat_pump_heat_loss", "heat_pump_balance_point", "water_heater_daily_kwh", "water_heater_cop", "water_heater_hours", "all_electric",
+			"sizing", "pv_cost_per_kw", "battery_cost_per_kwh", "incentive_share", "system_lifetime_years", "net_billing_export_rate",
+			"quote_cost", "quote_pv_kw", "quote_battery_kwh", "energy_escalation", "fixed_charge_escalation", "nbc_escalation",
+			"pv_degradation", "discount_rate"}),
+		run: runSimulate,
+	},
+	{
+		name:        "serve",
+		description: "Serves the calculators over a local HTTP JSON API. GET /openapi.json describes the endpoints.",
+		flags:       concat([]string{"config", "listen", "lenient", "max_parse_errors"}, pricingFlags),
+		run:         runServe,
+	},
+}
+
+// usageError is an error caused by how the reporter was invoked.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usageErrorf(format string, a ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, a...)}
+}
 
 func main() {
-	flag.Parse()
-	if *inputFilePath == "" {
-		panic("Must specify --input_file_path")
-	}
-	file, err := ioutil.ReadFile(*inputFilePath)
-	if err != nil {
-		panic(err)
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the subcommand in args and returns the exit code.
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
 	}
-	csv, err := csvparser.Parse(string(file))
-	if err != nil {
-		panic(err)
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			cmd := findCommand(args[1])
+			if cmd == nil {
+				_, _ = fmt.Fprintf(stderr, "reporter: unknown command '%s'\n", args[1])
+				return exitUsage
+			}
+			newFlagSet(cmd, stdout).Usage()
+			return exitOK
+		}
+		printUsage(stdout)
+		return exitOK
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight)
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		_, _ = fmt.Fprintf(stderr, "reporter: unknown command '%s'\n\n", args[0])
+		printUsage(stderr)
+		return exitUsage
+	}
+	fs := newFlagSet(cmd, stderr)
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		_, _ = fmt.Fprintf(stderr, "reporter %s: unexpected arguments %v\n", cmd.name, fs.Args())
+		return exitUsage
+	}
+	if *configPath != "" {
+		values, err := loadConfig(*configPath)
+		if err == nil {
+			err = applyConfig(fs, values)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "reporter %s: %v\n", cmd.name, err)
+			return exitUsage
+		}
+	}
 
-	hours, err := analyzer.AggregateIntoHourWindows(csv)
+	format, err := report.ParseFormat(*outputFormat)
 	if err != nil {
-		panic(err)
+		_, _ = fmt.Fprintf(stderr, "reporter %s: %v\n", cmd.name, err)
+		return exitUsage
 	}
-	fmt.Printf("Read %d data points (%d days).\n", len(csv), len(hours)/24)
-	fmt.Printf("First date: %+v\n", hours[0].StartTime())
-	fmt.Printf("Last date: %+v\n", hours[len(hours)-1].StartTime())
 
-	totalUsage := 0.0
-	for _, hr := range csv {
-		totalUsage += hr.UsageKwh
+	doc := report.NewDocument(cmd.name)
+	err = cmd.run(doc, stderr)
+	var validation *validationError
+	if err == nil || errors.As(err, &validation) {
+		if writeErr := report.Write(stdout, doc, format); writeErr != nil {
+			_, _ = fmt.Fprintf(stderr, "reporter %s: %v\n", cmd.name, writeErr)
+			return exitFailure
+		}
 	}
-	fmt.Printf("Total usage: %.2f kWh.\n\n", totalUsage)
-
-	days, err := analyzer.SplitByDay(hours)
 	if err != nil {
-		panic(err)
+		_, _ = fmt.Fprintf(stderr, "reporter %s: %v\n", cmd.name, err)
+		var usage *usageError
+		if errors.As(err, &usage) {
+			return exitUsage
+		}
+		return exitFailure
 	}
+	return exitOK
+}
 
-	_, _ = fmt.Fprintf(w, "Start\t%s\t\n", days[0].Day.Format("2006-01-02"))
-	_, _ = fmt.Fprintf(w, "End (excl.)\t%s\t\n", days[len(days)-1].EndTime().Format("2006-01-02"))
-	_, _ = fmt.Fprintf(w, "Time\t%d\tDays\t\n", len(days))
-	_, _ = fmt.Fprintln(w)
-
-	domesticBreakdown := costcalculator.CalculateDomesticForDays(days)
-	fmt.Printf("Domestic estimate: %+v\n", domesticBreakdown)
-
-	for _, plan := range []costcalculator.TouPlan{costcalculator.NewTouDAPlan(), costcalculator.NewTouDPrime(), costcalculator.NewTouD58()} {
-
-		touBill := costcalculator.CalculateWithTouPlan(days, plan)
-
-		_, _ = fmt.Fprintf(w, "Name\t%s\t\t\n", plan.Name())
-		_, _ = fmt.Fprintf(w, "Energy exported\t%.2f\tKWh\t\n", -1*touBill.EnergyExported())
-		_, _ = fmt.Fprintf(w, "Energy imported\t%.2f\tKWh\t\n", touBill.EnergyImported())
-		_, _ = fmt.Fprintf(w, "Net usage\t%.2f\tKWh\t\n", touBill.NetEnergyUsage())
-		_, _ = fmt.Fprintf(w, "Average daily usage\t%.2f\tKWh\t\n", touBill.AverageDailyUsage())
-
-		_, _ = fmt.Fprintf(w, "-------\t-------\t\n")
-		for period, usage := range touBill.UsageByPeriod() {
-			_, _ = fmt.Fprintf(w, "%s\t%.2f\tKWh\t\n", period.Name(), usage)
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
 		}
-		_, _ = fmt.Fprintf(w, "-------\t-------\t\n")
+	}
+	return nil
+}
 
-		touNemCost := touBill.NetMeteredCostNoBaseline()
-		basicCharge := touBill.TotalBasicCharge()
-		touTax := touBill.Taxes()
-		nbc := touBill.NonBypassableCharges()
-		baselineDiscount := touBill.BaselineCredit()
-		touMonthlyCosts := nbc + basicCharge + touTax
-		touTrueUpDiff := touNemCost + baselineDiscount
-		_, _ = fmt.Fprintf(w, "Non-bypassable charges\t%.2f\t$\t\n", nbc)
-		_, _ = fmt.Fprintf(w, "Max baseline allocation\t%.2f\tKWh\t\n", touBill.MaxBaselineAllowance())
-		_, _ = fmt.Fprintf(w, "Baseline discount\t%.2f\t$\t\n", baselineDiscount)
-		_, _ = fmt.Fprintf(w, "Basic charge\t%.2f\t$\t\n", basicCharge)
-		_, _ = fmt.Fprintf(w, "Taxes\t%.2f\t$\t\n", touTax)
-		_, _ = fmt.Fprintf(w, "Fees from monthly bills\t%.2f\t$\t\n", touMonthlyCosts)
-		_, _ = fmt.Fprintf(w, "NEM true-up\t%.2f\t$\t\n", touTrueUpDiff)
-		_, _ = fmt.Fprintln(w)
+func printUsage(out io.Writer) {
+	_, _ = fmt.Fprintf(out, "Usage: reporter <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		_, _ = fmt.Fprintf(out, "  %-10s %s\n", c.name, c.description)
 	}
-	_ = w.Flush()
+	_, _ = fmt.Fprintf(out, "\nRun 'reporter help <command>' for the flags of a command.\n")
 }
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kodek/sce-greenbutton/pkg/report"
+	"github.com/stretchr/testify/assert"
+)
+
+const testInput = "../../pkg/csvparser/testdata/two_days_constant_power.csv"
+
+// resetFlags restores the flags of every command to their defaults, since the commands share their values with
+// flag.CommandLine.
+func resetFlags(t *testing.T) {
+	t.Cleanup(func() {
+		inputFilePaths = nil
+		for _, c := range commands {
+			for _, name := range c.flags {
+				if name != "input_file_path" {
+					f := flag.Lookup(name)
+					_ = f.Value.Set(f.DefValue)
+				}
+			}
+		}
+	})
+}
+
+func runForTest(t *testing.T, args ...string) (int, string, string) {
+	resetFlags(t)
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_NoArguments_PrintsUsage(t *testing.T) {
+	code, _, stderr := runForTest(t)
+
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "Usage: reporter <command>")
+}
+
+func TestRun_UnknownCommand_UsageError(t *testing.T) {
+	code, _, stderr := runForTest(t, "frobnicate")
+
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "unknown command 'frobnicate'")
+}
+
+func TestRun_HelpForCommand_PrintsItsFlags(t *testing.T) {
+	code, stdout, _ := runForTest(t, "help", "bill")
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "Usage: reporter bill")
+	assert.Contains(t, stdout, "-plan")
+	assert.NotContains(t, stdout, "-sizing")
+}
+
+func TestRun_HelpFlag_Succeeds(t *testing.T) {
+	code, _, stderr := runForTest(t, "compare", "--help")
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stderr, "Usage: reporter compare")
+}
+
+func TestRun_FlagOfOtherCommand_UsageError(t *testing.T) {
+	code, _, stderr := runForTest(t, "profile", "--sizing")
+
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "flag provided but not defined: -sizing")
+}
+
+func TestRun_MissingInput_UsageError(t *testing.T) {
+	code, _, stderr := runForTest(t, "summary")
+
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "must specify --input_file_path")
+}
+
+func TestRun_MissingFile_Fails(t *testing.T) {
+	code, _, stderr := runForTest(t, "summary", "--input_file_path", "does_not_exist.csv")
+
+	assert.Equal(t, exitFailure, code)
+	assert.Contains(t, stderr, "reporter summary:")
+}
+
+func TestRun_NoReadings_Fails(t *testing.T) {
+	code, stdout, stderr := runForTest(t, "summary", "--input_file_path", "../../pkg/csvparser/testdata/header_only.csv")
+
+	assert.Equal(t, exitFailure, code)
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "no readings in")
+}
+
+func TestRun_UnsupportedBaselineRegion_UsageError(t *testing.T) {
+	code, _, stderr := runForTest(t, "bill", "--input_file_path", testInput, "--baseline_region", "Barstow")
+
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "unsupported baseline region 'Barstow'")
+}
+
+func TestRun_UnknownPlan_UsageError(t *testing.T) {
+	code, _, stderr := runForTest(t, "bill", "--input_file_path", testInput, "--plan", "TOU-X")
+
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "unknown plan 'TOU-X'")
+}
+
+func TestRun_Bill_PrintsSelectedPlan(t *testing.T) {
+	code, stdout, _ := runForTest(t, "bill", "--input_file_path", testInput, "--plan", "TOU-D-A")
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "TOU-D-A")
+	assert.NotContains(t, stdout, "TOU-D-PRIME")
+}
+
+func TestRun_Compare_PrintsAllPlans(t *testing.T) {
+	code, stdout, _ := runForTest(t, "compare", "--input_file_path", testInput)
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "Domestic")
+	assert.Contains(t, stdout, "TOU-D-A")
+	assert.Contains(t, stdout, "TOU-D-PRIME")
+	assert.Contains(t, stdout, "TOU-D-5-8PM")
+}
+
+func TestRun_ValidateCleanFile_Succeeds(t *testing.T) {
+	code, stdout, _ := runForTest(t, "validate", "--input_file_path", testInput)
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "No problems found.")
+}
+
+func TestRun_ValidateGap_Fails(t *testing.T) {
+	file, err := ioutil.ReadFile(testInput)
+	assert.NoError(t, err)
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(file), "\n") {
+		if !strings.HasPrefix(line, "\"2020-05-01 12:00:00") {
+			lines = append(lines, line)
+		}
+	}
+	withGap := strings.Join(lines, "\n")
+	input := filepath.Join(t.TempDir(), "gap.csv")
+	assert.NoError(t, ioutil.WriteFile(input, []byte(withGap), 0644))
+
+	code, stdout, stderr := runForTest(t, "validate", "--input_file_path", input)
+
+	assert.Equal(t, exitFailure, code)
+	assert.Contains(t, stdout, "Gap from 2020-05-01 12:00:00")
+	assert.Contains(t, stderr, "found")
+}
+
+func TestRun_ConflictingDownloads_ReportedOnStderr(t *testing.T) {
+	file, err := ioutil.ReadFile(testInput)
+	assert.NoError(t, err)
+	changed := strings.Replace(string(file), `12:15:00","0.25"`, `12:15:00","0.5"`, 1)
+	input := filepath.Join(t.TempDir(), "changed.csv")
+	assert.NoError(t, ioutil.WriteFile(input, []byte(changed), 0644))
+
+	code, _, stderr := runForTest(t, "summary", "--input_file_path", testInput, "--input_file_path", input)
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stderr, "Conflicting readings for")
+}
+
+func TestRun_SimulateWithoutScenario_UsageError(t *testing.T) {
+	code, _, stderr := runForTest(t, "simulate", "--input_file_path", testInput)
+
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "nothing to simulate")
+}
+
+func TestRun_SimulateAllElectricWithoutAllocation_UsageError(t *testing.T) {
+	code, _, stderr := runForTest(t, "simulate", "--input_file_path", testInput, "--water_heater_daily_kwh", "5", "--all_electric")
+
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "requires --all_electric_summer_daily_allocation")
+}
+
+func TestRun_SimulateAllElectric_Succeeds(t *testing.T) {
+	code, stdout, stderr := runForTest(t, "simulate", "--input_file_path", testInput, "--water_heater_daily_kwh", "5", "--all_electric",
+		"--all_electric_summer_daily_allocation", "20", "--all_electric_winter_daily_allocation", "30")
+
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "Electrification adds")
+}
+
+func TestRun_SimulateQuote_EscalatesFixedCharges(t *testing.T) {
+	var flat, escalated report.Document
+	args := []string{"simulate", "--input_file_path", testInput, "--quote_cost", "1000", "--energy_escalation", "0", "--nbc_escalation", "0", "--format", "json"}
+
+	code, stdout, _ := runForTest(t, append(args, "--fixed_charge_escalation", "0")...)
+	assert.Equal(t, exitOK, code)
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &flat))
+	code, stdout, _ = runForTest(t, append(args, "--fixed_charge_escalation", "0.1")...)
+	assert.Equal(t, exitOK, code)
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &escalated))
+
+	flatYears, escalatedYears := flat.Meters[0].Projection.Years, escalated.Meters[0].Projection.Years
+	assert.Equal(t, flatYears[0].CostWithout, escalatedYears[0].CostWithout)
+	assert.Greater(t, escalatedYears[1].CostWithout, flatYears[1].CostWithout)
+}
+
+func TestRun_Config_ProvidesDefaults(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, ioutil.WriteFile(config, []byte(`{"input_file_path": ["`+testInput+`"], "plan": "TOU-D-5-8PM"}`), 0644))
+
+	code, stdout, _ := runForTest(t, "bill", "--config", config)
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "TOU-D-5-8PM")
+}
+
+func TestRun_ConfigBaselineRegion_Validated(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, ioutil.WriteFile(config, []byte(`{"baseline_region": "Barstow"}`), 0644))
+
+	code, _, stderr := runForTest(t, "bill", "--input_file_path", testInput, "--config", config)
+
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "unsupported baseline region 'Barstow'")
+}
+
+func TestRun_BillJSON_MatchesSchema(t *testing.T) {
+	code, stdout, _ := runForTest(t, "bill", "--input_file_path", testInput, "--format", "json")
+
+	assert.Equal(t, exitOK, code)
+	var doc report.Document
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &doc))
+	assert.Equal(t, report.SchemaVersion, doc.SchemaVersion)
+	assert.Equal(t, "bill", doc.Command)
+	assert.Len(t, doc.Meters, 1)
+	assert.Equal(t, "TOU-D-PRIME", doc.Meters[0].Bills[0].Plan)
+	assert.Len(t, doc.Meters[0].Bills[0].Monthly, 1)
+}
+
+// writeMeterInput writes a copy of the test input with its readings attributed to the given meter.
+func writeMeterInput(t *testing.T, meter string) string {
+	data, err := ioutil.ReadFile(testInput)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), meter+".csv")
+	// The meter goes after the first line, which starts the preamble.
+	withMeter := strings.Replace(string(data), "\n", "\n\"Meter: "+meter+"\"\n", 1)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(withMeter), 0644))
+	return path
+}
+
+func TestRun_TwoMeters_CombinedSectionAddsUpDemand(t *testing.T) {
+	a, b := writeMeterInput(t, "A"), writeMeterInput(t, "B")
+	var summary, bill report.Document
+
+	code, stdout, _ := runForTest(t, "summary", "--input_file_path", a, "--input_file_path", b, "--format", "json")
+	assert.Equal(t, exitOK, code)
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &summary))
+	code, stdout, _ = runForTest(t, "bill", "--input_file_path", a, "--input_file_path", b,
+		"--demand_charge_per_kw", "10", "--format", "json")
+	assert.Equal(t, exitOK, code)
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &bill))
+
+	if assert.Len(t, summary.Meters, 3) {
+		meter, combined := summary.Meters[0].Dataset, summary.Meters[2].Dataset
+		assert.True(t, summary.Meters[2].Combined)
+		assert.Equal(t, 1.0, combined.Coverage)
+		assert.InDelta(t, 2*meter.MonthlyPeaks[0].Kw, combined.MonthlyPeaks[0].Kw, 0.0001)
+	}
+	if assert.Len(t, bill.Meters, 3) {
+		meter, combined := bill.Meters[0].Bills[0], bill.Meters[2].Bills[0]
+		assert.Positive(t, meter.DemandCharges)
+		assert.InDelta(t, 2*meter.DemandCharges, combined.DemandCharges, 0.0001)
+	}
+}
+
+func TestRun_CompareCSV_PrintsRows(t *testing.T) {
+	code, stdout, _ := runForTest(t, "compare", "--input_file_path", testInput, "--format", "csv")
+
+	assert.Equal(t, exitOK, code)
+	assert.True(t, strings.HasPrefix(stdout, "table,key,field,value\n"))
+	assert.Contains(t, stdout, "meters.bills,CA FOO ST MY CITY 12345 / TOU-D-A,total_cost,")
+}
+
+func TestRun_UnknownFormat_UsageError(t *testing.T) {
+	code, _, stderr := runForTest(t, "profile", "--input_file_path", testInput, "--format", "xml")
+
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "unknown format 'xml'")
+}
+
+func TestRun_CompareHTML_ChartsPlans(t *testing.T) {
+	code, stdout, _ := runForTest(t, "compare", "--input_file_path", testInput, "--format", "html")
+
+	assert.Equal(t, exitOK, code)
+	assert.True(t, strings.HasPrefix(stdout, "<!DOCTYPE html>"))
+	assert.Contains(t, stdout, "Monthly cost by plan")
+	assert.Contains(t, stdout, "Hourly usage")
+	assert.Contains(t, stdout, "Average load shape by season")
+}
+
+func TestRun_ProfileCharts_PrintsChartsWithoutColor(t *testing.T) {
+	code, stdout, _ := runForTest(t, "profile", "--input_file_path", testInput, "--charts", "--plan", "TOU-D-A")
+
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "Monthly usage:")
+	assert.Contains(t, stdout, "Daily usage")
+	assert.Contains(t, stdout, "with TOU periods of TOU-D-A")
+	assert.NotContains(t, stdout, "\x1b[")
+}
+
+func TestRun_ServeInvalidAddress_Fails(t *testing.T) {
+	code, _, stderr := runForTest(t, "serve", "--listen", "localhost:not-a-port")
+
+	assert.Equal(t, exitFailure, code)
+	assert.Contains(t, stderr, "Serving on http://localhost:not-a-port/")
+	assert.Contains(t, stderr, "reporter serve:")
+}
+
+func TestRun_AnomalyPlanAlias_SetsPlan(t *testing.T) {
+	code, _, stderr := runForTest(t, "summary", "--input_file_path", testInput, "--anomalies", "--anomaly_plan", "TOU-X")
+
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "unknown plan 'TOU-X'")
+}
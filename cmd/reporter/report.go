@@ -0,0 +1,179 @@
+package main
+
+import (
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/finance"
+	"github.com/kodek/sce-greenbutton/pkg/report"
+	"github.com/kodek/sce-greenbutton/pkg/sense"
+	"github.com/kodek/sce-greenbutton/pkg/simulator"
+	"github.com/kodek/sce-greenbutton/pkg/solar"
+	"github.com/kodek/sce-greenbutton/pkg/weather"
+)
+
+// sizingTop is the number of systems listed by --sizing.
+const sizingTop = 5
+
+// readWeather parses the file given by --weather_file_path.
+func readWeather() ([]weather.CsvRow, error) {
+	file, err := ioutil.ReadFile(*weatherFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return weather.ParseCSV(string(file))
+}
+
+func weatherModel(days []analyzer.UsageDay) (*report.WeatherModel, error) {
+	rows, err := readWeather()
+	if err != nil {
+		return nil, err
+	}
+	degreeDays := weather.DailyDegreeDays(rows, weather.BaseTemperatureF)
+
+	baseline, reporting := days, []analyzer.UsageDay{}
+	if *weatherBaselineEnd != "" {
+		end, err := time.ParseInLocation("2006-01-02", *weatherBaselineEnd, days[0].Day.Location())
+		if err != nil {
+			return nil, usageErrorf("invalid --weather_baseline_end: %v", err)
+		}
+		split := sort.Search(len(days), func(i int) bool { return !days[i].Day.Before(end) })
+		baseline, reporting = days[:split], days[split:]
+	}
+	model, err := weather.Fit(baseline, degreeDays)
+	if err != nil {
+		return &report.WeatherModel{Error: err.Error()}, nil
+	}
+	var savings *weather.Savings
+	if len(reporting) > 0 {
+		s := weather.CalculateSavings(model, reporting, degreeDays)
+		savings = &s
+	}
+	normal := weather.NormalWeather(degreeDays)
+	return report.NewWeatherModel(model, normal, savings, weather.FindOutliers(model, days, degreeDays, 3)), nil
+}
+
+func electrification(hours []analyzer.UsageHour) (*report.Electrification, error) {
+	scenario := simulator.Scenario{AllElectric: *allElectric}
+	var temperatures *weather.Temperatures
+	if *heatPumpHeatLoss > 0 {
+		rows, err := readWeather()
+		if err != nil {
+			return nil, err
+		}
+		temperatures = weather.NewTemperatures(rows)
+		scenario.HeatPump = &simulator.HeatPump{
+			HeatLossBtuPerHourF: *heatPumpHeatLoss,
+			BalancePointF:       *heatPumpBalancePoint,
+			COP:                 simulator.TypicalHeatPumpCOP(),
+		}
+	}
+	if *waterHeaterDailyKwh > 0 {
+		runHours := make([]int, 0)
+		for _, h := range strings.Split(*waterHeaterHours, ",") {
+			hour, err := strconv.Atoi(strings.TrimSpace(h))
+			if err != nil {
+				return nil, usageErrorf("invalid --water_heater_hours: %v", err)
+			}
+			runHours = append(runHours, hour)
+		}
+		scenario.WaterHeater = &simulator.WaterHeater{DailyThermalKwh: *waterHeaterDailyKwh, COP: *waterHeaterCop, Hours: runHours}
+	}
+
+	result, err := simulator.SimulateElectrification(hours, temperatures, scenario, touPlans())
+	if err != nil {
+		return nil, err
+	}
+	return report.NewElectrification(result), nil
+}
+
+// exportCompensation returns how exports are credited, as requested by flags.
+func exportCompensation() simulator.ExportCompensation {
+	if *netBillingExportRate > 0 {
+		return simulator.ExportCompensation{NetBilling: true, ExportRatePerKwh: *netBillingExportRate}
+	}
+	return simulator.ExportCompensation{}
+}
+
+func sizingReport(hours []analyzer.UsageHour) (*report.Sizing, error) {
+	opts := simulator.DefaultSizingOptions(touPlans())
+	opts.PVCostPerKw = *pvCostPerKw
+	opts.BatteryCostPerKwh = *batteryCostPerKwh
+	opts.IncentiveShare = *incentiveShare
+	opts.LifetimeYears = *systemLifetimeYears
+	opts.Export = exportCompensation()
+	candidates, err := simulator.OptimizeSizing(hours, opts)
+	if err != nil {
+		return nil, err
+	}
+	return report.NewSizing(candidates, sizingTop), nil
+}
+
+func projection(hours []analyzer.UsageHour, days []analyzer.UsageDay) (*report.Projection, error) {
+	plan, err := findPlan(*selectedPlan)
+	if err != nil {
+		return nil, err
+	}
+	opts := simulator.DefaultSizingOptions(nil)
+	withSystem := simulator.SimulateSystem(hours, *quotePvKw, *quoteBatteryKwh, plan, opts)
+	systemBill, exportCredit, err := simulator.BillBreakdown(withSystem, plan, exportCompensation())
+	if err != nil {
+		return nil, err
+	}
+	baselineBill := costcalculator.CalculateWithTouPlan(days, plan)
+
+	toYear := costcalculator.YearScale(days)
+	baseline := finance.CostsFromBill(&baselineBill).Scale(toYear)
+	system := finance.CostsFromBill(&systemBill)
+	system.EnergyCost -= exportCredit
+	system = system.Scale(toYear)
+
+	projectionOpts := finance.DefaultProjectionOptions(*quoteCost)
+	projectionOpts.Escalation.Energy = *energyEscalation
+	projectionOpts.Escalation.Fixed = *fixedChargeEscalation
+	projectionOpts.Escalation.NonBypassable = *nbcEscalation
+	projectionOpts.PVDegradation = *pvDegradation
+	projectionOpts.DiscountRate = *discountRate
+	return report.NewProjection(plan, *quotePvKw, *quoteBatteryKwh, *quoteCost, finance.Project(baseline, system, projectionOpts)), nil
+}
+
+func planUncertainty(days []analyzer.UsageDay) (*report.Uncertainty, error) {
+	opts := simulator.DefaultMonteCarloOptions()
+	opts.Samples = *monteCarloSamples
+	result, err := simulator.SimulatePlanUncertainty(days, touPlans(), opts)
+	if err != nil {
+		return nil, err
+	}
+	return report.NewUncertainty(result, opts.Samples), nil
+}
+
+func solarHealthReport(days []analyzer.UsageDay) (*report.SolarHealth, error) {
+	production := solar.EstimateProduction(days)
+	opts := solar.DefaultOptions()
+	lowDays, lowWeeks, err := solar.FindOutages(production, opts)
+	if err != nil {
+		return nil, err
+	}
+	return report.NewSolarHealth(lowDays, lowWeeks, solar.EstimateDegradation(production, opts)), nil
+}
+
+func deviceCosts(path string, plan costcalculator.TouPlan) ([]report.DeviceCost, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := sense.ParseCSV(string(file))
+	if err != nil {
+		return nil, err
+	}
+	byDevice, err := sense.GroupByDeviceId(rows)
+	if err != nil {
+		return nil, err
+	}
+	return report.NewDeviceCosts(costcalculator.CalculateDeviceCosts(byDevice, plan), plan), nil
+}
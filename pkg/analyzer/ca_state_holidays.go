@@ -21,12 +21,23 @@ var stateHolidays = []string{
 	"2021-12-25",
 }
 
-func IsHoliday(t time.Time) bool {
-	for _, hStr := range stateHolidays {
+// parsedHolidays is stateHolidays parsed once, since IsHoliday is called for every hour that is billed.
+var parsedHolidays = parseHolidays()
+
+func parseHolidays() []time.Time {
+	out := make([]time.Time, len(stateHolidays))
+	for i, hStr := range stateHolidays {
 		h, err := time.ParseInLocation("2006-01-02", hStr, time.UTC)
 		if err != nil {
 			panic(fmt.Sprintf("Unable to parse date %s", hStr))
 		}
+		out[i] = h
+	}
+	return out
+}
+
+func IsHoliday(t time.Time) bool {
+	for _, h := range parsedHolidays {
 		if t.Month() == h.Month() && t.Day() == h.Day() {
 			return true
 		}
@@ -1,11 +1,50 @@
 package analyzer
 
-import "time"
+import (
+	"math"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/timebucket"
+)
 
 type UsageDay struct {
 	Day        time.Time
 	DataPoints []UsageHour
 	UsageKwh   float64
+	// Covered is the amount of time within the day that has readings.
+	Covered time.Duration
+	// LowQualityKwh is the absolute energy of estimated and missing readings.
+	LowQualityKwh float64
+}
+
+// lowQualityHour is implemented by hours that know the quality of their readings, like GreenButtonHour.
+type lowQualityHour interface {
+	LowQualityKwh() float64
+}
+
+// LowQualityShare returns the fraction of the day's absolute energy that comes from estimated or missing readings.
+func (d *UsageDay) LowQualityShare() float64 {
+	return LowQualityShare([]UsageDay{*d})
+}
+
+// LowQualityShare returns the fraction of the absolute energy that comes from estimated or missing readings.
+func LowQualityShare(days []UsageDay) float64 {
+	lowQuality, total := 0.0, 0.0
+	for _, d := range days {
+		lowQuality += d.LowQualityKwh
+		for _, hr := range d.DataPoints {
+			total += math.Abs(hr.UsageKwh())
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return lowQuality / total
+}
+
+// Complete returns true if the day has readings for every interval.
+func (d *UsageDay) Complete() bool {
+	return d.Covered >= d.Day.AddDate(0, 0, 1).Sub(d.Day)
 }
 
 func (d *UsageDay) EndTime() time.Time {
@@ -13,51 +52,30 @@ func (d *UsageDay) EndTime() time.Time {
 }
 
 func SplitByDay(in []UsageHour) ([]UsageDay, error) {
-	yearMonthDayMap := make(map[time.Time]*UsageDay)
-	// Keep track of the order in which keys were added.
-	sortedKeys := make([]time.Time, 0)
-
-	for _, hr := range in {
-		dayBucket := truncateToDay(hr.StartTime())
-		usageDay, ok := yearMonthDayMap[dayBucket]
-		if !ok {
-			sortedKeys = append(sortedKeys, dayBucket)
-			usageDay = &UsageDay{
-				Day:        dayBucket,
-				DataPoints: make([]UsageHour, 0),
-				UsageKwh:   0}
-			yearMonthDayMap[dayBucket] = usageDay
-		}
-		// Append the data to the day
-		usageDay.DataPoints = append(usageDay.DataPoints, hr)
-		usageDay.UsageKwh += hr.UsageKwh()
+	points := make([]timebucket.Interval, len(in))
+	for i, hr := range in {
+		points[i] = hr
 	}
-
-	values := make([]UsageDay, 0)
-	for _, k := range sortedKeys {
-		values = append(values, *yearMonthDayMap[k])
+	buckets, err := timebucket.Resample(points, timebucket.Daily())
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate data points
-	for _, v := range values {
-		expectedYear := v.Day.Year()
-		expectedMonth := v.Day.Month()
-		expectedDay := v.Day.Day()
-		for _, p := range v.DataPoints {
-			if p.StartTime().Day() != expectedDay {
-				panic("Wrong day within UsageDay")
-			}
-			if p.StartTime().Month() != expectedMonth {
-				panic("Wrong month within UsageDay")
-			}
-			if p.StartTime().Year() != expectedYear {
-				panic("Wrong year within UsageDay")
+	values := make([]UsageDay, 0, len(buckets))
+	for _, b := range buckets {
+		day := UsageDay{
+			Day:        b.Start,
+			DataPoints: make([]UsageHour, len(b.Points)),
+			UsageKwh:   b.UsageKwh,
+			Covered:    b.Covered,
+		}
+		for i, p := range b.Points {
+			day.DataPoints[i] = p.(UsageHour)
+			if hr, ok := p.(lowQualityHour); ok {
+				day.LowQualityKwh += hr.LowQualityKwh()
 			}
 		}
+		values = append(values, day)
 	}
 	return values, nil
 }
-
-func truncateToDay(in time.Time) time.Time {
-	return time.Date(in.Year(), in.Month(), in.Day(), 0, 0, 0, 0, in.Location())
-}
@@ -96,3 +96,21 @@ func TestUsageDay_EndTime_ReturnsEndFromLastDataPoint(t *testing.T) {
 	assert.Len(t, got, 1)
 	assert.Equal(t, time.Date(2020, 01, 01, 15, 15, 0, 0, time.UTC), got[0].EndTime())
 }
+
+func TestSplitByDay_LowQualityShare(t *testing.T) {
+	estimated := csvparser.NewRowWith15MinuteDuration(time.Date(2020, 01, 01, 13, 00, 00, 0, time.UTC), 1.0)
+	estimated.ReadingQuality = csvparser.QualityEstimated
+	in, err := AggregateIntoHourWindows([]csvparser.CsvRow{
+		csvparser.NewRowWith15MinuteDuration(
+			time.Date(2020, 01, 01, 12, 00, 00, 0, time.UTC),
+			3.0),
+		estimated})
+	assert.NoError(t, err)
+
+	got, err := SplitByDay(in)
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, 1.0, got[0].LowQualityKwh)
+	assert.Equal(t, 0.25, got[0].LowQualityShare())
+}
@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/kodek/sce-greenbutton/pkg/timebucket"
+)
+
+// peakDemandHour is implemented by hours that know the demand of their individual readings, like GreenButtonHour.
+type peakDemandHour interface {
+	PeakDemandKw() float64
+}
+
+// PeakDemandKw returns the highest demand within the hour. Hours that don't keep their readings, like simulated ones,
+// only know their average demand.
+func PeakDemandKw(hr UsageHour) float64 {
+	if p, ok := hr.(peakDemandHour); ok {
+		return p.PeakDemandKw()
+	}
+	d := hr.EndTime().Sub(hr.StartTime())
+	if d <= 0 {
+		return 0
+	}
+	return hr.UsageKwh() / d.Hours()
+}
+
+// DemandPeak is the highest demand within a period.
+type DemandPeak struct {
+	Start time.Time
+	End   time.Time
+	// At is the start of the reading with the highest demand.
+	At time.Time
+	Kw float64
+}
+
+// PeakDemand returns the highest demand of each bucket of the given resolution, computed from the individual
+// readings. Readings should belong to a single meter. For the demand of several meters together, add up their
+// readings with CombineMeters first.
+func PeakDemand(file csvparser.CsvFile, res timebucket.Resolution) ([]DemandPeak, error) {
+	buckets, err := timebucket.Resample(toIntervals(sortedRows(file)), res)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]DemandPeak, 0, len(buckets))
+	for _, b := range buckets {
+		peak := DemandPeak{Start: b.Start, End: b.End}
+		for i, p := range b.Points {
+			row := p.(*csvRowInterval).row
+			if i == 0 || row.DemandKw() > peak.Kw {
+				peak.At = row.StartTime
+				peak.Kw = row.DemandKw()
+			}
+		}
+		out = append(out, peak)
+	}
+	return out, nil
+}
+
+// PeakDemandByDay returns the highest demand of each day.
+func PeakDemandByDay(file csvparser.CsvFile) ([]DemandPeak, error) {
+	return PeakDemand(file, timebucket.Daily())
+}
+
+// PeakDemandByMonth returns the highest demand of each calendar month.
+func PeakDemandByMonth(file csvparser.CsvFile) ([]DemandPeak, error) {
+	return PeakDemand(file, timebucket.Monthly())
+}
+
+// LoadDurationPoint is a point of a load-duration curve: the demand was at least Kw for Duration.
+type LoadDurationPoint struct {
+	Kw       float64
+	Duration time.Duration
+}
+
+// LoadDurationCurve returns the demand of every reading from highest to lowest, together with the total time spent
+// at or above that demand.
+func LoadDurationCurve(file csvparser.CsvFile) []LoadDurationPoint {
+	rows := make(csvparser.CsvFile, len(file))
+	copy(rows, file)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].DemandKw() > rows[j].DemandKw()
+	})
+
+	out := make([]LoadDurationPoint, 0, len(rows))
+	total := time.Duration(0)
+	for i := range rows {
+		total += rows[i].Duration()
+		out = append(out, LoadDurationPoint{Kw: rows[i].DemandKw(), Duration: total})
+	}
+	return out
+}
+
+// DurationAbove returns the time spent at or above kw.
+func DurationAbove(curve []LoadDurationPoint, kw float64) time.Duration {
+	out := time.Duration(0)
+	for _, p := range curve {
+		if p.Kw < kw {
+			break
+		}
+		out = p.Duration
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/kodek/sce-greenbutton/pkg/timebucket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeakDemandByDay_UsesFifteenMinuteReadings(t *testing.T) {
+	in := append(fullDay(jan1, 0.25), fullDay(jan1.AddDate(0, 0, 1), 0.25)...)
+	// 1.5 kWh in 15 minutes is 6 kW, even though the hour averages 2.625 kW.
+	in[40].UsageKwh = 1.5
+
+	got, err := PeakDemandByDay(in)
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 2)
+	assert.Equal(t, jan1.Add(10*time.Hour), got[0].At)
+	assert.InEpsilon(t, 6.0, got[0].Kw, 0.0001)
+	assert.InEpsilon(t, 1.0, got[1].Kw, 0.0001)
+	assert.Equal(t, jan1.AddDate(0, 0, 1), got[1].Start)
+}
+
+func TestPeakDemandByMonth_OneBucketPerMonth(t *testing.T) {
+	in := append(fullDay(jan1, 0.25), fullDay(jan1.AddDate(0, 1, 0), 0.5)...)
+
+	got, err := PeakDemandByMonth(in)
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 2)
+	assert.InEpsilon(t, 2.0, got[1].Kw, 0.0001)
+}
+
+func TestPeakDemand_ReadingCrossesBucket_Fails(t *testing.T) {
+	in := csvparser.CsvFile{csvparser.NewRowWith15MinuteDuration(jan1.Add(-5*time.Minute), 1)}
+
+	_, err := PeakDemand(in, timebucket.Daily())
+
+	assert.Error(t, err)
+}
+
+func TestLoadDurationCurve_SortedWithCumulativeDuration(t *testing.T) {
+	in := csvparser.CsvFile{
+		csvparser.NewRowWith15MinuteDuration(jan1, 0.5),
+		csvparser.NewRowWith15MinuteDuration(jan1.Add(15*time.Minute), 1),
+		csvparser.NewRowWith15MinuteDuration(jan1.Add(30*time.Minute), 0.25),
+	}
+
+	got := LoadDurationCurve(in)
+
+	assert.Equal(t, []LoadDurationPoint{
+		{Kw: 4, Duration: 15 * time.Minute},
+		{Kw: 2, Duration: 30 * time.Minute},
+		{Kw: 1, Duration: 45 * time.Minute},
+	}, got)
+	assert.Equal(t, 30*time.Minute, DurationAbove(got, 1.5))
+	assert.Equal(t, time.Duration(0), DurationAbove(got, 5))
+}
+
+func TestPeakDemandKw_GreenButtonHour_UsesReadings(t *testing.T) {
+	in := fullDay(jan1, 0.25)[:4]
+	in[2].UsageKwh = 1
+	hours, err := AggregateIntoHourWindows(in)
+	assert.NoError(t, err)
+
+	assert.InEpsilon(t, 4.0, PeakDemandKw(hours[0]), 0.0001)
+	assert.InEpsilon(t, 2.0, PeakDemandKw(NewUsageHour(jan1, jan1.Add(time.Hour), 2)), 0.0001)
+}
@@ -0,0 +1,59 @@
+package analyzer
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+)
+
+// MeterFile is the readings of a single meter.
+type MeterFile struct {
+	Meter csvparser.MeterId
+	Rows  csvparser.CsvFile
+}
+
+// SplitByMeter separates readings of different meters, so that each can be checked and billed on its own. Meters are
+// returned in the order they first appear.
+func SplitByMeter(file csvparser.CsvFile) []MeterFile {
+	out := make([]MeterFile, 0)
+	index := make(map[csvparser.MeterId]int)
+	for _, r := range file {
+		i, ok := index[r.Meter]
+		if !ok {
+			i = len(out)
+			index[r.Meter] = i
+			out = append(out, MeterFile{Meter: r.Meter, Rows: make(csvparser.CsvFile, 0)})
+		}
+		out[i].Rows = append(out[i].Rows, r)
+	}
+	return out
+}
+
+// CombineMeters adds up the readings of several meters that start at the same time, as an aggregation tariff bills
+// them. The meters should have the same reading interval. Duplicate readings within a meter count once (the first
+// one wins), and an interval that only some meters have is the sum of those meters. A combined reading has the worst
+// quality of its readings and no meter identity. The result is sorted chronologically.
+func CombineMeters(meters []MeterFile) csvparser.CsvFile {
+	byStart := make(map[time.Time]int)
+	out := make(csvparser.CsvFile, 0)
+	for _, m := range meters {
+		for _, r := range dedupe(sortedRows(m.Rows)) {
+			i, ok := byStart[r.StartTime]
+			if !ok {
+				byStart[r.StartTime] = len(out)
+				r.Meter = csvparser.MeterId{}
+				out = append(out, r)
+				continue
+			}
+			out[i].UsageKwh += r.UsageKwh
+			if !r.ReadingQuality.AtLeast(out[i].ReadingQuality) {
+				out[i].ReadingQuality = r.ReadingQuality
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].StartTime.Before(out[j].StartTime)
+	})
+	return out
+}
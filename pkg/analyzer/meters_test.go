@@ -0,0 +1,60 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitByMeter_TwoMeters_InOrderOfAppearance(t *testing.T) {
+	house := csvparser.MeterId{Meter: "house"}
+	adu := csvparser.MeterId{Meter: "adu"}
+	in := fullDay(jan1, 1)[:3]
+	in[0].Meter = adu
+	in[1].Meter = house
+	in[2].Meter = adu
+
+	got := SplitByMeter(in)
+
+	assert.Equal(t, []MeterFile{
+		{Meter: adu, Rows: csvparser.CsvFile{in[0], in[2]}},
+		{Meter: house, Rows: csvparser.CsvFile{in[1]}},
+	}, got)
+}
+
+func TestSplitByMeter_NoIdentity_SingleMeter(t *testing.T) {
+	in := fullDay(jan1, 1)
+
+	got := SplitByMeter(in)
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, in, got[0].Rows)
+}
+
+func TestCombineMeters_OverlappingMeters_SumsEachInterval(t *testing.T) {
+	house := fullDay(jan1, 1)
+	adu := fullDay(jan1, 0.5)
+	for i := range adu {
+		adu[i].Meter = csvparser.MeterId{Meter: "adu"}
+	}
+	// The ADU meter starts an hour later.
+	adu = adu[4:]
+	adu[0].ReadingQuality = csvparser.QualityEstimated
+
+	got := CombineMeters([]MeterFile{{Rows: house}, {Meter: csvparser.MeterId{Meter: "adu"}, Rows: adu}})
+
+	if assert.Len(t, got, 96) {
+		assert.Equal(t, 1.0, got[0].UsageKwh)
+		assert.Equal(t, 1.5, got[4].UsageKwh)
+		assert.Equal(t, csvparser.QualityEstimated, got[4].ReadingQuality)
+		assert.Equal(t, csvparser.QualityActual, got[5].ReadingQuality)
+		assert.Equal(t, csvparser.MeterId{}, got[4].Meter)
+	}
+	hours, err := AggregateIntoHourWindows(got)
+	assert.NoError(t, err)
+	days, err := SplitByDay(hours)
+	assert.NoError(t, err)
+	completeness := Completeness(days)
+	assert.Equal(t, 1.0, completeness.Coverage())
+}
@@ -1,6 +1,10 @@
 package analyzer
 
-import "time"
+import (
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/timebucket"
+)
 
 type UsageMonth struct {
 	Month     time.Time
@@ -13,48 +17,38 @@ func (m *UsageMonth) AverageDailyUsageKwh() float64 {
 }
 
 func SplitByMonth(in []UsageDay) ([]UsageMonth, error) {
-	yearMonthMap := make(map[time.Time]*UsageMonth)
-	sortedKeys := make([]time.Time, 0)
-
-	for _, d := range in {
-		monthBucket := truncateToMonth(d.Day)
-		usageMonth, ok := yearMonthMap[monthBucket]
-		if !ok {
-			sortedKeys = append(sortedKeys, monthBucket)
-			usageMonth = &UsageMonth{
-				Month:     monthBucket,
-				UsageDays: make([]UsageDay, 0),
-				UsageKwh:  0,
-			}
-			yearMonthMap[monthBucket] = usageMonth
-		}
-		// Append the data to the month
-		usageMonth.UsageDays = append(usageMonth.UsageDays, d)
-		usageMonth.UsageKwh += d.UsageKwh
+	points := make([]timebucket.Interval, len(in))
+	for i := range in {
+		points[i] = &usageDayInterval{day: in[i]}
 	}
-
-	values := make([]UsageMonth, 0)
-	for _, k := range sortedKeys {
-		values = append(values, *yearMonthMap[k])
+	buckets, err := timebucket.Resample(points, timebucket.Monthly())
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate data points
-	for _, v := range values {
-		expectedYear := v.Month.Year()
-		expectedMonth := v.Month.Month()
-
-		for _, d := range v.UsageDays {
-			if d.Day.Month() != expectedMonth {
-				panic("Wrong month within UsageMonth")
-			}
-			if d.Day.Year() != expectedYear {
-				panic("Wrong year within UsageMonth")
-			}
+	values := make([]UsageMonth, 0, len(buckets))
+	for _, b := range buckets {
+		usageDays := make([]UsageDay, len(b.Points))
+		for i, p := range b.Points {
+			usageDays[i] = p.(*usageDayInterval).day
 		}
+		values = append(values, UsageMonth{
+			Month:     b.Start,
+			UsageDays: usageDays,
+			UsageKwh:  b.UsageKwh,
+		})
 	}
 	return values, nil
 }
 
-func truncateToMonth(in time.Time) time.Time {
-	return time.Date(in.Year(), in.Month(), 1, 0, 0, 0, 0, in.Location())
+// usageDayInterval adapts a UsageDay to a timebucket.Interval.
+type usageDayInterval struct {
+	day UsageDay
+}
+
+func (d *usageDayInterval) UsageKwh() float64    { return d.day.UsageKwh }
+func (d *usageDayInterval) StartTime() time.Time { return d.day.Day }
+func (d *usageDayInterval) EndTime() time.Time   { return d.day.Day.AddDate(0, 0, 1) }
+func (d *usageDayInterval) CoveredDuration() time.Duration {
+	return d.day.Covered
 }
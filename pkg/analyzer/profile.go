@@ -0,0 +1,225 @@
+package analyzer
+
+import (
+	"math"
+	"sort"
+)
+
+// ProfileKey identifies the days that share a load profile.
+type ProfileKey struct {
+	Season  Season
+	DayType DayType
+}
+
+func (k ProfileKey) String() string {
+	return k.Season.String() + " " + k.DayType.String()
+}
+
+// HourStats summarizes the average demand in kW during one hour of the day across several days.
+type HourStats struct {
+	Samples int
+	Mean    float64
+	P10     float64
+	P50     float64
+	P90     float64
+}
+
+// LoadProfile is the shape of an average day.
+type LoadProfile struct {
+	Key  ProfileKey
+	Days int
+	// Hours is indexed by the hour of the day.
+	Hours [24]HourStats
+}
+
+// PeakHour returns the hour of the day with the highest mean demand.
+func (p *LoadProfile) PeakHour() int {
+	peak := 0
+	for h := range p.Hours {
+		if p.Hours[h].Mean > p.Hours[peak].Mean {
+			peak = h
+		}
+	}
+	return peak
+}
+
+// WindowShare returns the fraction of the average day's energy used in [startHour, endHour), such as 4-9pm.
+func (p *LoadProfile) WindowShare(startHour int, endHour int) float64 {
+	window, total := 0.0, 0.0
+	for h := range p.Hours {
+		total += p.Hours[h].Mean
+		if h >= startHour && h < endHour {
+			window += p.Hours[h].Mean
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return window / total
+}
+
+// LoadProfiles returns the 24-hour load profile of each combination of season and day type, in the order summer
+// weekday, weekend and holiday, then winter. Combinations without days are skipped. Hours without readings don't
+// count as samples, so partial days don't drag the averages down.
+func LoadProfiles(days []UsageDay) []LoadProfile {
+	samples := make(map[ProfileKey]*[24][]float64)
+	dayCounts := make(map[ProfileKey]int)
+	for _, d := range days {
+		key := ProfileKey{Season: SeasonOf(d.Day), DayType: DayTypeOf(d.Day)}
+		if _, ok := samples[key]; !ok {
+			samples[key] = &[24][]float64{}
+		}
+		dayCounts[key]++
+		for h, kw := range hourlyDemandKw(d) {
+			if !math.IsNaN(kw) {
+				samples[key][h] = append(samples[key][h], kw)
+			}
+		}
+	}
+
+	out := make([]LoadProfile, 0)
+	for _, season := range []Season{Summer, Winter} {
+		for _, dayType := range []DayType{Weekday, Weekend, Holiday} {
+			key := ProfileKey{Season: season, DayType: dayType}
+			hours, ok := samples[key]
+			if !ok {
+				continue
+			}
+			profile := LoadProfile{Key: key, Days: dayCounts[key]}
+			for h := range hours {
+				profile.Hours[h] = hourStats(hours[h])
+			}
+			out = append(out, profile)
+		}
+	}
+	return out
+}
+
+// BaseloadKw returns the typical always-on demand: the median over all days of the lowest hourly demand between
+// midnight and 6am.
+func BaseloadKw(days []UsageDay) float64 {
+	minimums := make([]float64, 0, len(days))
+	for _, d := range days {
+		lowest := math.NaN()
+		for h, kw := range hourlyDemandKw(d) {
+			if h < 6 && !math.IsNaN(kw) && (math.IsNaN(lowest) || kw < lowest) {
+				lowest = kw
+			}
+		}
+		if !math.IsNaN(lowest) {
+			minimums = append(minimums, lowest)
+		}
+	}
+	sort.Float64s(minimums)
+	return Percentile(minimums, 0.5)
+}
+
+// EveningRamp describes how much demand rises from the afternoon into the 4-9pm peak.
+type EveningRamp struct {
+	Days int
+	// MeanKw and P90Kw are the increase from the average demand between 1pm and 4pm to the highest hourly demand
+	// between 4pm and 9pm.
+	MeanKw float64
+	P90Kw  float64
+	// MeanPeakShare is the average fraction of a day's energy used between 4pm and 9pm.
+	MeanPeakShare float64
+}
+
+// EveningRampStats returns the evening ramp over the days that have readings for every hour between 1pm and 9pm.
+func EveningRampStats(days []UsageDay) EveningRamp {
+	ramps := make([]float64, 0, len(days))
+	shareSum := 0.0
+	for _, d := range days {
+		kw := hourlyDemandKw(d)
+		afternoon, evening, peak := 0.0, 0.0, math.Inf(-1)
+		complete := true
+		for h := 13; h < 21; h++ {
+			if math.IsNaN(kw[h]) {
+				complete = false
+				break
+			}
+			if h < 16 {
+				afternoon += kw[h] / 3
+			} else {
+				evening += kw[h]
+				peak = math.Max(peak, kw[h])
+			}
+		}
+		if !complete {
+			continue
+		}
+		ramps = append(ramps, peak-afternoon)
+		if d.UsageKwh != 0 {
+			shareSum += evening / d.UsageKwh
+		}
+	}
+
+	out := EveningRamp{Days: len(ramps)}
+	if len(ramps) == 0 {
+		return out
+	}
+	out.MeanPeakShare = shareSum / float64(len(ramps))
+	sort.Float64s(ramps)
+	out.MeanKw = mean(ramps)
+	out.P90Kw = Percentile(ramps, 0.9)
+	return out
+}
+
+// hourlyDemandKw returns the average demand in each hour of the day, or NaN for hours without readings. Readings
+// from the repeated hour at the end of daylight saving time are added together.
+func hourlyDemandKw(d UsageDay) [24]float64 {
+	var kwh [24]float64
+	var covered [24]float64
+	for h := range kwh {
+		kwh[h] = math.NaN()
+	}
+	for _, hr := range d.DataPoints {
+		h := hr.StartTime().Hour()
+		if math.IsNaN(kwh[h]) {
+			kwh[h] = 0
+		}
+		kwh[h] += hr.UsageKwh()
+		covered[h] += hr.EndTime().Sub(hr.StartTime()).Hours()
+	}
+	for h := range kwh {
+		if !math.IsNaN(kwh[h]) && covered[h] > 0 {
+			kwh[h] /= math.Min(covered[h], 1)
+		}
+	}
+	return kwh
+}
+
+func hourStats(values []float64) HourStats {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	return HourStats{
+		Samples: len(sorted),
+		Mean:    mean(sorted),
+		P10:     Percentile(sorted, 0.1),
+		P50:     Percentile(sorted, 0.5),
+		P90:     Percentile(sorted, 0.9),
+	}
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+// Percentile returns the p-th quantile of sorted values, interpolating linearly between the closest ranks.
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/stretchr/testify/assert"
+)
+
+// eveningPeakDay returns a day of readings at 1 kW, and at 3 kW between 4pm and 9pm.
+func eveningPeakDay(day time.Time) csvparser.CsvFile {
+	out := fullDay(day, 0.25)
+	for i := 16 * 4; i < 21*4; i++ {
+		out[i].UsageKwh = 0.75
+	}
+	return out
+}
+
+func splitDaysOrDie(t *testing.T, rows csvparser.CsvFile) []UsageDay {
+	hours, err := AggregateIntoHourWindows(rows)
+	assert.NoError(t, err)
+	days, err := SplitByDay(hours)
+	assert.NoError(t, err)
+	return days
+}
+
+func TestLoadProfiles_GroupsBySeasonAndDayType(t *testing.T) {
+	// Jan 2 and 3 2020 are weekdays, Jan 4 is a Saturday and Jul 1 is a weekday.
+	july := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	rows := append(eveningPeakDay(jan1.AddDate(0, 0, 1)), eveningPeakDay(jan1.AddDate(0, 0, 2))...)
+	rows = append(rows, fullDay(jan1.AddDate(0, 0, 3), 0.5)...)
+	rows = append(rows, fullDay(july, 1)...)
+
+	got := LoadProfiles(splitDaysOrDie(t, rows))
+
+	assert.Len(t, got, 3)
+	assert.Equal(t, ProfileKey{Season: Summer, DayType: Weekday}, got[0].Key)
+	assert.Equal(t, ProfileKey{Season: Winter, DayType: Weekday}, got[1].Key)
+	assert.Equal(t, ProfileKey{Season: Winter, DayType: Weekend}, got[2].Key)
+	assert.Equal(t, 2, got[1].Days)
+	assert.InEpsilon(t, 3.0, got[1].Hours[17].Mean, 0.0001)
+	assert.InEpsilon(t, 1.0, got[1].Hours[3].P90, 0.0001)
+	assert.Equal(t, 2, got[1].Hours[17].Samples)
+	assert.Equal(t, 16, got[1].PeakHour())
+	assert.InEpsilon(t, 15.0/34, got[1].WindowShare(16, 21), 0.0001)
+	assert.InEpsilon(t, 2.0, got[2].Hours[12].Mean, 0.0001)
+}
+
+func TestLoadProfiles_MissingHours_NotCounted(t *testing.T) {
+	jan2, jan3 := jan1.AddDate(0, 0, 1), jan1.AddDate(0, 0, 2)
+	rows := fullDay(jan2, 0.25)
+	rows = append(rows, without(fullDay(jan3, 1), jan3.Add(2*time.Hour), jan3.Add(135*time.Minute),
+		jan3.Add(150*time.Minute), jan3.Add(165*time.Minute))...)
+
+	got := LoadProfiles(splitDaysOrDie(t, rows))
+
+	assert.Equal(t, 1, got[0].Hours[2].Samples)
+	assert.InEpsilon(t, 1.0, got[0].Hours[2].Mean, 0.0001)
+	assert.InEpsilon(t, 2.5, got[0].Hours[3].Mean, 0.0001)
+}
+
+func TestBaseloadKw_MedianOfOvernightMinimums(t *testing.T) {
+	rows := make(csvparser.CsvFile, 0)
+	for d, usage := range []float64{0.1, 0.2, 0.3} {
+		day := fullDay(jan1.AddDate(0, 0, d), 1)
+		for i := 4 * 4; i < 5*4; i++ {
+			day[i].UsageKwh = usage
+		}
+		rows = append(rows, day...)
+	}
+
+	got := BaseloadKw(splitDaysOrDie(t, rows))
+
+	assert.InEpsilon(t, 0.8, got, 0.0001)
+}
+
+func TestEveningRampStats(t *testing.T) {
+	rows := append(eveningPeakDay(jan1), fullDay(jan1.AddDate(0, 0, 1), 0.25)...)
+
+	got := EveningRampStats(splitDaysOrDie(t, rows))
+
+	assert.Equal(t, 2, got.Days)
+	assert.InEpsilon(t, 1.0, got.MeanKw, 0.0001)
+	assert.InEpsilon(t, 1.8, got.P90Kw, 0.0001)
+	assert.InEpsilon(t, (15.0/34+5.0/24)/2, got.MeanPeakShare, 0.0001)
+}
+
+func TestSeasonAndDayType(t *testing.T) {
+	assert.Equal(t, Winter, SeasonOf(jan1))
+	assert.Equal(t, Summer, SeasonOf(time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, Weekday, DayTypeOf(jan1.AddDate(0, 0, 1)))
+	assert.Equal(t, Weekend, DayTypeOf(jan1.AddDate(0, 0, 3)))
+	assert.Equal(t, Holiday, DayTypeOf(jan1))
+}
@@ -0,0 +1,296 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/kodek/sce-greenbutton/pkg/timebucket"
+)
+
+// DefaultReadingInterval is the interval of SCE Green Button readings.
+const DefaultReadingInterval = 15 * time.Minute
+
+// Gap is a span of time without readings.
+type Gap struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (g *Gap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
+
+// PartialDay is a day that does not have readings for all of its intervals.
+type PartialDay struct {
+	Day               time.Time
+	Intervals         int
+	ExpectedIntervals int
+}
+
+// QualityReport describes the problems found in a series of readings.
+type QualityReport struct {
+	// Interval is the most common reading duration.
+	Interval            time.Duration
+	Intervals           int
+	Gaps                []Gap
+	DuplicateTimestamps []time.Time
+	PartialDays         []PartialDay
+}
+
+// MissingIntervals returns the number of readings that would fill all gaps.
+func (r *QualityReport) MissingIntervals() int {
+	total := 0
+	for _, g := range r.Gaps {
+		total += int(g.Duration() / r.Interval)
+	}
+	return total
+}
+
+// IsClean returns true if no problems were found.
+func (r *QualityReport) IsClean() bool {
+	return len(r.Gaps) == 0 && len(r.DuplicateTimestamps) == 0 && len(r.PartialDays) == 0
+}
+
+// CheckQuality finds gaps, duplicate timestamps and partial days in the readings. Rows may be in any order.
+func CheckQuality(file csvparser.CsvFile) QualityReport {
+	rows := sortedRows(file)
+	report := QualityReport{
+		Interval:            mostCommonDuration(rows),
+		Intervals:           len(rows),
+		Gaps:                make([]Gap, 0),
+		DuplicateTimestamps: make([]time.Time, 0),
+		PartialDays:         make([]PartialDay, 0),
+	}
+
+	for i := 1; i < len(rows); i++ {
+		prev, cur := rows[i-1], rows[i]
+		if cur.StartTime.Equal(prev.StartTime) {
+			if len(report.DuplicateTimestamps) == 0 || !report.DuplicateTimestamps[len(report.DuplicateTimestamps)-1].Equal(cur.StartTime) {
+				report.DuplicateTimestamps = append(report.DuplicateTimestamps, cur.StartTime)
+			}
+			continue
+		}
+		if cur.StartTime.After(prev.EndTime) {
+			report.Gaps = append(report.Gaps, Gap{Start: prev.EndTime, End: cur.StartTime})
+		}
+	}
+
+	expected := int(24 * time.Hour / report.Interval)
+	days, err := timebucket.Resample(toIntervals(dedupe(rows)), timebucket.Daily())
+	if err != nil {
+		// Rows that cross midnight can't be assigned to a single day, so don't report partial days.
+		return report
+	}
+	for _, d := range days {
+		if !d.Complete() {
+			report.PartialDays = append(report.PartialDays, PartialDay{
+				Day:               d.Start,
+				Intervals:         len(d.Points),
+				ExpectedIntervals: expected,
+			})
+		}
+	}
+	return report
+}
+
+// ImputationStrategy decides the usage of readings added by FillGaps.
+type ImputationStrategy int
+
+const (
+	// ImputeZero fills gaps with 0 kWh readings.
+	ImputeZero ImputationStrategy = iota
+	// ImputeLinear interpolates between the readings before and after the gap.
+	ImputeLinear
+	// ImputePriorWeek copies the reading from the same time a week earlier, falling back to ImputeLinear.
+	ImputePriorWeek
+)
+
+// ParseImputationStrategy returns the strategy for a name such as "linear".
+func ParseImputationStrategy(name string) (ImputationStrategy, error) {
+	switch name {
+	case "zero":
+		return ImputeZero, nil
+	case "linear":
+		return ImputeLinear, nil
+	case "prior_week":
+		return ImputePriorWeek, nil
+	}
+	return 0, fmt.Errorf("unknown imputation strategy '%s'. Expected one of zero, linear or prior_week", name)
+}
+
+// FillGaps returns the readings sorted chronologically, with duplicates removed (the first reading wins) and gaps
+// filled according to the strategy. Added readings are marked as estimated. It also returns the quality report of
+// the original readings.
+func FillGaps(file csvparser.CsvFile, strategy ImputationStrategy) (csvparser.CsvFile, QualityReport) {
+	report := CheckQuality(file)
+	rows := dedupe(sortedRows(file))
+
+	byStart := make(map[time.Time]float64, len(rows))
+	for _, r := range rows {
+		byStart[r.StartTime] = r.UsageKwh
+	}
+
+	out := make(csvparser.CsvFile, 0, len(rows)+report.MissingIntervals())
+	for i, r := range rows {
+		if i > 0 && r.StartTime.After(rows[i-1].EndTime) {
+			before, after := rows[i-1], r
+			steps := int(after.StartTime.Sub(before.EndTime) / report.Interval)
+			for s := 0; s < steps; s++ {
+				start := before.EndTime.Add(time.Duration(s) * report.Interval)
+				usage := imputedUsage(strategy, start, before, after, float64(s+1)/float64(steps+1), byStart)
+				byStart[start] = usage
+				out = append(out, csvparser.CsvRow{
+					StartTime:      start,
+					EndTime:        start.Add(report.Interval),
+					UsageKwh:       usage,
+					ReadingQuality: csvparser.QualityEstimated,
+					Meter:          before.Meter,
+				})
+			}
+		}
+		out = append(out, r)
+	}
+	return out, report
+}
+
+func imputedUsage(strategy ImputationStrategy, start time.Time, before csvparser.CsvRow, after csvparser.CsvRow, fraction float64, byStart map[time.Time]float64) float64 {
+	switch strategy {
+	case ImputeZero:
+		return 0
+	case ImputePriorWeek:
+		if usage, ok := byStart[start.AddDate(0, 0, -7)]; ok {
+			return usage
+		}
+	}
+	return before.UsageKwh + (after.UsageKwh-before.UsageKwh)*fraction
+}
+
+// FilterByQuality removes the readings whose quality is worse than min.
+func FilterByQuality(file csvparser.CsvFile, min csvparser.ReadingQuality) csvparser.CsvFile {
+	out := make(csvparser.CsvFile, 0, len(file))
+	for _, r := range file {
+		if r.ReadingQuality.AtLeast(min) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// ReestimateBelowQuality replaces the readings whose quality is worse than min with readings imputed from their
+// neighbors. Readings before the first or after the last good reading have only one neighbor, so they carry its
+// usage, or 0 with ImputeZero. If no reading is good enough, the result is empty. The result is sorted
+// chronologically.
+func ReestimateBelowQuality(file csvparser.CsvFile, min csvparser.ReadingQuality, strategy ImputationStrategy) csvparser.CsvFile {
+	filled, _ := FillGaps(FilterByQuality(file, min), strategy)
+	if len(filled) == 0 {
+		return filled
+	}
+	first, last := filled[0], filled[len(filled)-1]
+	out := make(csvparser.CsvFile, 0, len(file))
+	trailing := make(csvparser.CsvFile, 0)
+	for _, r := range dedupe(sortedRows(file)) {
+		if r.ReadingQuality.AtLeast(min) {
+			continue
+		}
+		if r.StartTime.Before(first.StartTime) {
+			out = append(out, edgeEstimate(r, first, strategy))
+		} else if !r.StartTime.Before(last.EndTime) {
+			trailing = append(trailing, edgeEstimate(r, last, strategy))
+		}
+	}
+	out = append(out, filled...)
+	return append(out, trailing...)
+}
+
+// edgeEstimate returns the reading re-estimated from its only good neighbor.
+func edgeEstimate(r csvparser.CsvRow, neighbor csvparser.CsvRow, strategy ImputationStrategy) csvparser.CsvRow {
+	r.ReadingQuality = csvparser.QualityEstimated
+	r.UsageKwh = neighbor.UsageKwh
+	if strategy == ImputeZero {
+		r.UsageKwh = 0
+	}
+	return r
+}
+
+// CompletenessReport summarizes how much of the billed time has data.
+type CompletenessReport struct {
+	Days         int
+	CompleteDays int
+	PartialDays  []time.Time
+	Covered      time.Duration
+	Expected     time.Duration
+}
+
+// Coverage returns the fraction of the expected time that has data.
+func (r *CompletenessReport) Coverage() float64 {
+	if r.Expected == 0 {
+		return 0
+	}
+	return float64(r.Covered) / float64(r.Expected)
+}
+
+// Completeness returns the completeness of the given days.
+func Completeness(days []UsageDay) CompletenessReport {
+	out := CompletenessReport{
+		Days:        len(days),
+		PartialDays: make([]time.Time, 0),
+	}
+	for i := range days {
+		d := &days[i]
+		out.Covered += d.Covered
+		out.Expected += d.Day.AddDate(0, 0, 1).Sub(d.Day)
+		if d.Complete() {
+			out.CompleteDays++
+		} else {
+			out.PartialDays = append(out.PartialDays, d.Day)
+		}
+	}
+	return out
+}
+
+func sortedRows(file csvparser.CsvFile) csvparser.CsvFile {
+	rows := make(csvparser.CsvFile, len(file))
+	copy(rows, file)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].StartTime.Before(rows[j].StartTime)
+	})
+	return rows
+}
+
+// dedupe removes rows with the same start time as the previous row. Rows must be sorted.
+func dedupe(rows csvparser.CsvFile) csvparser.CsvFile {
+	out := make(csvparser.CsvFile, 0, len(rows))
+	for i, r := range rows {
+		if i > 0 && r.StartTime.Equal(rows[i-1].StartTime) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func mostCommonDuration(rows csvparser.CsvFile) time.Duration {
+	counts := make(map[time.Duration]int)
+	best := DefaultReadingInterval
+	for i := range rows {
+		d := rows[i].Duration()
+		if d <= 0 {
+			continue
+		}
+		counts[d]++
+		if counts[d] > counts[best] {
+			best = d
+		}
+	}
+	return best
+}
+
+func toIntervals(rows csvparser.CsvFile) []timebucket.Interval {
+	out := make([]timebucket.Interval, len(rows))
+	for i := range rows {
+		out[i] = &csvRowInterval{row: rows[i]}
+	}
+	return out
+}
@@ -0,0 +1,180 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/stretchr/testify/assert"
+)
+
+var jan1 = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fullDay returns one day of 15-minute readings with constant usage.
+func fullDay(day time.Time, usage float64) csvparser.CsvFile {
+	out := make(csvparser.CsvFile, 0)
+	for i := 0; i < 96; i++ {
+		out = append(out, csvparser.NewRowWith15MinuteDuration(day.Add(time.Duration(i)*15*time.Minute), usage))
+	}
+	return out
+}
+
+// without returns the rows with the given start times removed.
+func without(rows csvparser.CsvFile, starts ...time.Time) csvparser.CsvFile {
+	out := make(csvparser.CsvFile, 0)
+	for _, r := range rows {
+		skip := false
+		for _, s := range starts {
+			if r.StartTime.Equal(s) {
+				skip = true
+			}
+		}
+		if !skip {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func TestCheckQuality_FullDay_IsClean(t *testing.T) {
+	got := CheckQuality(fullDay(jan1, 1))
+
+	assert.True(t, got.IsClean())
+	assert.Equal(t, 15*time.Minute, got.Interval)
+	assert.Equal(t, 96, got.Intervals)
+}
+
+func TestCheckQuality_MissingReadings_ReportsGapAndPartialDay(t *testing.T) {
+	in := without(fullDay(jan1, 1), jan1.Add(1*time.Hour), jan1.Add(75*time.Minute))
+
+	got := CheckQuality(in)
+
+	assert.Equal(t, []Gap{{Start: jan1.Add(1 * time.Hour), End: jan1.Add(90 * time.Minute)}}, got.Gaps)
+	assert.Equal(t, 2, got.MissingIntervals())
+	assert.Equal(t, []PartialDay{{Day: jan1, Intervals: 94, ExpectedIntervals: 96}}, got.PartialDays)
+}
+
+func TestCheckQuality_DuplicateTimestamps_Reported(t *testing.T) {
+	in := append(fullDay(jan1, 1), csvparser.NewRowWith15MinuteDuration(jan1, 5))
+
+	got := CheckQuality(in)
+
+	assert.Equal(t, []time.Time{jan1}, got.DuplicateTimestamps)
+	assert.Empty(t, got.PartialDays)
+}
+
+func TestFillGaps_Zero(t *testing.T) {
+	in := without(fullDay(jan1, 1), jan1.Add(1*time.Hour))
+
+	got, report := FillGaps(in, ImputeZero)
+
+	assert.Len(t, report.Gaps, 1)
+	assert.Len(t, got, 96)
+	assert.Equal(t, 0.0, got[4].UsageKwh)
+	assert.Equal(t, csvparser.QualityEstimated, got[4].ReadingQuality)
+	after := CheckQuality(got)
+	assert.True(t, after.IsClean())
+}
+
+func TestFillGaps_Linear(t *testing.T) {
+	in := csvparser.CsvFile{
+		csvparser.NewRowWith15MinuteDuration(jan1, 1),
+		csvparser.NewRowWith15MinuteDuration(jan1.Add(45*time.Minute), 4),
+	}
+
+	got, _ := FillGaps(in, ImputeLinear)
+
+	assert.Len(t, got, 4)
+	assert.InEpsilon(t, 2.0, got[1].UsageKwh, 0.0001)
+	assert.InEpsilon(t, 3.0, got[2].UsageKwh, 0.0001)
+}
+
+func TestFillGaps_PriorWeek_CopiesSameTimeLastWeek(t *testing.T) {
+	in := append(fullDay(jan1, 2), without(fullDay(jan1.AddDate(0, 0, 7), 1), jan1.AddDate(0, 0, 7))...)
+	// Make the week in between present so the only gap is the missing reading.
+	for d := 1; d < 7; d++ {
+		in = append(in, fullDay(jan1.AddDate(0, 0, d), 1)...)
+	}
+
+	got, report := FillGaps(in, ImputePriorWeek)
+
+	assert.Len(t, report.Gaps, 1)
+	assert.Equal(t, jan1.AddDate(0, 0, 7), got[96*7].StartTime)
+	assert.Equal(t, 2.0, got[96*7].UsageKwh)
+}
+
+func TestFillGaps_Duplicates_FirstWins(t *testing.T) {
+	in := csvparser.CsvFile{
+		csvparser.NewRowWith15MinuteDuration(jan1, 1),
+		csvparser.NewRowWith15MinuteDuration(jan1, 2),
+	}
+
+	got, _ := FillGaps(in, ImputeZero)
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, 1.0, got[0].UsageKwh)
+}
+
+func TestFilterByQuality_RemovesWorseReadings(t *testing.T) {
+	in := fullDay(jan1, 1)[:3]
+	in[1].ReadingQuality = csvparser.QualityEstimated
+	in[2].ReadingQuality = csvparser.QualityValidated
+
+	got := FilterByQuality(in, csvparser.QualityActual)
+
+	assert.Equal(t, csvparser.CsvFile{in[0], in[2]}, got)
+}
+
+func TestReestimateBelowQuality_ReplacesWorseReadings(t *testing.T) {
+	in := csvparser.CsvFile{
+		csvparser.NewRowWith15MinuteDuration(jan1, 1),
+		csvparser.NewRowWith15MinuteDuration(jan1.Add(15*time.Minute), 100),
+		csvparser.NewRowWith15MinuteDuration(jan1.Add(30*time.Minute), 3),
+	}
+	in[1].ReadingQuality = csvparser.QualityMissing
+
+	got := ReestimateBelowQuality(in, csvparser.QualityEstimated, ImputeLinear)
+
+	assert.Len(t, got, 3)
+	assert.InEpsilon(t, 2.0, got[1].UsageKwh, 0.0001)
+	assert.Equal(t, csvparser.QualityEstimated, got[1].ReadingQuality)
+}
+
+func TestReestimateBelowQuality_FirstAndLastReadings_CarryNearestReading(t *testing.T) {
+	in := fullDay(jan1, 1)[:5]
+	in[0].ReadingQuality = csvparser.QualityMissing
+	in[1].UsageKwh = 2
+	in[3].UsageKwh = 4
+	in[4].ReadingQuality = csvparser.QualityEstimated
+
+	got := ReestimateBelowQuality(in, csvparser.QualityActual, ImputeLinear)
+
+	if assert.Len(t, got, 5) {
+		assert.Equal(t, jan1, got[0].StartTime)
+		assert.Equal(t, 2.0, got[0].UsageKwh)
+		assert.Equal(t, csvparser.QualityEstimated, got[0].ReadingQuality)
+		assert.Equal(t, jan1.Add(time.Hour), got[4].StartTime)
+		assert.Equal(t, 4.0, got[4].UsageKwh)
+		assert.Equal(t, csvparser.QualityEstimated, got[4].ReadingQuality)
+	}
+}
+
+func TestCompleteness_PartialDay_Reported(t *testing.T) {
+	hours, err := AggregateIntoHourWindows(append(fullDay(jan1, 1), without(fullDay(jan1.AddDate(0, 0, 1), 1), jan1.AddDate(0, 0, 1))...))
+	assert.NoError(t, err)
+	days, err := SplitByDay(hours)
+	assert.NoError(t, err)
+
+	got := Completeness(days)
+
+	assert.Equal(t, 2, got.Days)
+	assert.Equal(t, 1, got.CompleteDays)
+	assert.Equal(t, []time.Time{jan1.AddDate(0, 0, 1)}, got.PartialDays)
+	assert.InEpsilon(t, 191.0/192, got.Coverage(), 0.0001)
+}
+
+func TestParseImputationStrategy_Unknown_Fails(t *testing.T) {
+	_, err := ParseImputationStrategy("nope")
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package analyzer
+
+import "time"
+
+// IsSummerMonth returns true if it's a summer month.
+// Winter allocation: October through May
+// Summer allocation: June through September
+func IsSummerMonth(m time.Month) bool {
+	return m >= 6 && m <= 9
+}
+
+// Season is the SCE rate season.
+type Season int
+
+const (
+	Summer Season = iota
+	Winter
+)
+
+// SeasonOf returns the season of the month containing t.
+func SeasonOf(t time.Time) Season {
+	if IsSummerMonth(t.Month()) {
+		return Summer
+	}
+	return Winter
+}
+
+func (s Season) String() string {
+	switch s {
+	case Summer:
+		return "Summer"
+	case Winter:
+		return "Winter"
+	}
+	panic("unexpected")
+}
+
+// DayType distinguishes the days that TOU plans price differently.
+type DayType int
+
+const (
+	Weekday DayType = iota
+	Weekend
+	Holiday
+)
+
+// DayTypeOf returns the type of the day containing t. Holidays that fall on a weekend are holidays.
+func DayTypeOf(t time.Time) DayType {
+	if IsHoliday(t) {
+		return Holiday
+	}
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return Weekend
+	}
+	return Weekday
+}
+
+func (d DayType) String() string {
+	switch d {
+	case Weekday:
+		return "Weekday"
+	case Weekend:
+		return "Weekend"
+	case Holiday:
+		return "Holiday"
+	}
+	panic("unexpected")
+}
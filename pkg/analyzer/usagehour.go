@@ -1,11 +1,12 @@
 package analyzer
 
 import (
-	"fmt"
+	"math"
 	"sort"
 	"time"
 
 	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/kodek/sce-greenbutton/pkg/timebucket"
 )
 
 type UsageHour interface {
@@ -35,27 +36,83 @@ func (h *GreenButtonHour) EndTime() time.Time {
 	return h.DataPoints[len(h.DataPoints)-1].EndTime
 }
 
-func AggregateIntoHourWindows(parsedFile csvparser.CsvFile) ([]UsageHour, error) {
-	valuesByHour := make(map[time.Time][]csvparser.CsvRow)
+// SimpleHour is a UsageHour backed by a single energy value. It is used for simulated or derived data that
+// does not come from a Green Button file.
+type SimpleHour struct {
+	startTime time.Time
+	endTime   time.Time
+	usageKwh  float64
+}
+
+// NewUsageHour returns a UsageHour with the given window and energy.
+func NewUsageHour(start time.Time, end time.Time, usageKwh float64) UsageHour {
+	return &SimpleHour{
+		startTime: start,
+		endTime:   end,
+		usageKwh:  usageKwh,
+	}
+}
+
+func (h *SimpleHour) UsageKwh() float64 {
+	return h.usageKwh
+}
 
-	for _, v := range parsedFile {
-		hr := truncateToHour(v.StartTime)
-		hrEnd := truncateToHour(v.EndTime.Add(-1 * time.Second))
-		if hr != hrEnd {
-			return nil, fmt.Errorf("start and end of data point should be within the same hour for %+v", v)
+func (h *SimpleHour) StartTime() time.Time {
+	return h.startTime
+}
+
+func (h *SimpleHour) EndTime() time.Time {
+	return h.endTime
+}
+
+// LowQualityKwh returns the absolute energy of the estimated and missing readings in the hour.
+func (h *GreenButtonHour) LowQualityKwh() float64 {
+	total := 0.0
+	for _, p := range h.DataPoints {
+		if p.ReadingQuality.IsLowQuality() {
+			total += math.Abs(p.UsageKwh)
 		}
-		valuesByHour[hr] = append(valuesByHour[hr], v)
 	}
+	return total
+}
+
+// CoveredDuration returns the total duration of the data points, which is less than an hour if readings are missing.
+func (h *GreenButtonHour) CoveredDuration() time.Duration {
+	total := time.Duration(0)
+	for _, p := range h.DataPoints {
+		total += p.Duration()
+	}
+	return total
+}
+
+// PeakDemandKw returns the highest demand of the readings in the hour.
+func (h *GreenButtonHour) PeakDemandKw() float64 {
+	peak := math.Inf(-1)
+	for i := range h.DataPoints {
+		peak = math.Max(peak, h.DataPoints[i].DemandKw())
+	}
+	return peak
+}
 
-	hours := make([]GreenButtonHour, 0)
-	// Map iteration is random in Go, but we will sort the data points in chronological order later.
-	for k, v := range valuesByHour {
+func AggregateIntoHourWindows(parsedFile csvparser.CsvFile) ([]UsageHour, error) {
+	buckets, err := timebucket.Resample(toIntervals(parsedFile), timebucket.Hourly())
+	if err != nil {
+		return nil, err
+	}
+
+	hours := make([]GreenButtonHour, 0, len(buckets))
+	for _, b := range buckets {
+		dataPoints := make([]csvparser.CsvRow, len(b.Points))
+		for i, p := range b.Points {
+			dataPoints[i] = p.(*csvRowInterval).row
+		}
 		hours = append(hours, GreenButtonHour{
-			startTime:  k,
-			DataPoints: v,
+			startTime:  b.Start,
+			DataPoints: dataPoints,
 		})
 	}
-	sort.Slice(hours, func(i, j int) bool {
+	// Rows may come in any order, so sort the hours chronologically.
+	sort.SliceStable(hours, func(i, j int) bool {
 		return hours[i].StartTime().Before(hours[j].StartTime())
 	})
 
@@ -66,6 +123,11 @@ func AggregateIntoHourWindows(parsedFile csvparser.CsvFile) ([]UsageHour, error)
 	return out, nil
 }
 
-func truncateToHour(in time.Time) time.Time {
-	return time.Date(in.Year(), in.Month(), in.Day(), in.Hour(), 0, 0, 0, in.Location())
+// csvRowInterval adapts a CsvRow to a timebucket.Interval.
+type csvRowInterval struct {
+	row csvparser.CsvRow
 }
+
+func (r *csvRowInterval) UsageKwh() float64    { return r.row.UsageKwh }
+func (r *csvRowInterval) StartTime() time.Time { return r.row.StartTime }
+func (r *csvRowInterval) EndTime() time.Time   { return r.row.EndTime }
@@ -16,7 +16,7 @@ func TestConvertsDataPointToUsageHour(t *testing.T) {
 			StartTime:      now,
 			EndTime:        now.Add(15 * time.Minute),
 			UsageKwh:       123,
-			ReadingQuality: "",
+			ReadingQuality: csvparser.QualityActual,
 		},
 	}
 
@@ -47,7 +47,7 @@ func TestWindowOverlapsHourFails(t *testing.T) {
 			StartTime:      time.Date(2020, 01, 01, 01, 50, 0, 0, time.UTC),
 			EndTime:        time.Date(2020, 01, 01, 02, 10, 0, 0, time.UTC),
 			UsageKwh:       1,
-			ReadingQuality: "",
+			ReadingQuality: csvparser.QualityActual,
 		},
 	}
 
@@ -70,3 +70,29 @@ func TestUsageHour_EndTime_ReturnsEndTimeFromLastPoint(t *testing.T) {
 	assert.Equal(t, 1, len(got))
 	assert.Equal(t, now.Add(30*time.Minute), got[0].EndTime())
 }
+
+func TestNewUsageHour_ReturnsGivenValues(t *testing.T) {
+	got := NewUsageHour(now, now.Add(time.Hour), 2.5)
+
+	assert.Equal(t, now, got.StartTime())
+	assert.Equal(t, now.Add(time.Hour), got.EndTime())
+	assert.Equal(t, 2.5, got.UsageKwh())
+}
+
+func TestGreenButtonHour_LowQualityKwh_SumsEstimatedAndMissing(t *testing.T) {
+	estimated := csvparser.NewRowWith15MinuteDuration(now.Add(15*time.Minute), -2)
+	estimated.ReadingQuality = csvparser.QualityEstimated
+	missing := csvparser.NewRowWith15MinuteDuration(now.Add(30*time.Minute), 0.5)
+	missing.ReadingQuality = csvparser.QualityMissing
+	parsed := csvparser.CsvFile{
+		csvparser.NewRowWith15MinuteDuration(now, 1),
+		estimated,
+		missing,
+	}
+
+	got, err := AggregateIntoHourWindows(parsed)
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, 2.5, got[0].(*GreenButtonHour).LowQualityKwh())
+}
@@ -0,0 +1,202 @@
+// Package anomaly flags hours and days whose usage is unusual compared to the recent history of the same hour of
+// the week.
+package anomaly
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+)
+
+// Kind is the kind of anomaly.
+type Kind int
+
+const (
+	// Spike is an hour with much more usage than usual.
+	Spike Kind = iota
+	// HighBaseload is a day whose always-on load is much higher than usual, like a pump that doesn't turn off.
+	HighBaseload
+	// ExportDrop is an hour that usually exports solar energy but exported much less, which can mean a solar outage.
+	ExportDrop
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Spike:
+		return "Spike"
+	case HighBaseload:
+		return "High baseload"
+	case ExportDrop:
+		return "Export drop"
+	}
+	panic("unexpected")
+}
+
+// Options tunes how unusual an interval must be to be flagged.
+type Options struct {
+	// WindowWeeks is how many previous weeks make up the baseline of each hour of the week.
+	WindowWeeks int
+	// MinSamples is the fewest baseline values needed to judge an interval.
+	MinSamples int
+	// Threshold is how many standard deviations above the baseline an interval must be to be flagged.
+	Threshold float64
+	// MinDeltaKwh ignores differences smaller than this, so that tiny loads with little variance aren't flagged. For
+	// HighBaseload it is compared to the increase in kW.
+	MinDeltaKwh float64
+}
+
+// DefaultOptions compares against four weeks of history and flags intervals 3 standard deviations above it.
+func DefaultOptions() Options {
+	return Options{
+		WindowWeeks: 4,
+		MinSamples:  3,
+		Threshold:   3,
+		MinDeltaKwh: 0.5,
+	}
+}
+
+// Anomaly is an unusual interval.
+type Anomaly struct {
+	Kind        Kind
+	Start       time.Time
+	End         time.Time
+	ActualKwh   float64
+	ExpectedKwh float64
+	ZScore      float64
+	// CostImpact is the estimated extra cost in $ compared to the baseline, at the plan's marginal rates.
+	CostImpact float64
+}
+
+// Detect returns the anomalies in the days, sorted by start time.
+func Detect(days []analyzer.UsageDay, plan costcalculator.TouPlan, opts Options) []Anomaly {
+	hours := make([]analyzer.UsageHour, 0, len(days)*24)
+	for _, d := range days {
+		hours = append(hours, d.DataPoints...)
+	}
+	out := append(DetectHourly(hours, plan, opts), DetectHighBaseload(days, plan, opts)...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Start.Before(out[j].Start)
+	})
+	return out
+}
+
+// DetectHourly flags spikes and export drops by comparing each hour to the same hour of the week over the previous
+// opts.WindowWeeks weeks. Hours must be sorted chronologically.
+func DetectHourly(hours []analyzer.UsageHour, plan costcalculator.TouPlan, opts Options) []Anomaly {
+	out := make([]Anomaly, 0)
+	history := make(map[int][]analyzer.UsageHour)
+	for _, hr := range hours {
+		key := hourOfWeek(hr.StartTime())
+		baseline := recent(history[key], hr.StartTime().AddDate(0, 0, -7*opts.WindowWeeks))
+		history[key] = append(baseline, hr)
+		if len(baseline) < opts.MinSamples {
+			continue
+		}
+
+		values := make([]float64, len(baseline))
+		for i, b := range baseline {
+			values[i] = b.UsageKwh()
+		}
+		expected, stdDev := meanAndStdDev(values)
+		delta := hr.UsageKwh() - expected
+		if delta < opts.MinDeltaKwh || delta < opts.Threshold*stdDev {
+			continue
+		}
+		kind := Spike
+		if expected < 0 {
+			kind = ExportDrop
+		}
+		out = append(out, Anomaly{
+			Kind:        kind,
+			Start:       hr.StartTime(),
+			End:         hr.EndTime(),
+			ActualKwh:   hr.UsageKwh(),
+			ExpectedKwh: expected,
+			ZScore:      delta / stdDev,
+			CostImpact:  delta * costcalculator.MarginalRate(hr.StartTime(), plan),
+		})
+	}
+	return out
+}
+
+// DetectHighBaseload flags days whose overnight minimum load is much higher than over the previous
+// opts.WindowWeeks weeks. Days must be sorted chronologically.
+func DetectHighBaseload(days []analyzer.UsageDay, plan costcalculator.TouPlan, opts Options) []Anomaly {
+	out := make([]Anomaly, 0)
+	type dayBaseload struct {
+		day time.Time
+		kw  float64
+	}
+	history := make([]dayBaseload, 0)
+	for _, d := range days {
+		kw := analyzer.BaseloadKw([]analyzer.UsageDay{d})
+		windowStart := d.Day.AddDate(0, 0, -7*opts.WindowWeeks)
+		for len(history) > 0 && history[0].day.Before(windowStart) {
+			history = history[1:]
+		}
+		values := make([]float64, len(history))
+		for i, h := range history {
+			values[i] = h.kw
+		}
+		history = append(history, dayBaseload{day: d.Day, kw: kw})
+		if len(values) < opts.MinSamples {
+			continue
+		}
+
+		expected, stdDev := meanAndStdDev(values)
+		delta := kw - expected
+		if delta < opts.MinDeltaKwh || delta < opts.Threshold*stdDev {
+			continue
+		}
+		end := d.Day.AddDate(0, 0, 1)
+		hours := end.Sub(d.Day).Hours()
+		cost := 0.0
+		for t := d.Day; t.Before(end); t = t.Add(time.Hour) {
+			cost += delta * costcalculator.MarginalRate(t, plan)
+		}
+		out = append(out, Anomaly{
+			Kind:        HighBaseload,
+			Start:       d.Day,
+			End:         end,
+			ActualKwh:   kw * hours,
+			ExpectedKwh: expected * hours,
+			ZScore:      delta / stdDev,
+			CostImpact:  cost,
+		})
+	}
+	return out
+}
+
+func hourOfWeek(t time.Time) int {
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// recent returns the hours that start at or after since. Hours must be sorted chronologically.
+func recent(hours []analyzer.UsageHour, since time.Time) []analyzer.UsageHour {
+	for len(hours) > 0 && hours[0].StartTime().Before(since) {
+		hours = hours[1:]
+	}
+	return hours
+}
+
+// meanAndStdDev returns the mean and sample standard deviation of the values. The standard deviation is at least
+// 10% of the mean, so that a perfectly steady history doesn't flag every small change.
+func meanAndStdDev(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	squares := 0.0
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	stdDev := 0.0
+	if len(values) > 1 {
+		stdDev = math.Sqrt(squares / float64(len(values)-1))
+	}
+	return mean, math.Max(stdDev, math.Max(0.1*math.Abs(mean), 0.01))
+}
@@ -0,0 +1,106 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/stretchr/testify/assert"
+)
+
+var jan6 = time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+
+// hourlyLoad returns n days of hourly usage from usage(day, hour).
+func hourlyLoad(start time.Time, n int, usage func(day int, hour int) float64) []analyzer.UsageHour {
+	hours := make([]analyzer.UsageHour, 0)
+	for d := 0; d < n; d++ {
+		for h := 0; h < 24; h++ {
+			s := start.AddDate(0, 0, d).Add(time.Duration(h) * time.Hour)
+			hours = append(hours, analyzer.NewUsageHour(s, s.Add(time.Hour), usage(d, h)))
+		}
+	}
+	return hours
+}
+
+func toDaysOrDie(t *testing.T, hours []analyzer.UsageHour) []analyzer.UsageDay {
+	days, err := analyzer.SplitByDay(hours)
+	assert.NoError(t, err)
+	return days
+}
+
+// jitter alternates usage between weeks so the baseline has some variance.
+func jitter(day int) float64 {
+	return 0.05 * float64((day/7)%2)
+}
+
+func TestDetect_Spike_WithCostImpact(t *testing.T) {
+	plan := costcalculator.NewTouDPrime()
+	days := toDaysOrDie(t, hourlyLoad(jan6, 35, func(d int, h int) float64 {
+		if d == 28 && h == 18 {
+			return 6
+		}
+		return 1 + jitter(d)
+	}))
+
+	got := Detect(days, plan, DefaultOptions())
+
+	assert.Len(t, got, 1)
+	spike := jan6.AddDate(0, 0, 28).Add(18 * time.Hour)
+	assert.Equal(t, Spike, got[0].Kind)
+	assert.Equal(t, spike, got[0].Start)
+	assert.InDelta(t, 1.025, got[0].ExpectedKwh, 0.0001)
+	assert.InDelta(t, 4.975*costcalculator.MarginalRate(spike, plan), got[0].CostImpact, 0.0001)
+}
+
+func TestDetectHourly_NotEnoughHistory_NothingFlagged(t *testing.T) {
+	hours := hourlyLoad(jan6, 14, func(d int, h int) float64 {
+		if d == 13 {
+			return 10
+		}
+		return 1
+	})
+
+	got := DetectHourly(hours, costcalculator.NewTouDPrime(), DefaultOptions())
+
+	assert.Empty(t, got)
+}
+
+func TestDetect_ExportDrop(t *testing.T) {
+	days := toDaysOrDie(t, hourlyLoad(jan6, 35, func(d int, h int) float64 {
+		if h == 12 {
+			if d == 30 {
+				return 0.5
+			}
+			return -2 - jitter(d)
+		}
+		return 1 + jitter(d)
+	}))
+
+	got := Detect(days, costcalculator.NewTouDPrime(), DefaultOptions())
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, ExportDrop, got[0].Kind)
+	assert.Equal(t, jan6.AddDate(0, 0, 30).Add(12*time.Hour), got[0].Start)
+}
+
+func TestDetectHighBaseload_AlwaysOnLoadIncrease(t *testing.T) {
+	plan := costcalculator.NewTouDPrime()
+	days := toDaysOrDie(t, hourlyLoad(jan6, 35, func(d int, h int) float64 {
+		if d >= 33 {
+			return 2
+		}
+		return 0.5 + jitter(d)
+	}))
+
+	got := DetectHighBaseload(days, plan, DefaultOptions())
+
+	assert.Len(t, got, 2)
+	assert.Equal(t, HighBaseload, got[0].Kind)
+	assert.Equal(t, jan6.AddDate(0, 0, 33), got[0].Start)
+	assert.Greater(t, got[0].CostImpact, 0.0)
+}
+
+func TestKind_String(t *testing.T) {
+	assert.Equal(t, "Export drop", ExportDrop.String())
+}
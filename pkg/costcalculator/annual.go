@@ -0,0 +1,18 @@
+package costcalculator
+
+import "github.com/kodek/sce-greenbutton/pkg/analyzer"
+
+// AverageDaysPerYear and AverageDaysPerMonth are used to normalize costs into yearly and monthly figures.
+const (
+	AverageDaysPerYear  = 365.25
+	AverageDaysPerMonth = AverageDaysPerYear / 12
+)
+
+// YearScale returns the factor that scales the cost of billing the days to an average year. Bills charge per day
+// with readings, so days missing from the data don't count.
+func YearScale(days []analyzer.UsageDay) float64 {
+	if len(days) == 0 {
+		return 0
+	}
+	return AverageDaysPerYear / float64(len(days))
+}
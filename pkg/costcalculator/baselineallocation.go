@@ -2,7 +2,12 @@ package costcalculator
 
 import (
 	"flag"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
 )
 
 // From https://www.sce.com/residential/rates/Standard-Residential-Rate-Plan
@@ -11,24 +16,126 @@ const SIMI_WINTER_DAILY_ALLOCATION = 12.3
 
 const MedicalBaselineAllocation = 16.5
 
+// BaselineRegion is an SCE baseline region.
+type BaselineRegion string
+
+const SimiValley BaselineRegion = "Simi Valley"
+
+// seasonAllocation is a daily baseline allocation in kWh for each season.
+type seasonAllocation struct {
+	summer float64
+	winter float64
+}
+
+// regionAllocations are the basic service allocations of each baseline region.
+var regionAllocations = map[BaselineRegion]seasonAllocation{
+	SimiValley: {summer: SIMI_SUMMER_DAILY_ALLOCATION, winter: SIMI_WINTER_DAILY_ALLOCATION},
+}
+
+var baselineRegion = SimiValley
+
+func init() {
+	flag.Var(&baselineRegion, "baseline_region", "SCE baseline region whose basic allocations are used. One of: "+strings.Join(regionNames(), ", ")+".")
+}
+
+func (r *BaselineRegion) String() string {
+	return string(*r)
+}
+
+func (r *BaselineRegion) Set(value string) error {
+	for region := range regionAllocations {
+		if strings.EqualFold(strings.TrimSpace(value), string(region)) {
+			*r = region
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported baseline region '%s'; one of: %s", value, strings.Join(regionNames(), ", "))
+}
+
+func regionNames() []string {
+	out := make([]string, 0, len(regionAllocations))
+	for region := range regionAllocations {
+		out = append(out, string(region))
+	}
+	sort.Strings(out)
+	return out
+}
+
 var useMedicalBaseline = flag.Bool("use_medical_baseline", true, "Add medical baseline")
 
-// GetDailyAllocation returns the daily allocation based on the following data:
-func GetDailyAllocation(t time.Time) float64 {
+// All-electric homes get a larger baseline allocation. The amounts depend on the baseline region, so they must be
+// supplied by the user (they are printed on the bill and in SCE's baseline region table).
+var allElectricSummerAllocation = flag.Float64("all_electric_summer_daily_allocation", 0, "Daily baseline allocation in kWh for all-electric service in summer.")
+var allElectricWinterAllocation = flag.Float64("all_electric_winter_daily_allocation", 0, "Daily baseline allocation in kWh for all-electric service in winter.")
+
+// ServiceType decides which baseline allocation applies.
+type ServiceType int
+
+const (
+	// BasicService is for homes with gas heating.
+	BasicService ServiceType = iota
+	// AllElectricService is for homes heated with electricity.
+	AllElectricService
+)
+
+// GetDailyAllocation returns the daily allocation of the given type of service on the day of t. Basic service gets the
+// allocation of --baseline_region. The allocation of all-electric service is 0 until it is set by flags;
+// CheckServiceType reports that.
+func GetDailyAllocation(t time.Time, service ServiceType) float64 {
 	medicalOffset := 0.0
 	if *useMedicalBaseline {
 		medicalOffset = MedicalBaselineAllocation
 	}
+	if service == AllElectricService {
+		if isSummerMonth(t.Month()) {
+			return *allElectricSummerAllocation + medicalOffset
+		}
+		return *allElectricWinterAllocation + medicalOffset
+	}
+	allocation := regionAllocations[baselineRegion]
 	if isSummerMonth(t.Month()) {
-		return SIMI_SUMMER_DAILY_ALLOCATION + medicalOffset
+		return allocation.summer + medicalOffset
 	} else {
-		return SIMI_WINTER_DAILY_ALLOCATION + medicalOffset
+		return allocation.winter + medicalOffset
+	}
+}
+
+// CheckServiceType returns an error if the daily allocation of the given type of service is not known.
+func CheckServiceType(service ServiceType) error {
+	if service == AllElectricService && (*allElectricSummerAllocation == 0 || *allElectricWinterAllocation == 0) {
+		return fmt.Errorf("all-electric service requires --all_electric_summer_daily_allocation and --all_electric_winter_daily_allocation")
+	}
+	return nil
+}
+
+// ServicePlan is implemented by plans billed for a service other than BasicService.
+type ServicePlan interface {
+	TouPlan
+	ServiceType() ServiceType
+}
+
+type servicePlan struct {
+	TouPlan
+	service ServiceType
+}
+
+// WithServiceType returns plan with the baseline allocation of the given type of service.
+func WithServiceType(plan TouPlan, service ServiceType) TouPlan {
+	return &servicePlan{TouPlan: plan, service: service}
+}
+
+func (p *servicePlan) ServiceType() ServiceType {
+	return p.service
+}
+
+// serviceTypeOf returns the type of service the plan is billed for.
+func serviceTypeOf(plan TouPlan) ServiceType {
+	if p, ok := plan.(ServicePlan); ok {
+		return p.ServiceType()
 	}
+	return BasicService
 }
 
-// Returns true if it's a summer month.
-// Winter allocation: October through May
-// Summer allocation: June through September
 func isSummerMonth(m time.Month) bool {
-	return m >= 6 && m <= 9
+	return analyzer.IsSummerMonth(m)
 }
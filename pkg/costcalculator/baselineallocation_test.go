@@ -10,11 +10,50 @@ import (
 func TestMedicalBaselineEnabled_BaselineIsHigher(t *testing.T) {
 	err := flag.CommandLine.Parse([]string{"--use_medical_baseline=false"})
 	assert.NoError(t, err)
-	noMedicalBaseline := GetDailyAllocation(now)
+	noMedicalBaseline := GetDailyAllocation(now, BasicService)
 
 	err = flag.CommandLine.Parse([]string{"--use_medical_baseline=true"})
 	assert.NoError(t, err)
-	withMedicalBaseline := GetDailyAllocation(now)
+	withMedicalBaseline := GetDailyAllocation(now, BasicService)
 
 	assert.Greater(t, withMedicalBaseline, noMedicalBaseline)
 }
+
+func TestAllElectricService_UsesAllElectricAllocation(t *testing.T) {
+	err := flag.CommandLine.Parse([]string{"--use_medical_baseline=false", "--all_electric_summer_daily_allocation=20", "--all_electric_winter_daily_allocation=30"})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, flag.CommandLine.Parse([]string{"--use_medical_baseline=true", "--all_electric_summer_daily_allocation=0", "--all_electric_winter_daily_allocation=0"}))
+	}()
+
+	assert.Equal(t, 30.0, GetDailyAllocation(now, AllElectricService))
+	assert.Equal(t, SIMI_WINTER_DAILY_ALLOCATION, GetDailyAllocation(now, BasicService))
+	days := toDaysOrDie(t, oneDataPointPerHourWithConstantUsage(now, 1))
+	bill := CalculateWithTouPlan(days, WithServiceType(NewTouDAPlan(), AllElectricService))
+	assert.Equal(t, 30.0, bill.MaxBaselineAllowance())
+}
+
+func TestCheckServiceType_AllElectricAllocationNotSet_Error(t *testing.T) {
+	assert.Error(t, CheckServiceType(AllElectricService))
+	assert.NoError(t, CheckServiceType(BasicService))
+}
+
+func TestBaselineRegion_SelectsRegionAllocations(t *testing.T) {
+	const testRegion BaselineRegion = "Test Region"
+	regionAllocations[testRegion] = seasonAllocation{summer: 1, winter: 2}
+	defer delete(regionAllocations, testRegion)
+	err := flag.CommandLine.Parse([]string{"--use_medical_baseline=false", "--baseline_region=test region"})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, flag.CommandLine.Parse([]string{"--use_medical_baseline=true", "--baseline_region=Simi Valley"}))
+	}()
+
+	assert.Equal(t, 2.0, GetDailyAllocation(now, BasicService))
+	assert.Equal(t, 1.0, GetDailyAllocation(now.AddDate(0, 6, 0), BasicService))
+}
+
+func TestBaselineRegion_Unknown_Error(t *testing.T) {
+	var r BaselineRegion
+
+	assert.EqualError(t, r.Set("Barstow"), "unsupported baseline region 'Barstow'; one of: Simi Valley")
+}
@@ -0,0 +1,100 @@
+package costcalculator
+
+import (
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+)
+
+// DemandChargePlan is implemented by plans that also charge for peak demand, like SCE's TOU-GS business plans.
+// Charges are in $/kW and apply once per calendar month.
+type DemandChargePlan interface {
+	TouPlan
+	// MonthlyMaxDemandCharge applies to the highest demand of the month at any time.
+	MonthlyMaxDemandCharge() float64
+	// OnPeakDemandCharge applies to the highest demand of the month during on-peak hours.
+	OnPeakDemandCharge() float64
+}
+
+type demandChargePlan struct {
+	TouPlan
+	monthlyMaxPerKw float64
+	onPeakPerKw     float64
+}
+
+// WithDemandCharges returns plan with added demand charges, in $/kW of the monthly maximum demand and of the monthly
+// maximum on-peak demand.
+func WithDemandCharges(plan TouPlan, monthlyMaxPerKw float64, onPeakPerKw float64) TouPlan {
+	return &demandChargePlan{
+		TouPlan:         plan,
+		monthlyMaxPerKw: monthlyMaxPerKw,
+		onPeakPerKw:     onPeakPerKw,
+	}
+}
+
+func (p *demandChargePlan) MonthlyMaxDemandCharge() float64 {
+	return p.monthlyMaxPerKw
+}
+
+func (p *demandChargePlan) OnPeakDemandCharge() float64 {
+	return p.onPeakPerKw
+}
+
+// PeakDemandByPeriod returns the highest demand of the readings in each of the plan's periods.
+func PeakDemandByPeriod(file csvparser.CsvFile, plan TouPlan) map[CostPeriod]analyzer.DemandPeak {
+	out := make(map[CostPeriod]analyzer.DemandPeak)
+	for i := range file {
+		r := &file[i]
+		period := calculateTouRateForHour(r.StartTime, plan)
+		peak, ok := out[period]
+		if !ok || r.DemandKw() > peak.Kw {
+			out[period] = analyzer.DemandPeak{Start: r.StartTime, End: r.EndTime, At: r.StartTime, Kw: r.DemandKw()}
+		}
+	}
+	return out
+}
+
+// MonthlyDemand is the peak demand and demand charges of a calendar month.
+type MonthlyDemand struct {
+	Month    time.Time
+	MaxKw    float64
+	OnPeakKw float64
+	Charge   float64
+}
+
+// MonthlyDemand returns the peak demand of every billed month. Charges are 0 unless the plan is a DemandChargePlan.
+func (b *TouBillSummary) MonthlyDemand() []MonthlyDemand {
+	out := make([]MonthlyDemand, 0)
+	for _, d := range b.days {
+		month := time.Date(d.Day.Year(), d.Day.Month(), 1, 0, 0, 0, 0, d.Day.Location())
+		if len(out) == 0 || !out[len(out)-1].Month.Equal(month) {
+			out = append(out, MonthlyDemand{Month: month})
+		}
+		m := &out[len(out)-1]
+		for _, h := range d.DataPoints {
+			kw := analyzer.PeakDemandKw(h)
+			if kw > m.MaxKw {
+				m.MaxKw = kw
+			}
+			if isOnPeakPeriod(calculateTouRateForHour(h.StartTime(), b.touPlan)) && kw > m.OnPeakKw {
+				m.OnPeakKw = kw
+			}
+		}
+	}
+	if plan, ok := b.touPlan.(DemandChargePlan); ok {
+		for i := range out {
+			out[i].Charge = out[i].MaxKw*plan.MonthlyMaxDemandCharge() + out[i].OnPeakKw*plan.OnPeakDemandCharge()
+		}
+	}
+	return out
+}
+
+// DemandCharges returns the demand charges over all billed months.
+func (b *TouBillSummary) DemandCharges() float64 {
+	total := 0.0
+	for _, m := range b.MonthlyDemand() {
+		total += m.Charge
+	}
+	return total
+}
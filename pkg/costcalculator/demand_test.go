@@ -0,0 +1,61 @@
+package costcalculator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/stretchr/testify/assert"
+)
+
+// summerWeekday is a Wednesday in TOU-D-PRIME's summer season.
+var summerWeekday = time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+
+func TestPeakDemandByPeriod_SplitsOnAndOffPeak(t *testing.T) {
+	in := []csvparser.CsvRow{
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(10*time.Hour), 2),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(17*time.Hour), 1),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(18*time.Hour), 0.5),
+	}
+
+	got := PeakDemandByPeriod(in, NewTouDPrime())
+
+	assert.Len(t, got, 2)
+	assert.InEpsilon(t, 8.0, got[SummerOffPeak].Kw, 0.0001)
+	assert.InEpsilon(t, 4.0, got[SummerOnPeak].Kw, 0.0001)
+	assert.Equal(t, summerWeekday.Add(17*time.Hour), got[SummerOnPeak].At)
+}
+
+func TestTouBillSummary_MonthlyDemand_NoDemandCharges(t *testing.T) {
+	days := toDaysOrDie(t, []csvparser.CsvRow{
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(10*time.Hour), 2),
+	})
+
+	bill := CalculateWithTouPlan(days, NewTouDPrime())
+
+	assert.Len(t, bill.MonthlyDemand(), 1)
+	assert.InEpsilon(t, 8.0, bill.MonthlyDemand()[0].MaxKw, 0.0001)
+	assert.Equal(t, 0.0, bill.DemandCharges())
+}
+
+func TestTouBillSummary_DemandCharges_MonthlyMaxAndOnPeak(t *testing.T) {
+	days := toDaysOrDie(t, []csvparser.CsvRow{
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(10*time.Hour), 2),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(17*time.Hour), 1),
+		// A Monday.
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.AddDate(0, 1, 2).Add(17*time.Hour), 0.5),
+	})
+	plan := WithDemandCharges(NewTouDPrime(), 10, 1)
+
+	bill := CalculateWithTouPlan(days, plan)
+	withoutDemand := CalculateWithTouPlan(days, NewTouDPrime())
+
+	months := bill.MonthlyDemand()
+	assert.Len(t, months, 2)
+	assert.InEpsilon(t, 4.0, months[0].OnPeakKw, 0.0001)
+	assert.InEpsilon(t, 8.0*10+4.0*1, months[0].Charge, 0.0001)
+	assert.InEpsilon(t, 2.0*10+2.0*1, months[1].Charge, 0.0001)
+	assert.InEpsilon(t, 106.0, bill.DemandCharges(), 0.0001)
+	assert.InEpsilon(t, withoutDemand.TotalCost()+106, bill.TotalCost(), 0.0001)
+	assert.Equal(t, "TOU-D-PRIME", plan.Name())
+}
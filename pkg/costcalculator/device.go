@@ -0,0 +1,165 @@
+package costcalculator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/sense"
+)
+
+// Sense device IDs that describe the whole house rather than a single device.
+var nonDeviceIds = map[string]bool{
+	"mains": true,
+	"solar": true,
+}
+
+// DeviceCost is the portion of a TOU bill attributed to a single Sense device.
+type DeviceCost struct {
+	DeviceId string
+	Name     string
+	Days     int
+
+	EnergyKwh          float64
+	EnergyKwhByPeriod  map[CostPeriod]float64
+	EnergyCostByPeriod map[CostPeriod]float64
+
+	// BaselineCredit and FixedCharges are this device's share of the house-wide amounts, proportional to its
+	// share of the total device energy. The baseline credit is that of the house's net usage, as on the bill.
+	BaselineCredit float64
+	FixedCharges   float64
+}
+
+// EnergyCost returns the device energy priced at the plan's marginal TOU rates.
+func (c *DeviceCost) EnergyCost() float64 {
+	total := 0.0
+	for _, cost := range c.EnergyCostByPeriod {
+		total += cost
+	}
+	return total
+}
+
+// TotalCost returns the energy cost including the allocated baseline credit and fixed charges.
+func (c *DeviceCost) TotalCost() float64 {
+	return c.EnergyCost() + c.BaselineCredit + c.FixedCharges
+}
+
+// MonthlyCost returns the total cost normalized to an average month.
+func (c *DeviceCost) MonthlyCost() float64 {
+	if c.Days == 0 {
+		return 0
+	}
+	return c.TotalCost() / float64(c.Days) * AverageDaysPerMonth
+}
+
+// OnPeakShare returns the fraction of the energy cost incurred during on-peak periods.
+func (c *DeviceCost) OnPeakShare() float64 {
+	energyCost := c.EnergyCost()
+	if energyCost == 0 {
+		return 0
+	}
+	onPeak := 0.0
+	for period, cost := range c.EnergyCostByPeriod {
+		if isOnPeakPeriod(period) {
+			onPeak += cost
+		}
+	}
+	return onPeak / energyCost
+}
+
+// Summary returns a one-line description such as "Dryer: $23.00/month, 41% of it during on-peak".
+func (c *DeviceCost) Summary() string {
+	return fmt.Sprintf("%s: $%.2f/month, %.0f%% of it during on-peak", c.Name, c.MonthlyCost(), 100*c.OnPeakShare())
+}
+
+// CalculateDeviceCosts prices the energy of each device returned by sense.GroupByDeviceId with the given plan.
+// The house-wide baseline credit and basic charges are split across devices in proportion to their energy.
+// The whole-house "mains" and "solar" series are not devices. They give the net usage the baseline credit is
+// computed from, as in TouBillSummary.BaselineCredit.
+// The result is ranked from the most to the least expensive device.
+func CalculateDeviceCosts(byDevice map[string][]sense.CsvRow, plan TouPlan) []DeviceCost {
+	days := make(map[time.Time]bool)
+	out := make([]DeviceCost, 0)
+	totalEnergy := 0.0
+	for id, rows := range byDevice {
+		if nonDeviceIds[id] {
+			continue
+		}
+		c := DeviceCost{
+			DeviceId:           id,
+			EnergyKwhByPeriod:  make(map[CostPeriod]float64),
+			EnergyCostByPeriod: make(map[CostPeriod]float64),
+		}
+		for _, row := range rows {
+			if c.Name == "" {
+				c.Name = row.Name
+			}
+			t := row.DateTime.Value
+			period := calculateTouRateForHour(t, plan)
+			c.EnergyKwh += row.EnergyKwh
+			c.EnergyKwhByPeriod[period] += row.EnergyKwh
+			c.EnergyCostByPeriod[period] += row.EnergyKwh * plan.Cost(period)
+			days[time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())] = true
+		}
+		totalEnergy += c.EnergyKwh
+		out = append(out, c)
+	}
+
+	baselineCredit := 0.0
+	if plan.HasBaselineAllocation() {
+		maxBaseline, netUsage := 0.0, 0.0
+		for d, kwh := range netUsageByDay(byDevice, days) {
+			maxBaseline += GetDailyAllocation(d, serviceTypeOf(plan))
+			netUsage += kwh
+		}
+		baselineCredit = math.Copysign(math.Min(math.Abs(netUsage), maxBaseline), netUsage) * BaselineCreditPerKwh
+	}
+	fixedCharges := float64(len(days)) * plan.DailyBasicCharge()
+
+	for i := range out {
+		out[i].Days = len(days)
+		if totalEnergy == 0 {
+			continue
+		}
+		share := out[i].EnergyKwh / totalEnergy
+		out[i].BaselineCredit = share * baselineCredit
+		out[i].FixedCharges = share * fixedCharges
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TotalCost() == out[j].TotalCost() {
+			return out[i].DeviceId < out[j].DeviceId
+		}
+		return out[i].TotalCost() > out[j].TotalCost()
+	})
+	return out
+}
+
+// netUsageByDay returns the energy drawn from the grid on each of the days: the "mains" series plus the "solar"
+// series, which is negative. Without a "mains" series, the devices' energy stands in for the house's consumption.
+func netUsageByDay(byDevice map[string][]sense.CsvRow, days map[time.Time]bool) map[time.Time]float64 {
+	ids := []string{"mains", "solar"}
+	if _, ok := byDevice["mains"]; !ok {
+		ids = []string{"solar"}
+		for id := range byDevice {
+			if !nonDeviceIds[id] {
+				ids = append(ids, id)
+			}
+		}
+	}
+	out := make(map[time.Time]float64)
+	for d := range days {
+		out[d] = 0
+	}
+	for _, id := range ids {
+		for _, row := range byDevice[id] {
+			t := row.DateTime.Value
+			d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+			if days[d] {
+				out[d] += row.EnergyKwh
+			}
+		}
+	}
+	return out
+}
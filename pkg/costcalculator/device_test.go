@@ -0,0 +1,95 @@
+package costcalculator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/sense"
+	"github.com/stretchr/testify/assert"
+)
+
+func senseRow(id string, name string, t time.Time, kwh float64) sense.CsvRow {
+	return sense.CsvRow{
+		DateTime:  sense.DateTime{Value: t},
+		DeviceId:  id,
+		Name:      name,
+		EnergyKwh: kwh,
+	}
+}
+
+func TestCalculateDeviceCosts_SkipsMainsAndSolar(t *testing.T) {
+	in := map[string][]sense.CsvRow{
+		"mains": {senseRow("mains", "Total Usage", now, 10)},
+		"solar": {senseRow("solar", "Solar Production", now, -5)},
+		"abc":   {senseRow("abc", "Dryer", now, 1)},
+	}
+
+	got := CalculateDeviceCosts(in, NewTouDAPlan())
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, "Dryer", got[0].Name)
+}
+
+func TestCalculateDeviceCosts_PricesAtMarginalRate(t *testing.T) {
+	// Monday, Aug 3, 2020 at 3pm is on-peak for TOU-D-A.
+	onPeak := time.Date(2020, 8, 3, 15, 0, 0, 0, time.UTC)
+	in := map[string][]sense.CsvRow{
+		"abc": {senseRow("abc", "Dryer", onPeak, 2)},
+	}
+
+	got := CalculateDeviceCosts(in, NewTouDAPlan())
+
+	assert.Len(t, got, 1)
+	assert.InEpsilon(t, 2*NewTouDAPlan().Cost(SummerOnPeak), got[0].EnergyCost(), 0.0001)
+	assert.Equal(t, 1.0, got[0].OnPeakShare())
+}
+
+func TestCalculateDeviceCosts_AllocatesSharedChargesProportionally(t *testing.T) {
+	in := map[string][]sense.CsvRow{
+		"abc": {senseRow("abc", "Dryer", now, 3)},
+		"def": {senseRow("def", "Washer", now, 1)},
+	}
+
+	got := CalculateDeviceCosts(in, NewTouDAPlan())
+
+	assert.Len(t, got, 2)
+	assert.Equal(t, "Dryer", got[0].Name)
+	assert.InEpsilon(t, 3*got[1].FixedCharges, got[0].FixedCharges, 0.0001)
+	assert.InEpsilon(t, 3*got[1].BaselineCredit, got[0].BaselineCredit, 0.0001)
+	assert.InEpsilon(t, NewTouDAPlan().DailyBasicCharge(), got[0].FixedCharges+got[1].FixedCharges, 0.0001)
+}
+
+func TestCalculateDeviceCosts_NetExporter_CreditFromNetUsage(t *testing.T) {
+	in := map[string][]sense.CsvRow{
+		"mains": {senseRow("mains", "Total Usage", now, 10)},
+		"solar": {senseRow("solar", "Solar Production", now, -12)},
+		"abc":   {senseRow("abc", "Dryer", now, 3)},
+		"def":   {senseRow("def", "Washer", now, 1)},
+	}
+
+	got := CalculateDeviceCosts(in, NewTouDAPlan())
+
+	// The house exported 2 kWh, so it gets no credit for the devices' 4 kWh.
+	assert.InDelta(t, -2*BaselineCreditPerKwh, got[0].BaselineCredit+got[1].BaselineCredit, 0.0001)
+	assert.InDelta(t, 3*got[1].BaselineCredit, got[0].BaselineCredit, 0.0001)
+}
+
+func TestCalculateDeviceCosts_NoBaselineOnPlanWithoutAllocation(t *testing.T) {
+	in := map[string][]sense.CsvRow{
+		"abc": {senseRow("abc", "Dryer", now, 3)},
+	}
+
+	got := CalculateDeviceCosts(in, NewTouDPrime())
+
+	assert.Equal(t, 0.0, got[0].BaselineCredit)
+}
+
+func TestDeviceCost_Summary(t *testing.T) {
+	c := DeviceCost{
+		Name:               "Dryer",
+		Days:               1,
+		EnergyCostByPeriod: map[CostPeriod]float64{SummerOnPeak: 1, SummerOffPeak: 1},
+	}
+
+	assert.Equal(t, "Dryer: $60.88/month, 50% of it during on-peak", c.Summary())
+}
@@ -25,12 +25,18 @@ type DomesticBreakdown struct {
 	Tier1UsageKwh float64
 	Tier2UsageKwh float64
 	Tier3UsageKwh float64
+
+	Completeness analyzer.CompletenessReport
+	// LowQualityShare is the fraction of the energy that comes from estimated or missing readings.
+	LowQualityShare float64
 }
 
 func CalculateDomesticForDays(days []analyzer.UsageDay) DomesticBreakdown {
 	out := DomesticBreakdown{
 		Days:                  len(days),
 		BaselineAllocationKwh: baselineAllocationForDays(days),
+		Completeness:          analyzer.Completeness(days),
+		LowQualityShare:       analyzer.LowQualityShare(days),
 	}
 
 	for _, d := range days {
@@ -69,7 +75,7 @@ func rebalanceTiers(d *DomesticBreakdown) {
 func baselineAllocationForDays(days []analyzer.UsageDay) float64 {
 	totalAllowance := 0.0
 	for _, d := range days {
-		totalAllowance += GetDailyAllocation(d.Day)
+		totalAllowance += GetDailyAllocation(d.Day, BasicService)
 	}
 	return totalAllowance
 }
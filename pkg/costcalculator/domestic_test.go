@@ -10,7 +10,7 @@ import (
 
 func TestCalculateDomesticForDays(t *testing.T) {
 	winterDay := time.Date(2020, 01, 01, 00, 00, 00, 00, time.UTC)
-	dailyAllocation := GetDailyAllocation(winterDay)
+	dailyAllocation := GetDailyAllocation(winterDay, BasicService)
 	assert.Equal(t, 28.8, dailyAllocation)
 
 	in := []analyzer.UsageDay{
@@ -21,6 +21,13 @@ const (
 	WinterOnPeak
 )
 
+// costPeriods lists every period in order. Totals are summed in this order rather than in map order, so that the same
+// usage always gives the same floating-point result.
+var costPeriods = []CostPeriod{
+	SummerSuperOffPeak, SummerOffPeak, SummerMidPeak, SummerOnPeak,
+	WinterSuperOffPeak, WinterOffPeak, WinterMidPeak, WinterOnPeak,
+}
+
 func (cost *CostPeriod) Name() string {
 	switch *cost {
 	case SummerOffPeak:
@@ -61,6 +68,9 @@ func CalculateWithTouPlan(days []analyzer.UsageDay, plan TouPlan) TouBillSummary
 		hoursByPeriod:    make(map[CostPeriod]int),
 		energyImported:   0,
 
+		importedKwhByPeriod: make(map[CostPeriod]float64),
+		exportedKwhByPeriod: make(map[CostPeriod]float64),
+
 		weekdays: 0,
 		weekends: 0,
 		holidays: 0,
@@ -93,6 +103,9 @@ type TouBillSummary struct {
 	energyExported   float64
 	energyImported   float64
 
+	importedKwhByPeriod map[CostPeriod]float64
+	exportedKwhByPeriod map[CostPeriod]float64
+
 	weekdays int
 	weekends int
 	holidays int
@@ -100,16 +113,18 @@ type TouBillSummary struct {
 
 func (b *TouBillSummary) NetMeteredCostNoBaseline() float64 {
 	total := 0.0
-	for period, usage := range b.usageKwhByPeriod {
-		total += usage * b.touPlan.Cost(period)
+	for _, period := range costPeriods {
+		if usage, ok := b.usageKwhByPeriod[period]; ok {
+			total += usage * b.touPlan.Cost(period)
+		}
 	}
 	return total
 }
 
 func (b *TouBillSummary) NetEnergyUsage() float64 {
 	total := 0.0
-	for _, usage := range b.usageKwhByPeriod {
-		total += usage
+	for _, period := range costPeriods {
+		total += b.usageKwhByPeriod[period]
 	}
 	return total
 }
@@ -122,6 +137,11 @@ func (b *TouBillSummary) TrueUp() float64 {
 	}
 }
 
+// TotalCost returns the cost over all billed days: the true-up plus the fees and demand charges on the monthly bills.
+func (b *TouBillSummary) TotalCost() float64 {
+	return b.TrueUp() + b.TotalBasicCharge() + b.Taxes() + b.NonBypassableCharges() + b.DemandCharges()
+}
+
 func (b *TouBillSummary) Taxes() float64 {
 	usage := b.NetEnergyUsage()
 	if usage > 0 {
@@ -136,6 +156,16 @@ func (b *TouBillSummary) UsageByPeriod() map[CostPeriod]float64 {
 	return b.usageKwhByPeriod
 }
 
+// ImportedByPeriod returns the energy of the hours with net imports, by period.
+func (b *TouBillSummary) ImportedByPeriod() map[CostPeriod]float64 {
+	return b.importedKwhByPeriod
+}
+
+// ExportedByPeriod returns the energy of the hours with net exports, by period. Like EnergyExported, it is negative.
+func (b *TouBillSummary) ExportedByPeriod() map[CostPeriod]float64 {
+	return b.exportedKwhByPeriod
+}
+
 func (b *TouBillSummary) EnergyExported() float64 {
 	return b.energyExported
 }
@@ -154,7 +184,7 @@ func (b *TouBillSummary) MaxBaselineAllowance() float64 {
 	}
 	total := 0.0
 	for _, p := range b.days {
-		total += GetDailyAllocation(p.Day)
+		total += GetDailyAllocation(p.Day, serviceTypeOf(b.touPlan))
 	}
 	return total
 }
@@ -173,6 +203,16 @@ func (b *TouBillSummary) BaselineCredit() float64 {
 	return math.Copysign(absAllowance, actualUsage) * BaselineCreditPerKwh
 }
 
+// Completeness reports how much of the billed days have readings.
+func (b *TouBillSummary) Completeness() analyzer.CompletenessReport {
+	return analyzer.Completeness(b.days)
+}
+
+// LowQualityShare returns the fraction of the billed energy that comes from estimated or missing readings.
+func (b *TouBillSummary) LowQualityShare() float64 {
+	return analyzer.LowQualityShare(b.days)
+}
+
 func (b *TouBillSummary) AverageDailyUsage() float64 {
 	return b.NetEnergyUsage() / float64(len(b.days))
 }
@@ -193,12 +233,24 @@ func (b *TouBillSummary) add(d analyzer.UsageDay) {
 		b.hoursByPeriod[period] += 1
 		if h.UsageKwh() > 0 {
 			b.energyImported += h.UsageKwh()
+			b.importedKwhByPeriod[period] += h.UsageKwh()
 		} else {
 			b.energyExported += h.UsageKwh()
+			b.exportedKwhByPeriod[period] += h.UsageKwh()
 		}
 	}
 }
 
+// MarginalRate returns the plan's energy cost in $/kWh for an hour starting at t.
+func MarginalRate(t time.Time, plan TouPlan) float64 {
+	return plan.Cost(calculateTouRateForHour(t, plan))
+}
+
+// PeriodAt returns the plan's TOU period of an hour starting at t.
+func PeriodAt(t time.Time, plan TouPlan) CostPeriod {
+	return calculateTouRateForHour(t, plan)
+}
+
 func calculateTouRateForHour(t time.Time, plan TouPlan) CostPeriod {
 	if plan.IsOnPeak(t) {
 		if isSummerMonth(t.Month()) {
@@ -229,6 +281,10 @@ func calculateTouRateForHour(t time.Time, plan TouPlan) CostPeriod {
 	}
 }
 
+func isOnPeakPeriod(period CostPeriod) bool {
+	return period == SummerOnPeak || period == WinterOnPeak
+}
+
 func isWeekend(t time.Time) bool {
 	weekDay := t.Weekday()
 	return weekDay == time.Saturday || weekDay == time.Sunday
@@ -110,6 +110,28 @@ func TestTouBillSummary_UsageByPeriod_SumMatchesUsage(t *testing.T) {
 	assert.Equal(t, bill.NetEnergyUsage(), totalUsage)
 }
 
+func TestTouBillSummary_ImportedAndExportedByPeriod_SplitsHours(t *testing.T) {
+	summerWeekday := time.Date(2020, 8, 3, 0, 0, 0, 0, time.UTC)
+	days := toDaysOrDie(t, []csvparser.CsvRow{
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(12*time.Hour), -2.0),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(13*time.Hour), 1.0),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(18*time.Hour), 3.0),
+	})
+
+	bill := CalculateWithTouPlan(days, NewTouDPrime())
+
+	assert.Equal(t, map[CostPeriod]float64{SummerOffPeak: 1.0, SummerOnPeak: 3.0}, bill.ImportedByPeriod())
+	assert.Equal(t, map[CostPeriod]float64{SummerOffPeak: -2.0}, bill.ExportedByPeriod())
+	assert.Equal(t, -1.0, bill.UsageByPeriod()[SummerOffPeak])
+}
+
+func TestPeriodAt_SummerWeekdayEvening_OnPeak(t *testing.T) {
+	summerWeekday := time.Date(2020, 8, 3, 18, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, SummerOnPeak, PeriodAt(summerWeekday, NewTouDPrime()))
+	assert.Equal(t, SummerOffPeak, PeriodAt(summerWeekday.Add(-6*time.Hour), NewTouDPrime()))
+}
+
 func TestTouBillSummary_BaselineCredit_ReducesCostWhenNetConsumption(t *testing.T) {
 	days := toDaysOrDie(t, []csvparser.CsvRow{
 		csvparser.NewRowWith15MinuteDuration(now, 2.0),
@@ -169,6 +191,43 @@ func TestTouBillSummary_NegativeTrueUp_MuchCheaperDueToSurplusRate(t *testing.T)
 	assert.Greater(t, math.Abs(deficitBill.TrueUp()), math.Abs(surplusBill.TrueUp()))
 }
 
+func TestTouBillSummary_TotalCost_IncludesMonthlyFees(t *testing.T) {
+	days := toDaysOrDie(t, []csvparser.CsvRow{
+		csvparser.NewRowWith15MinuteDuration(now, 1000.0),
+	})
+
+	bill := CalculateTouDACostForDays(days)
+
+	assert.Equal(t, bill.TrueUp()+bill.TotalBasicCharge()+bill.Taxes()+bill.NonBypassableCharges(), bill.TotalCost())
+	assert.Greater(t, bill.TotalCost(), bill.TrueUp())
+}
+
+func TestTouBillSummary_Completeness_ReportsPartialDays(t *testing.T) {
+	days := toDaysOrDie(t, []csvparser.CsvRow{
+		csvparser.NewRowWith15MinuteDuration(now, 1.0),
+	})
+
+	bill := CalculateTouDACostForDays(days)
+	got := bill.Completeness()
+
+	assert.Equal(t, 1, got.Days)
+	assert.Equal(t, 0, got.CompleteDays)
+	assert.Equal(t, []time.Time{now}, got.PartialDays)
+}
+
+func TestTouBillSummary_LowQualityShare(t *testing.T) {
+	estimated := csvparser.NewRowWith15MinuteDuration(now.Add(1*time.Hour), 1.0)
+	estimated.ReadingQuality = csvparser.QualityEstimated
+	days := toDaysOrDie(t, []csvparser.CsvRow{
+		csvparser.NewRowWith15MinuteDuration(now, 1.0),
+		estimated,
+	})
+
+	bill := CalculateTouDACostForDays(days)
+
+	assert.Equal(t, 0.5, bill.LowQualityShare())
+}
+
 func oneDataPointPerHourWithConstantUsage(day time.Time, usage float64) []csvparser.CsvRow {
 	rows := make([]csvparser.CsvRow, 0)
 	for i := 0; i < 24; i++ {
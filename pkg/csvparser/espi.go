@@ -0,0 +1,332 @@
+package csvparser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ESPI units of measure, flow directions and service kinds used by Green Button XML downloads.
+const (
+	espiUomWattHours     = 72
+	espiFlowReverse      = 19
+	espiServiceElectric  = 0
+	espiQualityEstimated = 8
+	espiQualityInterp    = 9
+	espiQualityProjected = 12
+	espiQualityValidated = 17
+	espiQualityVerified  = 18
+	espiQualityRevenue   = 19
+)
+
+// SCE is in the Pacific time zone. These are used when a download has no LocalTimeParameters.
+const (
+	defaultTzOffset  = -8 * 60 * 60
+	defaultDstOffset = 60 * 60
+)
+
+type espiLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type espiEntry struct {
+	Links   []espiLink `xml:"link"`
+	Content struct {
+		UsagePoint          *espiUsagePoint          `xml:"UsagePoint"`
+		MeterReading        *struct{}                `xml:"MeterReading"`
+		ReadingType         *espiReadingType         `xml:"ReadingType"`
+		IntervalBlocks      []espiIntervalBlock      `xml:"IntervalBlock"`
+		LocalTimeParameters *espiLocalTimeParameters `xml:"LocalTimeParameters"`
+	} `xml:"content"`
+}
+
+type espiUsagePoint struct {
+	ServiceKind  int    `xml:"ServiceCategory>kind"`
+	DeliveryName string `xml:"ServiceDeliveryPoint>name"`
+}
+
+type espiReadingType struct {
+	FlowDirection        int `xml:"flowDirection"`
+	PowerOfTenMultiplier int `xml:"powerOfTenMultiplier"`
+	Uom                  int `xml:"uom"`
+}
+
+type espiIntervalBlock struct {
+	Readings []espiIntervalReading `xml:"IntervalReading"`
+}
+
+type espiIntervalReading struct {
+	Qualities []int `xml:"ReadingQuality>quality"`
+	Start     int64 `xml:"timePeriod>start"`
+	Duration  int64 `xml:"timePeriod>duration"`
+	Value     int64 `xml:"value"`
+}
+
+type espiLocalTimeParameters struct {
+	TzOffset  int64 `xml:"tzOffset"`
+	DstOffset int64 `xml:"dstOffset"`
+}
+
+// espiFeed is the entries of a download, linked by the paths of their hrefs.
+type espiFeed struct {
+	// usagePoints are keyed by the path through "UsagePoint/{id}", in order of appearance.
+	usagePoints     map[string]*espiUsagePoint
+	usagePointOrder []string
+	// readingTypeOf maps the path through "MeterReading/{id}" to the ID of its ReadingType.
+	readingTypeOf map[string]string
+	// readingTypes are keyed by ID.
+	readingTypes map[string]*espiReadingType
+	// blocks are keyed by the path through "MeterReading/{id}".
+	blocks    map[string][]espiIntervalBlock
+	localTime espiLocalTimeParameters
+}
+
+// IsEspi returns true if the buffered input looks like a Green Button XML (ESPI) download rather than a CSV file.
+func IsEspi(r *bufio.Reader) bool {
+	start, _ := r.Peek(512)
+	start = bytes.TrimPrefix(start, []byte("\xef\xbb\xbf"))
+	return bytes.HasPrefix(bytes.TrimSpace(start), []byte("<"))
+}
+
+// ParseEspi parses a Green Button XML download, an Atom feed of ESPI resources. Each row is tagged with the identity
+// of its UsagePoint: the UsagePoint ID as the meter, the RetailCustomer or Subscription ID as the service account,
+// and the name of the ServiceDeliveryPoint as the location. Delivered and received energy of the same interval are
+// netted, so that exports are negative as in CSV downloads. Times are converted to local time with the
+// LocalTimeParameters of the feed, or Pacific time if it has none, with US daylight saving rules. Non-electric usage
+// points are skipped.
+func ParseEspi(r io.Reader) (CsvFile, error) {
+	feed := &espiFeed{
+		usagePoints:   make(map[string]*espiUsagePoint),
+		readingTypeOf: make(map[string]string),
+		readingTypes:  make(map[string]*espiReadingType),
+		blocks:        make(map[string][]espiIntervalBlock),
+		localTime:     espiLocalTimeParameters{TzOffset: defaultTzOffset, DstOffset: defaultDstOffset},
+	}
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid ESPI XML: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "entry" {
+			continue
+		}
+		var entry espiEntry
+		if err := decoder.DecodeElement(&entry, &start); err != nil {
+			return nil, fmt.Errorf("invalid ESPI entry: %w", err)
+		}
+		feed.add(&entry)
+	}
+	if len(feed.usagePoints) == 0 {
+		return nil, fmt.Errorf("no UsagePoint found in ESPI XML")
+	}
+	return feed.rows()
+}
+
+func (f *espiFeed) add(e *espiEntry) {
+	self := e.link("self")
+	content := &e.Content
+	switch {
+	case content.UsagePoint != nil:
+		key := hrefThrough(self, "UsagePoint")
+		if _, ok := f.usagePoints[key]; !ok {
+			f.usagePointOrder = append(f.usagePointOrder, key)
+		}
+		f.usagePoints[key] = content.UsagePoint
+	case content.MeterReading != nil:
+		for _, l := range e.Links {
+			if id := hrefSegment(l.Href, "ReadingType"); l.Rel == "related" && id != "" {
+				f.readingTypeOf[hrefThrough(self, "MeterReading")] = id
+			}
+		}
+	case content.ReadingType != nil:
+		f.readingTypes[hrefSegment(self, "ReadingType")] = content.ReadingType
+	case len(content.IntervalBlocks) > 0:
+		href := self
+		if hrefSegment(href, "MeterReading") == "" {
+			href = e.link("up")
+		}
+		key := hrefThrough(href, "MeterReading")
+		f.blocks[key] = append(f.blocks[key], content.IntervalBlocks...)
+	case content.LocalTimeParameters != nil:
+		f.localTime = *content.LocalTimeParameters
+	}
+}
+
+func (e *espiEntry) link(rel string) string {
+	for _, l := range e.Links {
+		if l.Rel == rel {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// rows returns the readings of every electric usage point, each sorted chronologically.
+func (f *espiFeed) rows() (CsvFile, error) {
+	meterReadings := make([]string, 0, len(f.blocks))
+	for key := range f.blocks {
+		meterReadings = append(meterReadings, key)
+	}
+	sort.Strings(meterReadings)
+
+	out := make(CsvFile, 0)
+	for _, upKey := range f.usagePointOrder {
+		if f.usagePoints[upKey].ServiceKind != espiServiceElectric {
+			continue
+		}
+		meter := f.meterId(upKey)
+		// Readings are netted by their Unix start, so that the repeated hour in the fall is not netted with itself.
+		byStart := make(map[int64]int)
+		rows := make(CsvFile, 0)
+		for _, mrKey := range meterReadings {
+			if hrefThrough(mrKey, "UsagePoint") != upKey {
+				continue
+			}
+			readingType, ok := f.readingTypes[f.readingTypeOf[mrKey]]
+			if !ok {
+				return nil, fmt.Errorf("no ReadingType for MeterReading %s", mrKey)
+			}
+			if readingType.Uom != espiUomWattHours {
+				return nil, fmt.Errorf("MeterReading %s has unit %d, expected Wh (%d)", mrKey, readingType.Uom, espiUomWattHours)
+			}
+			scale := math.Pow(10, float64(readingType.PowerOfTenMultiplier)) / 1000
+			if readingType.FlowDirection == espiFlowReverse {
+				scale = -scale
+			}
+			for _, block := range f.blocks[mrKey] {
+				for _, reading := range block.Readings {
+					row := CsvRow{
+						StartTime:      f.toLocal(reading.Start),
+						UsageKwh:       float64(reading.Value) * scale,
+						ReadingQuality: espiQuality(reading.Qualities),
+						Meter:          meter,
+					}
+					row.EndTime = row.StartTime.Add(time.Duration(reading.Duration) * time.Second)
+					i, ok := byStart[reading.Start]
+					if !ok {
+						byStart[reading.Start] = len(rows)
+						rows = append(rows, row)
+						continue
+					}
+					rows[i].UsageKwh += row.UsageKwh
+					if !row.ReadingQuality.AtLeast(rows[i].ReadingQuality) {
+						rows[i].ReadingQuality = row.ReadingQuality
+					}
+				}
+			}
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			return rows[i].StartTime.Before(rows[j].StartTime)
+		})
+		out = append(out, rows...)
+	}
+	return out, nil
+}
+
+func (f *espiFeed) meterId(upKey string) MeterId {
+	account := hrefSegment(upKey, "RetailCustomer")
+	if account == "" {
+		account = hrefSegment(upKey, "Subscription")
+	}
+	return MeterId{
+		ServiceAccount: account,
+		Meter:          hrefSegment(upKey, "UsagePoint"),
+		Location:       strings.TrimSpace(f.usagePoints[upKey].DeliveryName),
+	}
+}
+
+// toLocal converts a Unix time to the local wall clock time, in UTC like the times of CSV downloads.
+func (f *espiFeed) toLocal(unix int64) time.Time {
+	offset := f.localTime.TzOffset
+	if f.localTime.DstOffset != 0 && f.isDst(unix) {
+		offset += f.localTime.DstOffset
+	}
+	return time.Unix(unix+offset, 0).UTC()
+}
+
+// isDst returns true if daylight saving time is in effect at the Unix time. US rules apply: from 2am on the second
+// Sunday of March to 2am on the first Sunday of November.
+func (f *espiFeed) isDst(unix int64) bool {
+	year := time.Unix(unix+f.localTime.TzOffset, 0).UTC().Year()
+	start := nthSunday(year, time.March, 2).Add(2*time.Hour).Unix() - f.localTime.TzOffset
+	end := nthSunday(year, time.November, 1).Add(2*time.Hour).Unix() - f.localTime.TzOffset - f.localTime.DstOffset
+	return unix >= start && unix < end
+}
+
+func nthSunday(year int, month time.Month, n int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	offset := (7 - int(first.Weekday())) % 7
+	return first.AddDate(0, 0, offset+7*(n-1))
+}
+
+// espiQuality returns the worst quality of the ESPI reading quality codes. Readings without a code are actual.
+func espiQuality(codes []int) ReadingQuality {
+	out := QualityValidated
+	if len(codes) == 0 {
+		out = QualityActual
+	}
+	for _, code := range codes {
+		q := QualityActual
+		switch code {
+		case espiQualityEstimated, espiQualityInterp, espiQualityProjected:
+			q = QualityEstimated
+		case espiQualityValidated, espiQualityVerified, espiQualityRevenue:
+			q = QualityValidated
+		}
+		if !q.AtLeast(out) {
+			out = q
+		}
+	}
+	return out
+}
+
+// hrefSegment returns the path segment that follows name in an href, such as "01" for name "UsagePoint" in
+// ".../UsagePoint/01/MeterReading".
+func hrefSegment(href string, name string) string {
+	parts := strings.Split(href, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == name {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// hrefThrough returns the href up to and including the segment that follows name.
+func hrefThrough(href string, name string) string {
+	parts := strings.Split(href, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == name {
+			return strings.Join(parts[:i+2], "/")
+		}
+	}
+	return href
+}
+
+// ParseDownload reads every row of a Green Button download, which may be a CSV file or ESPI XML. CSV files are
+// parsed with the options, and their malformed lines returned as warnings in Lenient mode.
+func ParseDownload(r io.Reader, opts ParseOptions) (CsvFile, []*LineParsingError, error) {
+	buffered := bufio.NewReader(r)
+	if IsEspi(buffered) {
+		rows, err := ParseEspi(buffered)
+		return rows, nil, err
+	}
+	reader := NewReader(buffered, opts)
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	return rows, reader.Warnings(), nil
+}
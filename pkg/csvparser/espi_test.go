@@ -0,0 +1,47 @@
+package csvparser
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEspi_TwoUsagePoints_TagsRowsWithUsagePoint(t *testing.T) {
+	got, err := ParseEspi(strings.NewReader(readOrDie("espi_two_usage_points.xml")))
+	assert.NoError(t, err)
+
+	home := MeterId{ServiceAccount: "9b6c7063", Meter: "1", Location: "1 MAIN ST"}
+	adu := MeterId{ServiceAccount: "9b6c7063", Meter: "2"}
+	jan1 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, CsvFile{
+		{StartTime: jan1, EndTime: jan1.Add(15 * time.Minute), UsageKwh: 1, ReadingQuality: QualityValidated, Meter: home},
+		{StartTime: jan1.Add(15 * time.Minute), EndTime: jan1.Add(30 * time.Minute), UsageKwh: 2, ReadingQuality: QualityEstimated, Meter: home},
+		// Daylight saving time is in effect in July.
+		{StartTime: time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC), EndTime: time.Date(2020, 7, 1, 0, 15, 0, 0, time.UTC), UsageKwh: 1, ReadingQuality: QualityActual, Meter: home},
+		{StartTime: jan1, EndTime: jan1.Add(15 * time.Minute), UsageKwh: 0.25, ReadingQuality: QualityActual, Meter: adu},
+	}, got)
+}
+
+func TestParseEspi_NoUsagePoint_Fails(t *testing.T) {
+	_, err := ParseEspi(strings.NewReader(`<feed xmlns="http://www.w3.org/2005/Atom"></feed>`))
+
+	assert.Error(t, err)
+}
+
+func TestIsEspi_DetectsXml(t *testing.T) {
+	assert.True(t, IsEspi(bufio.NewReader(strings.NewReader(readOrDie("espi_two_usage_points.xml")))))
+	assert.False(t, IsEspi(bufio.NewReader(strings.NewReader(readOrDie("one_day_constant_power.csv")))))
+}
+
+func TestParseDownload_CsvAndXml(t *testing.T) {
+	csvRows, _, err := ParseDownload(strings.NewReader(readOrDie("one_day_constant_power.csv")), DefaultParseOptions())
+	assert.NoError(t, err)
+	xmlRows, _, err := ParseDownload(strings.NewReader(readOrDie("espi_two_usage_points.xml")), DefaultParseOptions())
+	assert.NoError(t, err)
+
+	assert.Len(t, csvRows, 96)
+	assert.Len(t, xmlRows, 4)
+}
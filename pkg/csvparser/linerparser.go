@@ -3,6 +3,7 @@ package csvparser
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -13,13 +14,17 @@ type LineParsingError struct {
 	Cause        error
 	LineNumber   int
 	LineText     string
+	// Recovered is the row that Lenient mode keeps despite the error, e.g. a row with an unknown reading quality.
+	Recovered *CsvRow
 }
 
 func (e *LineParsingError) Error() string {
 	return fmt.Sprintf("Error when reading line %d:\n\n%s\n\n\t%s", e.LineNumber, e.LineText, e.Cause.Error())
 }
 
-func parseHourConsumption(line string, lineNumber int) (*CsvRow, error) {
+// parseHourConsumption parses the fields of a data record. line is the text of the record, for errors and for the
+// preamble.
+func parseHourConsumption(csvSplit []string, line string, lineNumber int) (*CsvRow, error) {
 	if line == HEADER {
 		return nil, &LineParsingError{
 			CanBeIgnored: true,
@@ -29,12 +34,12 @@ func parseHourConsumption(line string, lineNumber int) (*CsvRow, error) {
 		}
 	}
 
-	csvSplit := strings.Split(line, ",")
 	if len(csvSplit) != 3 {
 		err := errors.New("Expected 3 components in line (time period, usage, reading quality).")
 		return nil, &LineParsingError{
-			// TODO: This could be a malformed ACTUAL line, so we should be smart about which csv lines to ignore.
-			CanBeIgnored: true,
+			// Preamble and blank lines don't have 3 components either, but lines that start with a time period are
+			// malformed data.
+			CanBeIgnored: !isDataLine(line),
 			Cause:        err,
 			LineText:     line,
 			LineNumber:   lineNumber,
@@ -69,12 +74,30 @@ func parseHourConsumption(line string, lineNumber int) (*CsvRow, error) {
 			LineNumber: lineNumber,
 		}
 	}
+	row := &CsvRow{
+		StartTime: tStart,
+		EndTime:   tEnd,
+		UsageKwh:  usageNum,
+	}
+	row.ReadingQuality, err = ParseReadingQuality(readingQuality)
+	if err != nil {
+		// The usage is still valid, so the row can be kept as an actual reading.
+		return nil, &LineParsingError{
+			Cause:      err,
+			LineText:   line,
+			LineNumber: lineNumber,
+			Recovered:  row,
+		}
+	}
+	return row, nil
+}
+
+// dataLinePrefix matches the start of a line with usage data, e.g. "2017-09-01 23:00:00 to
+var dataLinePrefix = regexp.MustCompile(`^"?\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2} to`)
 
-	return &CsvRow{
-		StartTime:      tStart,
-		EndTime:        tEnd,
-		UsageKwh:       usageNum,
-		ReadingQuality: readingQuality}, nil
+// isDataLine returns true if the line looks like a line with usage data, even if it is malformed.
+func isDataLine(line string) bool {
+	return dataLinePrefix.MatchString(line)
 }
 
 const QUOTE = "\""
@@ -124,7 +147,8 @@ func checkDiff(before time.Time, after time.Time, expectedDiff time.Duration) er
 
 func parseUsage(usageStr string) (float64, error) {
 	noQuotes := removeQuotes(usageStr)
-	return strconv.ParseFloat(noQuotes, 64)
+	// Quoted values may have thousands separators, e.g. "1,234.5".
+	return strconv.ParseFloat(strings.ReplaceAll(noQuotes, ",", ""), 64)
 }
 
 func removeQuotes(in string) string {
@@ -0,0 +1,128 @@
+package csvparser
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// conflictTolerance is the largest difference in kWh between two readings of the same interval that is not
+// reported as a conflict. Green Button files have 3 decimals.
+const conflictTolerance = 0.0005
+
+// Source is a parsed Green Button download.
+type Source struct {
+	Name string
+	Rows CsvFile
+	// Downloaded is used to prefer newer downloads when readings have the same quality.
+	Downloaded time.Time
+}
+
+// ConflictValue is a reading of an interval from one of the sources.
+type ConflictValue struct {
+	Source         string
+	UsageKwh       float64
+	ReadingQuality ReadingQuality
+}
+
+// Conflict reports an interval with different readings in different sources.
+type Conflict struct {
+	Meter     MeterId
+	StartTime time.Time
+	Values    []ConflictValue
+	// Chosen is the reading that was kept.
+	Chosen ConflictValue
+}
+
+// intervalKey identifies an interval of a single meter.
+type intervalKey struct {
+	meter MeterId
+	start time.Time
+}
+
+type candidate struct {
+	row        CsvRow
+	source     string
+	downloaded time.Time
+	order      int
+}
+
+// better returns true if c should be kept over other: better reading quality wins, then the newer download, then
+// the source that comes later.
+func (c *candidate) better(other *candidate) bool {
+	if c.row.ReadingQuality.rank() != other.row.ReadingQuality.rank() {
+		return c.row.ReadingQuality.rank() > other.row.ReadingQuality.rank()
+	}
+	if !c.downloaded.Equal(other.downloaded) {
+		return c.downloaded.After(other.downloaded)
+	}
+	return c.order > other.order
+}
+
+// Merge unions the readings of several overlapping downloads into a single chronologically sorted file with one
+// reading per interval and meter. When an interval has several readings, the one with the best quality wins, then
+// the one from the newest download. Intervals whose readings disagree are returned as conflicts.
+func Merge(sources []Source) (CsvFile, []Conflict) {
+	byInterval := make(map[intervalKey][]candidate)
+	order := 0
+	for _, s := range sources {
+		for _, r := range s.Rows {
+			key := intervalKey{meter: r.Meter, start: r.StartTime}
+			byInterval[key] = append(byInterval[key], candidate{
+				row:        r,
+				source:     s.Name,
+				downloaded: s.Downloaded,
+				order:      order,
+			})
+			order++
+		}
+	}
+
+	out := make(CsvFile, 0, len(byInterval))
+	conflicts := make([]Conflict, 0)
+	for key, candidates := range byInterval {
+		best := &candidates[0]
+		disagree := false
+		for i := range candidates {
+			if math.Abs(candidates[i].row.UsageKwh-candidates[0].row.UsageKwh) > conflictTolerance {
+				disagree = true
+			}
+			if candidates[i].better(best) {
+				best = &candidates[i]
+			}
+		}
+		out = append(out, best.row)
+
+		if disagree {
+			conflict := Conflict{
+				Meter:     key.meter,
+				StartTime: key.start,
+				Values:    make([]ConflictValue, 0, len(candidates)),
+				Chosen:    toConflictValue(best),
+			}
+			for i := range candidates {
+				conflict.Values = append(conflict.Values, toConflictValue(&candidates[i]))
+			}
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].StartTime.Equal(out[j].StartTime) {
+			return out[i].Meter.String() < out[j].Meter.String()
+		}
+		return out[i].StartTime.Before(out[j].StartTime)
+	})
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].StartTime.Before(conflicts[j].StartTime)
+	})
+	return out, conflicts
+}
+
+func toConflictValue(c *candidate) ConflictValue {
+	return ConflictValue{
+		Source:         c.source,
+		UsageKwh:       c.row.UsageKwh,
+		ReadingQuality: c.row.ReadingQuality,
+	}
+}
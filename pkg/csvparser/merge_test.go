@@ -0,0 +1,75 @@
+package csvparser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var t0 = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func withQuality(row CsvRow, q ReadingQuality) CsvRow {
+	row.ReadingQuality = q
+	return row
+}
+
+func TestMerge_OverlappingFiles_UnionedWithoutDuplicates(t *testing.T) {
+	a := Source{Name: "a", Rows: CsvFile{
+		NewRowWith15MinuteDuration(t0, 1),
+		NewRowWith15MinuteDuration(t0.Add(15*time.Minute), 2),
+	}}
+	b := Source{Name: "b", Rows: CsvFile{
+		NewRowWith15MinuteDuration(t0.Add(15*time.Minute), 2),
+		NewRowWith15MinuteDuration(t0.Add(30*time.Minute), 3),
+	}}
+
+	got, conflicts := Merge([]Source{b, a})
+
+	assert.Empty(t, conflicts)
+	assert.Equal(t, CsvFile{a.Rows[0], a.Rows[1], b.Rows[1]}, got)
+}
+
+func TestMerge_DifferentQuality_BetterQualityWins(t *testing.T) {
+	older := Source{Name: "older", Downloaded: t0, Rows: CsvFile{
+		NewRowWith15MinuteDuration(t0, 1),
+	}}
+	newer := Source{Name: "newer", Downloaded: t0.Add(time.Hour), Rows: CsvFile{
+		withQuality(NewRowWith15MinuteDuration(t0, 5), QualityEstimated),
+	}}
+
+	got, conflicts := Merge([]Source{older, newer})
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, 1.0, got[0].UsageKwh)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, "older", conflicts[0].Chosen.Source)
+	assert.Len(t, conflicts[0].Values, 2)
+}
+
+func TestMerge_SameQuality_NewerDownloadWins(t *testing.T) {
+	older := Source{Name: "older", Downloaded: t0, Rows: CsvFile{
+		NewRowWith15MinuteDuration(t0, 1),
+	}}
+	newer := Source{Name: "newer", Downloaded: t0.Add(time.Hour), Rows: CsvFile{
+		NewRowWith15MinuteDuration(t0, 2),
+	}}
+
+	got, conflicts := Merge([]Source{newer, older})
+
+	assert.Equal(t, 2.0, got[0].UsageKwh)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, t0, conflicts[0].StartTime)
+}
+
+func TestMerge_SameIntervalDifferentMeters_KeepsBoth(t *testing.T) {
+	house := NewRowWith15MinuteDuration(t0, 1)
+	house.Meter = MeterId{Meter: "house"}
+	adu := NewRowWith15MinuteDuration(t0, 2)
+	adu.Meter = MeterId{Meter: "adu"}
+
+	got, conflicts := Merge([]Source{{Name: "a", Rows: CsvFile{house, adu}}})
+
+	assert.Empty(t, conflicts)
+	assert.Equal(t, CsvFile{adu, house}, got)
+}
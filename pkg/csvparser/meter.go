@@ -0,0 +1,52 @@
+package csvparser
+
+import (
+	"strings"
+)
+
+// MeterId identifies the meter a reading belongs to. A single download may contain several service accounts, each
+// with its own preamble.
+type MeterId struct {
+	ServiceAccount string
+	Meter          string
+	Location       string
+}
+
+// String returns the most specific identity available.
+func (m MeterId) String() string {
+	switch {
+	case m.Meter != "":
+		return m.Meter
+	case m.ServiceAccount != "":
+		return m.ServiceAccount
+	case m.Location != "":
+		return m.Location
+	}
+	return "default meter"
+}
+
+// sectionStart is the first line of the preamble of every service account within a download.
+const sectionStart = "Energy Usage Information"
+
+// updateFromPreamble updates the identity from a preamble line such as "For location: 1234 FAKE ST". Other lines
+// are ignored.
+func (m *MeterId) updateFromPreamble(line string) {
+	line = strings.TrimSpace(removeQuotes(strings.TrimSpace(line)))
+	if line == sectionStart {
+		*m = MeterId{}
+		return
+	}
+	split := strings.SplitN(line, ":", 2)
+	if len(split) != 2 {
+		return
+	}
+	value := strings.TrimSpace(split[1])
+	switch strings.ToLower(strings.TrimSpace(split[0])) {
+	case "for location":
+		m.Location = value
+	case "service account", "service account number", "account number":
+		m.ServiceAccount = value
+	case "meter", "meter number", "meter id":
+		m.Meter = value
+	}
+}
@@ -0,0 +1,46 @@
+package csvparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReader_TagsRowsWithLocationFromPreamble(t *testing.T) {
+	got, err := Parse(readOrDie("one_day_constant_power.csv"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, MeterId{Location: "CA FOO ST MY CITY 12345"}, got[0].Meter)
+}
+
+func TestReader_SeveralServiceAccounts_TagsEachSection(t *testing.T) {
+	file := strings.Join([]string{
+		`Energy Usage Information`,
+		`"For location: 1 MAIN ST"`,
+		`"Service Account: 111"`,
+		`"Meter Number: A"`,
+		HEADER,
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1",""`,
+		``,
+		`Energy Usage Information`,
+		`"For location: 1 MAIN ST ADU"`,
+		`"Service Account: 222"`,
+		HEADER,
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","2",""`,
+	}, "\n")
+
+	got, err := Parse(file)
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 2)
+	assert.Equal(t, MeterId{ServiceAccount: "111", Meter: "A", Location: "1 MAIN ST"}, got[0].Meter)
+	assert.Equal(t, MeterId{ServiceAccount: "222", Location: "1 MAIN ST ADU"}, got[1].Meter)
+}
+
+func TestMeterId_String_PrefersMostSpecific(t *testing.T) {
+	assert.Equal(t, "A", MeterId{ServiceAccount: "111", Meter: "A", Location: "X"}.String())
+	assert.Equal(t, "111", MeterId{ServiceAccount: "111", Location: "X"}.String())
+	assert.Equal(t, "X", MeterId{Location: "X"}.String())
+	assert.Equal(t, "default meter", MeterId{}.String())
+}
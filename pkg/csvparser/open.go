@@ -0,0 +1,165 @@
+package csvparser
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// Open opens a Green Button download for reading with NewReader. Plain CSV files, gzip files and zip archives (as
+// downloaded from SCE) are supported. The CSV files within a zip archive are read one after the other, in name
+// order.
+func Open(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReader(f)
+	magic, err := buffered.Peek(len(zipMagic))
+	if err != nil && err != io.EOF {
+		_ = f.Close()
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		return &gzipReadCloser{Reader: gz, file: f}, nil
+	case bytes.HasPrefix(magic, zipMagic):
+		_ = f.Close()
+		return openZip(path)
+	}
+	return &bufferedReadCloser{Reader: buffered, file: f}, nil
+}
+
+// OpenBytes is like Open, for a download that is already in memory, such as an upload.
+func OpenBytes(data []byte) (io.ReadCloser, error) {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return gz, nil
+	case bytes.HasPrefix(data, zipMagic):
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		return newZipReadCloser(archive, nil), nil
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+type bufferedReadCloser struct {
+	*bufio.Reader
+	file *os.File
+}
+
+func (r *bufferedReadCloser) Close() error {
+	return r.file.Close()
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *gzipReadCloser) Close() error {
+	gzErr := r.Reader.Close()
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	return gzErr
+}
+
+// zipReadCloser reads the CSV entries of a zip archive one after the other, separated by newlines.
+type zipReadCloser struct {
+	// archive closes the archive's file. It is nil for archives in memory.
+	archive io.Closer
+	entries []*zip.File
+	current io.ReadCloser
+}
+
+func openZip(path string) (io.ReadCloser, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	return newZipReadCloser(&archive.Reader, archive), nil
+}
+
+func newZipReadCloser(archive *zip.Reader, closer io.Closer) *zipReadCloser {
+	entries := make([]*zip.File, 0)
+	for _, f := range archive.File {
+		if !f.FileInfo().IsDir() && strings.HasSuffix(strings.ToLower(f.Name), ".csv") {
+			entries = append(entries, f)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return &zipReadCloser{archive: closer, entries: entries}
+}
+
+func (z *zipReadCloser) Read(p []byte) (int, error) {
+	for {
+		if z.current == nil {
+			if len(z.entries) == 0 {
+				return 0, io.EOF
+			}
+			entry, err := z.entries[0].Open()
+			if err != nil {
+				return 0, err
+			}
+			z.entries = z.entries[1:]
+			z.current = &entryReadCloser{Reader: io.MultiReader(entry, strings.NewReader("\n")), entry: entry}
+		}
+		n, err := z.current.Read(p)
+		if err == io.EOF {
+			if closeErr := z.current.Close(); closeErr != nil {
+				return n, closeErr
+			}
+			z.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (z *zipReadCloser) Close() error {
+	if z.current != nil {
+		_ = z.current.Close()
+	}
+	if z.archive == nil {
+		return nil
+	}
+	return z.archive.Close()
+}
+
+type entryReadCloser struct {
+	io.Reader
+	entry io.ReadCloser
+}
+
+func (e *entryReadCloser) Close() error {
+	return e.entry.Close()
+}
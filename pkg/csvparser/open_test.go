@@ -0,0 +1,122 @@
+package csvparser
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readAllFromPath(t *testing.T, path string) CsvFile {
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	got, err := NewReader(f, DefaultParseOptions()).ReadAll()
+	assert.NoError(t, err)
+	return got
+}
+
+func TestOpen_PlainCsv(t *testing.T) {
+	got := readAllFromPath(t, "testdata/one_day_constant_power.csv")
+
+	assert.Len(t, got, 24*4)
+}
+
+func TestOpen_Gzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.csv.gz")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	_, err = gz.Write([]byte(readOrDie("one_day_constant_power.csv")))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+	assert.NoError(t, f.Close())
+
+	got := readAllFromPath(t, path)
+
+	assert.Len(t, got, 24*4)
+}
+
+func TestOpen_Zip_ReadsEveryCsvEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.zip")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	w := zip.NewWriter(f)
+	for _, name := range []string{"a.csv", "b.csv", "notes.txt"} {
+		entry, err := w.Create(name)
+		assert.NoError(t, err)
+		_, err = entry.Write([]byte(readOrDie("one_day_constant_power.csv")))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	assert.NoError(t, f.Close())
+
+	got := readAllFromPath(t, path)
+
+	assert.Len(t, got, 2*24*4)
+}
+
+func TestOpen_MissingFile_Fails(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "nope.csv"))
+
+	assert.Error(t, err)
+}
+
+func readAllFromBytes(t *testing.T, data []byte) CsvFile {
+	f, err := OpenBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	got, err := NewReader(f, DefaultParseOptions()).ReadAll()
+	assert.NoError(t, err)
+	return got
+}
+
+func TestOpenBytes_PlainCsv(t *testing.T) {
+	got := readAllFromBytes(t, []byte(readOrDie("one_day_constant_power.csv")))
+
+	assert.Len(t, got, 24*4)
+}
+
+func TestOpenBytes_Gzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(readOrDie("one_day_constant_power.csv")))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+
+	got := readAllFromBytes(t, buf.Bytes())
+
+	assert.Len(t, got, 24*4)
+}
+
+func TestOpenBytes_Zip_ReadsEveryCsvEntry(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range []string{"a.csv", "b.csv", "notes.txt"} {
+		entry, err := w.Create(name)
+		assert.NoError(t, err)
+		_, err = entry.Write([]byte(readOrDie("one_day_constant_power.csv")))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	got := readAllFromBytes(t, buf.Bytes())
+
+	assert.Len(t, got, 2*24*4)
+}
+
+func TestOpenBytes_CorruptGzip_Fails(t *testing.T) {
+	_, err := OpenBytes([]byte{0x1f, 0x8b, 0x00})
+
+	assert.Error(t, err)
+}
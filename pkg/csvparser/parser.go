@@ -1,6 +1,7 @@
 package csvparser
 
 import (
+	"fmt"
 	"strings"
 	"time"
 )
@@ -81,7 +82,7 @@ func NewRowWith15MinuteDuration(start time.Time, usageKwh float64) CsvRow {
 		StartTime:      start,
 		EndTime:        start.Add(15 * time.Minute),
 		UsageKwh:       usageKwh,
-		ReadingQuality: "",
+		ReadingQuality: QualityActual,
 	}
 }
 
@@ -89,27 +90,82 @@ type CsvRow struct {
 	StartTime      time.Time
 	EndTime        time.Time
 	UsageKwh       float64
-	ReadingQuality string
+	ReadingQuality ReadingQuality
+	Meter          MeterId
 }
 
 func (r *CsvRow) Duration() time.Duration {
 	return r.EndTime.Sub(r.StartTime)
 }
 
+// DemandKw returns the average power over the reading's interval.
+func (r *CsvRow) DemandKw() float64 {
+	if r.Duration() <= 0 {
+		return 0
+	}
+	return r.UsageKwh / r.Duration().Hours()
+}
+
 type CsvFile []CsvRow
 
+// ParseMode decides what happens to malformed data lines.
+type ParseMode int
+
+const (
+	// Strict fails the parse if any data line is malformed.
+	Strict ParseMode = iota
+	// Lenient skips malformed data lines and reports them as warnings.
+	Lenient
+)
+
+type ParseOptions struct {
+	Mode ParseMode
+	// MaxErrors stops parsing and fails, even in Lenient mode, once more than this many malformed lines are found.
+	// 0 means no limit.
+	MaxErrors int
+}
+
+// DefaultParseOptions returns strict options without an error limit.
+func DefaultParseOptions() ParseOptions {
+	return ParseOptions{
+		Mode:      Strict,
+		MaxErrors: 0,
+	}
+}
+
+// ParseErrors lists every malformed line found in a file.
+type ParseErrors struct {
+	Errors []*LineParsingError
+	// Truncated is true if parsing stopped because there were more than ParseOptions.MaxErrors malformed lines.
+	Truncated bool
+}
+
+func (e *ParseErrors) Error() string {
+	msgs := make([]string, 0, len(e.Errors)+1)
+	msgs = append(msgs, fmt.Sprintf("%d lines could not be parsed", len(e.Errors)))
+	for _, lineErr := range e.Errors {
+		msgs = append(msgs, lineErr.Error())
+	}
+	if e.Truncated {
+		msgs = append(msgs, "Stopped after exceeding the maximum number of errors.")
+	}
+	return strings.Join(msgs, "\n\n")
+}
+
+// Parse parses a Green Button file with DefaultParseOptions.
 func Parse(fileIn string) (CsvFile, error) {
-	lines := strings.Split(fileIn, "\n")
-	out := make(CsvFile, 0)
-	for lNum, l := range lines {
-		parsed, err := parseHourConsumption(l, lNum)
-		if err != nil {
-			if err.(*LineParsingError).CanBeIgnored {
-				continue
-			}
-			return nil, err
-		}
-		out = append(out, *parsed)
+	out, _, err := ParseWithOptions(fileIn, DefaultParseOptions())
+	return out, err
+}
+
+// ParseWithOptions parses a Green Button file. In Strict mode, the error is a *ParseErrors with every malformed
+// line. In Lenient mode, the malformed lines are skipped and returned as warnings instead.
+// Use NewReader to parse large files without holding them in memory.
+func ParseWithOptions(fileIn string, opts ParseOptions) (CsvFile, []*LineParsingError, error) {
+	r := NewReader(strings.NewReader(fileIn), opts)
+	out, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, err
 	}
-	return out, nil
+	return out, r.Warnings(), nil
 }
@@ -83,6 +83,101 @@ func TestDurationWorksOnFallDaylightSavings(t *testing.T) {
 	assert.Equal(t, 15*time.Minute, got[0].Duration())
 }
 
+func TestReadingQualityIsParsed(t *testing.T) {
+	file := addHeaderTo([]string{
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1",""`,
+		`"2020-01-01 00:15:00 to 2020-01-01 00:30:00","1","estimated"`,
+	})
+	got, err := Parse(file)
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 2)
+	assert.Equal(t, QualityActual, got[0].ReadingQuality)
+	assert.Equal(t, QualityEstimated, got[1].ReadingQuality)
+}
+
+func TestUnknownReadingQualityFails(t *testing.T) {
+	file := addHeaderTo([]string{
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1","guessed"`,
+	})
+	_, err := Parse(file)
+
+	assert.Error(t, err)
+}
+
+func TestUnknownReadingQuality_Lenient_KeepsRowAndWarns(t *testing.T) {
+	file := addHeaderTo([]string{
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1","guessed"`,
+		`"2020-01-01 00:15:00 to 2020-01-01 00:30:00","2",""`,
+	})
+	got, warnings, err := ParseWithOptions(file, ParseOptions{Mode: Lenient})
+	assert.NoError(t, err)
+
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, 1.0, got[0].UsageKwh)
+		assert.Equal(t, QualityActual, got[0].ReadingQuality)
+	}
+	if assert.Len(t, warnings, 1) {
+		assert.Contains(t, warnings[0].Error(), "unknown reading quality")
+	}
+}
+
+func TestMalformedDataLine_Strict_Fails(t *testing.T) {
+	file := addHeaderTo([]string{
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1"`,
+	})
+	_, err := Parse(file)
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Expected 3 components")
+	}
+}
+
+func TestMalformedDataLines_Strict_ReportsEveryLine(t *testing.T) {
+	file := addHeaderTo([]string{
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1"`,
+		`"2020-01-01 00:15:00 to 2020-01-01 00:30:00","1",""`,
+		`"2020-01-01 00:30:00 to 2020-01-01 00:45:00","abc",""`,
+	})
+	_, _, err := ParseWithOptions(file, DefaultParseOptions())
+
+	parseErrors, ok := err.(*ParseErrors)
+	if assert.True(t, ok) {
+		assert.Len(t, parseErrors.Errors, 2)
+		assert.False(t, parseErrors.Truncated)
+		assert.Contains(t, parseErrors.Errors[0].LineText, "00:00:00")
+		assert.Contains(t, parseErrors.Errors[1].LineText, "abc")
+		assert.Equal(t, 1, parseErrors.Errors[1].LineNumber-parseErrors.Errors[0].LineNumber-1)
+	}
+}
+
+func TestMalformedDataLines_Lenient_SkipsAndWarns(t *testing.T) {
+	file := addHeaderTo([]string{
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1"`,
+		`"2020-01-01 00:15:00 to 2020-01-01 00:30:00","1",""`,
+	})
+	got, warnings, err := ParseWithOptions(file, ParseOptions{Mode: Lenient})
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 1)
+	assert.Len(t, warnings, 1)
+}
+
+func TestMalformedDataLines_MoreThanMaxErrors_Fails(t *testing.T) {
+	file := addHeaderTo([]string{
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1"`,
+		`"2020-01-01 00:15:00 to 2020-01-01 00:30:00","1"`,
+		`"2020-01-01 00:30:00 to 2020-01-01 00:45:00","1"`,
+	})
+	_, _, err := ParseWithOptions(file, ParseOptions{Mode: Lenient, MaxErrors: 2})
+
+	parseErrors, ok := err.(*ParseErrors)
+	if assert.True(t, ok) {
+		assert.Len(t, parseErrors.Errors, 2)
+		assert.True(t, parseErrors.Truncated)
+	}
+}
+
 func readOrDie(file string) string {
 	fileBytes, err := ioutil.ReadFile("testdata/" + file)
 	if err != nil {
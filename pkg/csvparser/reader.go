@@ -0,0 +1,179 @@
+package csvparser
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxRecordLength is the longest record the Reader accepts. Green Button records are much shorter than this, so a
+// longer one is most likely an unterminated quote.
+const maxRecordLength = 1024 * 1024
+
+// Reader reads the rows of a Green Button file one at a time, so that large files can be processed without holding
+// them in memory. The whole file goes through a single csv.Reader, so quoted fields may contain commas and newlines.
+//
+// Malformed data lines are handled according to the ParseOptions. In Strict mode, rows are still returned as they
+// are read, but Read returns a *ParseErrors instead of io.EOF at the end of the input if any line was malformed.
+// In Lenient mode, malformed lines are skipped, except for lines whose only problem is an unknown reading quality.
+// Those are kept as actual readings. Either way, they are reported as warnings.
+type Reader struct {
+	lines *lineReader
+	csv   *csv.Reader
+	opts  ParseOptions
+	meter MeterId
+	bad   []*LineParsingError
+	err   error
+}
+
+// NewReader returns a Reader that parses Green Button rows from r.
+func NewReader(r io.Reader, opts ParseOptions) *Reader {
+	lines := &lineReader{in: bufio.NewReader(r)}
+	records := csv.NewReader(lines)
+	// Preamble lines have a single field, and data lines are checked by the line parser.
+	records.FieldsPerRecord = -1
+	records.LazyQuotes = true
+	return &Reader{
+		lines: lines,
+		csv:   records,
+		opts:  opts,
+		bad:   make([]*LineParsingError, 0),
+	}
+}
+
+// Read returns the next row. At the end of the input, it returns io.EOF.
+// Rows are tagged with the meter described by the preamble that precedes them.
+func (r *Reader) Read() (*CsvRow, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	for {
+		lineNumber := r.lines.count
+		fields, err := r.csv.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			r.err = err
+			return nil, r.err
+		}
+		lineNumber, line := r.lines.takeRecord(lineNumber)
+
+		parsed, err := parseHourConsumption(fields, line, lineNumber)
+		if err == nil {
+			parsed.Meter = r.meter
+			return parsed, nil
+		}
+		lineErr := err.(*LineParsingError)
+		if lineErr.CanBeIgnored {
+			r.meter.updateFromPreamble(line)
+			continue
+		}
+		r.bad = append(r.bad, lineErr)
+		if r.opts.MaxErrors > 0 && len(r.bad) > r.opts.MaxErrors {
+			r.err = &ParseErrors{Errors: r.bad[:r.opts.MaxErrors], Truncated: true}
+			return nil, r.err
+		}
+		if r.opts.Mode == Lenient && lineErr.Recovered != nil {
+			row := *lineErr.Recovered
+			row.Meter = r.meter
+			return &row, nil
+		}
+	}
+	if len(r.bad) > 0 && r.opts.Mode == Strict {
+		r.err = &ParseErrors{Errors: r.bad}
+	} else {
+		r.err = io.EOF
+	}
+	return nil, r.err
+}
+
+// ForEach calls fn for every row until the end of the input. It stops at the first error returned by fn.
+func (r *Reader) ForEach(fn func(row CsvRow) error) error {
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(*row); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadAll reads all the remaining rows.
+func (r *Reader) ReadAll() (CsvFile, error) {
+	out := make(CsvFile, 0)
+	err := r.ForEach(func(row CsvRow) error {
+		out = append(out, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Warnings returns the malformed lines that were skipped so far in Lenient mode.
+func (r *Reader) Warnings() []*LineParsingError {
+	if r.opts.Mode != Lenient {
+		return nil
+	}
+	return r.bad
+}
+
+// lineReader hands out its input at most one line per Read call. csv.Reader only asks for more input when it
+// doesn't have a whole line buffered, so the lines handed out are exactly the lines of the records read so far. This
+// recovers the line number and text of each record, which csv.Reader doesn't expose.
+type lineReader struct {
+	in *bufio.Reader
+	// pending is the rest of the current line.
+	pending []byte
+	// record is the text handed out since the last call to takeRecord.
+	record strings.Builder
+	// count is the number of whole lines handed out.
+	count int
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		line, err := r.in.ReadSlice('\n')
+		if len(line) == 0 {
+			return 0, err
+		}
+		r.pending = line
+	}
+	if r.record.Len() > maxRecordLength {
+		return 0, fmt.Errorf("record starting on line %d is longer than %d bytes", r.count-strings.Count(r.record.String(), "\n"), maxRecordLength)
+	}
+	n := copy(p, r.pending)
+	r.record.Write(p[:n])
+	if p[n-1] == '\n' {
+		r.count++
+	}
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// takeRecord returns the 0-based line number and the text of the record that csv.Reader just read, given the number
+// of lines handed out before it. Blank lines that csv.Reader skipped before the record are dropped.
+func (r *lineReader) takeRecord(lineNumber int) (int, string) {
+	text := r.record.String()
+	r.record.Reset()
+	for {
+		if strings.HasPrefix(text, "\n") {
+			text = text[1:]
+		} else if strings.HasPrefix(text, "\r\n") {
+			text = text[2:]
+		} else {
+			break
+		}
+		lineNumber++
+	}
+	return lineNumber, strings.TrimRight(text, "\r\n")
+}
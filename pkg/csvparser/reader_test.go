@@ -0,0 +1,112 @@
+package csvparser
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReader_Read_ReturnsRowsThenEOF(t *testing.T) {
+	r := NewReader(strings.NewReader(readOrDie("one_day_constant_power.csv")), DefaultParseOptions())
+
+	count := 0
+	for {
+		_, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		count++
+	}
+
+	assert.Equal(t, 24*4, count)
+}
+
+func TestReader_ForEach_StopsOnCallbackError(t *testing.T) {
+	r := NewReader(strings.NewReader(readOrDie("one_day_constant_power.csv")), DefaultParseOptions())
+
+	count := 0
+	err := r.ForEach(func(row CsvRow) error {
+		count++
+		if count == 3 {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	})
+
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestReader_QuotedUsageWithComma_Parses(t *testing.T) {
+	file := addHeaderTo([]string{
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1,234.5",""`,
+	})
+
+	got, err := NewReader(strings.NewReader(file), DefaultParseOptions()).ReadAll()
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, 1234.5, got[0].UsageKwh)
+}
+
+func TestReader_WindowsLineEndings_Parses(t *testing.T) {
+	file := strings.ReplaceAll(readOrDie("one_day_constant_power.csv"), "\n", "\r\n")
+
+	got, err := NewReader(strings.NewReader(file), DefaultParseOptions()).ReadAll()
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 24*4)
+}
+
+func TestReader_Strict_ReturnsParseErrorsAtEnd(t *testing.T) {
+	file := addHeaderTo([]string{
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1"`,
+		`"2020-01-01 00:15:00 to 2020-01-01 00:30:00","1",""`,
+	})
+	r := NewReader(strings.NewReader(file), DefaultParseOptions())
+
+	row, err := r.Read()
+	assert.NoError(t, err)
+	assert.NotNil(t, row)
+
+	_, err = r.Read()
+	assert.IsType(t, &ParseErrors{}, err)
+	assert.Nil(t, r.Warnings())
+}
+
+func TestReader_QuotedFieldWithNewline_IsOneRecord(t *testing.T) {
+	// Without quoting, the second line of the note would look like a malformed data line.
+	file := addHeaderTo([]string{
+		`"Note: readings from`,
+		`2020-01-01 00:00:00 to 2020-01-01 00:15:00 are estimated"`,
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1","estimated"`,
+	})
+
+	got, err := NewReader(strings.NewReader(file), DefaultParseOptions()).ReadAll()
+	assert.NoError(t, err)
+
+	if assert.Len(t, got, 1) {
+		assert.Equal(t, QualityEstimated, got[0].ReadingQuality)
+	}
+}
+
+func TestReader_MalformedLineAfterMultilineRecord_ReportsLineNumberAndText(t *testing.T) {
+	header := readOrDie("header_only.csv")
+	file := header + "\n" + strings.Join([]string{
+		`"Note: spans`,
+		`two lines"`,
+		``,
+		`"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1"`,
+	}, "\n")
+
+	_, err := NewReader(strings.NewReader(file), DefaultParseOptions()).ReadAll()
+
+	parseErrors, ok := err.(*ParseErrors)
+	if assert.True(t, ok) && assert.Len(t, parseErrors.Errors, 1) {
+		assert.Equal(t, strings.Count(header, "\n")+4, parseErrors.Errors[0].LineNumber)
+		assert.Equal(t, `"2020-01-01 00:00:00 to 2020-01-01 00:15:00","1"`, parseErrors.Errors[0].LineText)
+	}
+}
@@ -0,0 +1,75 @@
+package csvparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReadingQuality is the "Reading quality" column of a Green Button file.
+type ReadingQuality int
+
+const (
+	// QualityActual is a regular meter reading. SCE leaves the column empty for these.
+	QualityActual ReadingQuality = iota
+	// QualityValidated is a reading that was checked by SCE.
+	QualityValidated
+	// QualityEstimated is a reading that was estimated by SCE, usually because the meter did not report it.
+	QualityEstimated
+	// QualityMissing is a reading that SCE does not have.
+	QualityMissing
+)
+
+// ParseReadingQuality parses the value of the "Reading quality" column.
+func ParseReadingQuality(in string) (ReadingQuality, error) {
+	switch strings.ToLower(strings.TrimSpace(removeQuotes(in))) {
+	case "", "actual":
+		return QualityActual, nil
+	case "validated":
+		return QualityValidated, nil
+	case "estimated":
+		return QualityEstimated, nil
+	case "missing":
+		return QualityMissing, nil
+	}
+	return QualityActual, fmt.Errorf("unknown reading quality %s", in)
+}
+
+func (q ReadingQuality) String() string {
+	switch q {
+	case QualityActual:
+		return "actual"
+	case QualityValidated:
+		return "validated"
+	case QualityEstimated:
+		return "estimated"
+	case QualityMissing:
+		return "missing"
+	}
+	return fmt.Sprintf("ReadingQuality(%d)", int(q))
+}
+
+// rank orders the qualities from worst to best.
+func (q ReadingQuality) rank() int {
+	switch q {
+	case QualityMissing:
+		return 0
+	case QualityEstimated:
+		return 1
+	case QualityActual:
+		return 2
+	case QualityValidated:
+		return 3
+	}
+	return -1
+}
+
+// AtLeast returns true if q is as good as or better than min. From worst to best: missing, estimated, actual and
+// validated.
+func (q ReadingQuality) AtLeast(min ReadingQuality) bool {
+	return q.rank() >= min.rank()
+}
+
+// IsLowQuality returns true for estimated and missing readings.
+func (q ReadingQuality) IsLowQuality() bool {
+	return !q.AtLeast(QualityActual)
+}
@@ -0,0 +1,44 @@
+package csvparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReadingQuality_KnownValues(t *testing.T) {
+	tests := map[string]ReadingQuality{
+		`""`:          QualityActual,
+		`"actual"`:    QualityActual,
+		`"Validated"`: QualityValidated,
+		`"estimated"`: QualityEstimated,
+		`"MISSING"`:   QualityMissing,
+	}
+	for in, want := range tests {
+		t.Run(in, func(t *testing.T) {
+			got, err := ParseReadingQuality(in)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestParseReadingQuality_Unknown_Fails(t *testing.T) {
+	_, err := ParseReadingQuality(`"guessed"`)
+
+	assert.Error(t, err)
+}
+
+func TestReadingQuality_AtLeast_OrdersWorstToBest(t *testing.T) {
+	assert.True(t, QualityValidated.AtLeast(QualityActual))
+	assert.True(t, QualityActual.AtLeast(QualityActual))
+	assert.False(t, QualityEstimated.AtLeast(QualityActual))
+	assert.False(t, QualityMissing.AtLeast(QualityEstimated))
+}
+
+func TestReadingQuality_IsLowQuality(t *testing.T) {
+	assert.False(t, QualityActual.IsLowQuality())
+	assert.False(t, QualityValidated.IsLowQuality())
+	assert.True(t, QualityEstimated.IsLowQuality())
+	assert.True(t, QualityMissing.IsLowQuality())
+}
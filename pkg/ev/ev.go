@@ -0,0 +1,154 @@
+// Package ev finds likely electric vehicle charging sessions in interval data and prices them.
+package ev
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+)
+
+// householdWindow is how many readings before a session are used to estimate the rest of the house's demand.
+const householdWindow = 4
+
+// Options tunes what counts as a charging session.
+type Options struct {
+	// MinKw and MaxKw bound the demand of readings within a session. Level 2 chargers draw 6-11 kW.
+	MinKw float64
+	MaxKw float64
+	// MinDuration is the shortest session. Shorter plateaus are usually ovens, dryers or water heaters.
+	MinDuration time.Duration
+}
+
+// DefaultOptions finds plateaus of 6-13 kW lasting at least an hour. The upper bound leaves 2 kW for the rest of the
+// house on top of an 11 kW charger. Higher plateaus are usually several appliances, such as a heat pump with its
+// backup heat.
+func DefaultOptions() Options {
+	return Options{
+		MinKw:       6,
+		MaxKw:       13,
+		MinDuration: time.Hour,
+	}
+}
+
+// Session is a likely charging session.
+type Session struct {
+	Start time.Time
+	End   time.Time
+	// EnergyKwh only counts the demand above what the rest of the house used just before the session.
+	EnergyKwh float64
+	// Cost is the cost of the session's energy at the plan's marginal rates.
+	Cost float64
+	// ShiftedCost is what the same energy would have cost in the cheapest hours of the day, which are the
+	// super-off-peak hours on plans that have them.
+	ShiftedCost float64
+}
+
+func (s *Session) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// AverageKw returns the charging power.
+func (s *Session) AverageKw() float64 {
+	return s.EnergyKwh / s.Duration().Hours()
+}
+
+// Savings returns how much less the session would cost if it was moved to the cheapest hours.
+func (s *Session) Savings() float64 {
+	return s.Cost - s.ShiftedCost
+}
+
+// reading is a timestamped energy value, either a CsvRow or a UsageHour.
+type reading struct {
+	start    time.Time
+	end      time.Time
+	usageKwh float64
+}
+
+func (r *reading) demandKw() float64 {
+	return r.usageKwh / r.end.Sub(r.start).Hours()
+}
+
+// DetectSessions finds charging sessions in 15-minute readings and prices them under the plan. Readings should
+// belong to a single meter.
+func DetectSessions(file csvparser.CsvFile, plan costcalculator.TouPlan, opts Options) []Session {
+	readings := make([]reading, len(file))
+	for i, r := range file {
+		readings[i] = reading{start: r.StartTime, end: r.EndTime, usageKwh: r.UsageKwh}
+	}
+	return detect(readings, plan, opts)
+}
+
+// DetectSessionsInHours is like DetectSessions for hourly data, such as simulated loads. Hourly data hides short
+// sessions and sessions that don't start on the hour.
+func DetectSessionsInHours(hours []analyzer.UsageHour, plan costcalculator.TouPlan, opts Options) []Session {
+	readings := make([]reading, len(hours))
+	for i, hr := range hours {
+		readings[i] = reading{start: hr.StartTime(), end: hr.EndTime(), usageKwh: hr.UsageKwh()}
+	}
+	return detect(readings, plan, opts)
+}
+
+func detect(readings []reading, plan costcalculator.TouPlan, opts Options) []Session {
+	sort.SliceStable(readings, func(i, j int) bool {
+		return readings[i].start.Before(readings[j].start)
+	})
+
+	out := make([]Session, 0)
+	for i := 0; i < len(readings); {
+		if !inRange(&readings[i], opts) {
+			i++
+			continue
+		}
+		// Extend the run while readings are contiguous and within range.
+		end := i + 1
+		for end < len(readings) && inRange(&readings[end], opts) && readings[end].start.Equal(readings[end-1].end) {
+			end++
+		}
+		if readings[end-1].end.Sub(readings[i].start) >= opts.MinDuration {
+			out = append(out, price(readings[i:end], householdKw(readings, i), plan))
+		}
+		i = end
+	}
+	return out
+}
+
+func inRange(r *reading, opts Options) bool {
+	kw := r.demandKw()
+	return kw >= opts.MinKw && kw <= opts.MaxKw
+}
+
+// householdKw returns the median demand of the readings just before the session starting at readings[start], or 0
+// if there are none.
+func householdKw(readings []reading, start int) float64 {
+	values := make([]float64, 0, householdWindow)
+	for i := start - 1; i >= 0 && len(values) < householdWindow; i-- {
+		values = append(values, math.Max(0, readings[i].demandKw()))
+	}
+	sort.Float64s(values)
+	return analyzer.Percentile(values, 0.5)
+}
+
+func price(run []reading, householdKw float64, plan costcalculator.TouPlan) Session {
+	s := Session{Start: run[0].start, End: run[len(run)-1].end}
+	for i := range run {
+		kwh := run[i].usageKwh - householdKw*run[i].end.Sub(run[i].start).Hours()
+		s.EnergyKwh += kwh
+		s.Cost += kwh * costcalculator.MarginalRate(run[i].start, plan)
+	}
+	s.ShiftedCost = s.EnergyKwh * cheapestRate(s.Start, plan)
+	return s
+}
+
+// cheapestRate returns the lowest rate of the plan in the 24 hours starting at the hour of t.
+func cheapestRate(t time.Time, plan costcalculator.TouPlan) float64 {
+	start := t.Truncate(time.Hour)
+	lowest := math.Inf(1)
+	for h := 0; h < 24; h++ {
+		lowest = math.Min(lowest, costcalculator.MarginalRate(start.Add(time.Duration(h)*time.Hour), plan))
+	}
+	return lowest
+}
@@ -0,0 +1,91 @@
+package ev
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/stretchr/testify/assert"
+)
+
+// winterWeekday is a Thursday in TOU-D-PRIME's winter season.
+var winterWeekday = time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+// day returns a day of 15-minute readings at 1 kW, and at kw between the given hours.
+func day(start time.Time, fromHour int, toHour int, kw float64) csvparser.CsvFile {
+	out := make(csvparser.CsvFile, 0, 96)
+	for i := 0; i < 96; i++ {
+		usage := 0.25
+		if i >= fromHour*4 && i < toHour*4 {
+			usage = kw / 4
+		}
+		out = append(out, csvparser.NewRowWith15MinuteDuration(start.Add(time.Duration(i)*15*time.Minute), usage))
+	}
+	return out
+}
+
+func TestDetectSessions_EveningCharge_PricedAndShifted(t *testing.T) {
+	plan := costcalculator.NewTouDPrime()
+	in := day(winterWeekday, 17, 20, 8)
+
+	got := DetectSessions(in, plan, DefaultOptions())
+
+	assert.Len(t, got, 1)
+	s := got[0]
+	assert.Equal(t, winterWeekday.Add(17*time.Hour), s.Start)
+	assert.Equal(t, 3*time.Hour, s.Duration())
+	assert.InDelta(t, 21.0, s.EnergyKwh, 0.0001)
+	assert.InDelta(t, 7.0, s.AverageKw(), 0.0001)
+	// Winter 4-9pm is mid-peak, and 8am-4pm is super-off-peak.
+	assert.InDelta(t, 21*plan.Cost(costcalculator.WinterMidPeak), s.Cost, 0.0001)
+	assert.InDelta(t, 21*plan.Cost(costcalculator.WinterSuperOffPeak), s.ShiftedCost, 0.0001)
+	assert.InDelta(t, 21*(0.41-0.16), s.Savings(), 0.0001)
+}
+
+func TestDetectSessions_ShortPlateau_Ignored(t *testing.T) {
+	in := day(winterWeekday, 17, 17, 8)
+	in[70].UsageKwh = 2
+	in[71].UsageKwh = 2
+
+	got := DetectSessions(in, costcalculator.NewTouDPrime(), DefaultOptions())
+
+	assert.Empty(t, got)
+}
+
+func TestDetectSessions_PlateauAboveCharger_Ignored(t *testing.T) {
+	in := day(winterWeekday, 17, 20, 15)
+
+	got := DetectSessions(in, costcalculator.NewTouDPrime(), DefaultOptions())
+
+	assert.Empty(t, got)
+}
+
+func TestDetectSessions_GapInReadings_SplitsSession(t *testing.T) {
+	in := day(winterWeekday, 0, 6, 8)
+	in = append(in[:8], in[9:]...)
+
+	got := DetectSessions(in, costcalculator.NewTouDPrime(), DefaultOptions())
+
+	assert.Len(t, got, 2)
+	assert.Equal(t, 2*time.Hour, got[0].Duration())
+	assert.Equal(t, winterWeekday.Add(135*time.Minute), got[1].Start)
+}
+
+func TestDetectSessionsInHours(t *testing.T) {
+	hours := make([]analyzer.UsageHour, 0)
+	for h := 0; h < 24; h++ {
+		usage := 1.0
+		if h == 1 || h == 2 {
+			usage = 10
+		}
+		start := winterWeekday.Add(time.Duration(h) * time.Hour)
+		hours = append(hours, analyzer.NewUsageHour(start, start.Add(time.Hour), usage))
+	}
+
+	got := DetectSessionsInHours(hours, costcalculator.NewTouDPrime(), DefaultOptions())
+
+	assert.Len(t, got, 1)
+	assert.InDelta(t, 18.0, got[0].EnergyKwh, 0.0001)
+}
@@ -0,0 +1,176 @@
+// Package finance projects bills into the future to compare the long-term value of energy systems.
+package finance
+
+import (
+	"math"
+
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+)
+
+// YearCosts is one year of electricity costs by component.
+type YearCosts struct {
+	// EnergyCost is the energy charged at TOU rates after credits, like the NEM true-up.
+	EnergyCost           float64
+	FixedCharges         float64
+	NonBypassableCharges float64
+	// OtherCharges are taxes and demand charges.
+	OtherCharges float64
+}
+
+func (c *YearCosts) Total() float64 {
+	return c.EnergyCost + c.FixedCharges + c.NonBypassableCharges + c.OtherCharges
+}
+
+// Scale returns the costs multiplied by factor, such as 365/days to turn a partial year into a full one.
+func (c YearCosts) Scale(factor float64) YearCosts {
+	return YearCosts{
+		EnergyCost:           c.EnergyCost * factor,
+		FixedCharges:         c.FixedCharges * factor,
+		NonBypassableCharges: c.NonBypassableCharges * factor,
+		OtherCharges:         c.OtherCharges * factor,
+	}
+}
+
+// CostsFromBill splits a bill into its components.
+func CostsFromBill(b *costcalculator.TouBillSummary) YearCosts {
+	return YearCosts{
+		EnergyCost:           b.TrueUp(),
+		FixedCharges:         b.TotalBasicCharge(),
+		NonBypassableCharges: b.NonBypassableCharges(),
+		OtherCharges:         b.Taxes() + b.DemandCharges(),
+	}
+}
+
+// Escalation is the yearly growth rate of each cost component, such as 0.04 for 4% a year.
+type Escalation struct {
+	Energy        float64
+	Fixed         float64
+	NonBypassable float64
+	Other         float64
+}
+
+// ProjectionOptions describes a system and the economic assumptions.
+type ProjectionOptions struct {
+	Years      int
+	Escalation Escalation
+	// PVDegradation is the yearly loss of solar production, such as 0.005. It shrinks the energy and
+	// non-bypassable charge savings.
+	PVDegradation float64
+	DiscountRate  float64
+	// SystemCost is the upfront cost after incentives.
+	SystemCost float64
+	// MaintenancePerYear is an ongoing cost, in today's dollars, that grows with Escalation.Other.
+	MaintenancePerYear float64
+}
+
+// DefaultProjectionOptions assumes 25 years, 4% energy escalation, 0.5% degradation and a 5% discount rate.
+func DefaultProjectionOptions(systemCost float64) ProjectionOptions {
+	return ProjectionOptions{
+		Years: 25,
+		Escalation: Escalation{
+			Energy:        0.04,
+			Fixed:         0.03,
+			NonBypassable: 0.04,
+			Other:         0.02,
+		},
+		PVDegradation: 0.005,
+		DiscountRate:  0.05,
+		SystemCost:    systemCost,
+	}
+}
+
+// YearlyCashFlow is a single year of a projection. Year 1 is the first year after installation.
+type YearlyCashFlow struct {
+	Year         int
+	BaselineCost float64
+	SystemCost   float64
+	// NetCashFlow is the savings minus maintenance.
+	NetCashFlow float64
+	// Cumulative includes the upfront cost.
+	Cumulative float64
+}
+
+// Projection is the outcome of a system over its lifetime.
+type Projection struct {
+	Years []YearlyCashFlow
+	// PaybackYear is the first year in which the cumulative cash flow is positive, or 0 if it never is.
+	PaybackYear int
+	NPV         float64
+	// IRR is the discount rate at which the NPV is 0, or NaN if there is none.
+	IRR float64
+}
+
+// Project grows the costs of a year without the system (baseline) and with it (system) over opts.Years.
+func Project(baseline YearCosts, system YearCosts, opts ProjectionOptions) Projection {
+	out := Projection{Years: make([]YearlyCashFlow, 0, opts.Years)}
+	flows := []float64{-opts.SystemCost}
+	cumulative := -opts.SystemCost
+	for y := 1; y <= opts.Years; y++ {
+		baselineCost := grow(baseline, opts.Escalation, y)
+		withSystem := grow(degrade(baseline, system, opts.PVDegradation, y), opts.Escalation, y)
+		maintenance := opts.MaintenancePerYear * math.Pow(1+opts.Escalation.Other, float64(y-1))
+		net := baselineCost.Total() - withSystem.Total() - maintenance
+		cumulative += net
+		out.Years = append(out.Years, YearlyCashFlow{
+			Year:         y,
+			BaselineCost: baselineCost.Total(),
+			SystemCost:   withSystem.Total(),
+			NetCashFlow:  net,
+			Cumulative:   cumulative,
+		})
+		if out.PaybackYear == 0 && cumulative >= 0 {
+			out.PaybackYear = y
+		}
+		flows = append(flows, net)
+	}
+	out.NPV = npv(flows, opts.DiscountRate)
+	out.IRR = irr(flows)
+	return out
+}
+
+// grow escalates the costs to year y, where year 1 is unchanged.
+func grow(c YearCosts, e Escalation, y int) YearCosts {
+	years := float64(y - 1)
+	return YearCosts{
+		EnergyCost:           c.EnergyCost * math.Pow(1+e.Energy, years),
+		FixedCharges:         c.FixedCharges * math.Pow(1+e.Fixed, years),
+		NonBypassableCharges: c.NonBypassableCharges * math.Pow(1+e.NonBypassable, years),
+		OtherCharges:         c.OtherCharges * math.Pow(1+e.Other, years),
+	}
+}
+
+// degrade returns the system's costs in year y, when the energy and non-bypassable charge savings have shrunk with
+// the panels' production.
+func degrade(baseline YearCosts, system YearCosts, degradation float64, y int) YearCosts {
+	remaining := math.Pow(1-degradation, float64(y-1))
+	out := system
+	out.EnergyCost = baseline.EnergyCost - (baseline.EnergyCost-system.EnergyCost)*remaining
+	out.NonBypassableCharges = baseline.NonBypassableCharges - (baseline.NonBypassableCharges-system.NonBypassableCharges)*remaining
+	return out
+}
+
+// npv discounts the cash flows, where flows[0] happens today.
+func npv(flows []float64, rate float64) float64 {
+	total := 0.0
+	for y, f := range flows {
+		total += f / math.Pow(1+rate, float64(y))
+	}
+	return total
+}
+
+// irr finds the rate at which the NPV of the flows is 0 by bisection.
+func irr(flows []float64) float64 {
+	low, high := -0.99, 10.0
+	if npv(flows, low)*npv(flows, high) > 0 {
+		return math.NaN()
+	}
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		if npv(flows, low)*npv(flows, mid) <= 0 {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	return (low + high) / 2
+}
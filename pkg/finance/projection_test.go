@@ -0,0 +1,60 @@
+package finance
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProject_NoEscalation_ConstantSavings(t *testing.T) {
+	baseline := YearCosts{EnergyCost: 1000, FixedCharges: 100}
+	system := YearCosts{EnergyCost: 0, FixedCharges: 100}
+
+	got := Project(baseline, system, ProjectionOptions{Years: 10, SystemCost: 4500, DiscountRate: 0.05})
+
+	assert.Len(t, got.Years, 10)
+	assert.Equal(t, 1000.0, got.Years[9].NetCashFlow)
+	assert.Equal(t, 5, got.PaybackYear)
+	assert.InDelta(t, 5500.0, got.Years[9].Cumulative, 0.0001)
+	// Annuity of 1000 for 10 years at 5%.
+	assert.InDelta(t, 7721.73-4500, got.NPV, 0.01)
+	assert.InDelta(t, 0.1796, got.IRR, 0.0001)
+}
+
+func TestProject_EscalationAndDegradation(t *testing.T) {
+	baseline := YearCosts{EnergyCost: 1000, FixedCharges: 100, NonBypassableCharges: 50}
+	system := YearCosts{EnergyCost: 0, FixedCharges: 100, NonBypassableCharges: 10}
+	opts := ProjectionOptions{
+		Years:         2,
+		Escalation:    Escalation{Energy: 0.1, Fixed: 0.5, NonBypassable: 0.1},
+		PVDegradation: 0.1,
+		SystemCost:    1000,
+	}
+
+	got := Project(baseline, system, opts)
+
+	assert.InDelta(t, 1040.0, got.Years[0].NetCashFlow, 0.0001)
+	// Year 2: savings are 90% of the year 1 savings, escalated 10%.
+	assert.InDelta(t, 1040*0.9*1.1, got.Years[1].NetCashFlow, 0.0001)
+	assert.InDelta(t, 1100+150+55, got.Years[1].BaselineCost, 0.0001)
+	assert.Equal(t, 1, got.PaybackYear)
+}
+
+func TestProject_NeverPaysBack(t *testing.T) {
+	costs := YearCosts{EnergyCost: 100}
+
+	got := Project(costs, costs, ProjectionOptions{Years: 5, SystemCost: 100, MaintenancePerYear: 10})
+
+	assert.Equal(t, 0, got.PaybackYear)
+	assert.InDelta(t, -150.0, got.Years[4].Cumulative, 0.0001)
+	assert.True(t, math.IsNaN(got.IRR))
+}
+
+func TestYearCosts_Scale(t *testing.T) {
+	c := YearCosts{EnergyCost: 1, FixedCharges: 2, NonBypassableCharges: 3, OtherCharges: 4}
+
+	got := c.Scale(2)
+
+	assert.Equal(t, 20.0, got.Total())
+}
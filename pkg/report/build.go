@@ -0,0 +1,499 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/anomaly"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/kodek/sce-greenbutton/pkg/ev"
+	"github.com/kodek/sce-greenbutton/pkg/finance"
+	"github.com/kodek/sce-greenbutton/pkg/simulator"
+	"github.com/kodek/sce-greenbutton/pkg/solar"
+	"github.com/kodek/sce-greenbutton/pkg/weather"
+)
+
+const dayFormat = "2006-01-02"
+const monthFormat = "2006-01"
+
+// NewQuality summarizes a quality report of the given number of readings.
+func NewQuality(q analyzer.QualityReport, readings int) *Quality {
+	out := &Quality{
+		Readings:            readings,
+		IntervalMinutes:     q.Interval.Minutes(),
+		MissingIntervals:    q.MissingIntervals(),
+		Gaps:                make([]Gap, 0, len(q.Gaps)),
+		DuplicateTimestamps: q.DuplicateTimestamps,
+		PartialDays:         make([]PartialDay, 0, len(q.PartialDays)),
+	}
+	if out.DuplicateTimestamps == nil {
+		out.DuplicateTimestamps = make([]time.Time, 0)
+	}
+	for _, g := range q.Gaps {
+		out.Gaps = append(out.Gaps, Gap{Start: g.Start, End: g.End})
+	}
+	for _, p := range q.PartialDays {
+		out.PartialDays = append(out.PartialDays, PartialDay{Day: p.Day.Format(dayFormat), Intervals: p.Intervals, ExpectedIntervals: p.ExpectedIntervals})
+	}
+	return out
+}
+
+// NewConflicts converts the conflicts found while merging downloads.
+func NewConflicts(conflicts []csvparser.Conflict) []Conflict {
+	out := make([]Conflict, 0, len(conflicts))
+	for _, c := range conflicts {
+		values := make([]ConflictValue, 0, len(c.Values))
+		for _, v := range c.Values {
+			values = append(values, ConflictValue{Source: v.Source, UsageKwh: v.UsageKwh, Quality: v.ReadingQuality.String()})
+		}
+		out = append(out, Conflict{
+			Meter:    c.Meter.String(),
+			Start:    c.StartTime,
+			KeptKwh:  c.Chosen.UsageKwh,
+			KeptFrom: c.Chosen.Source,
+			Readings: values,
+		})
+	}
+	return out
+}
+
+// NewDataset summarizes the readings and their aggregation into hours and days.
+func NewDataset(csv csvparser.CsvFile, hours []analyzer.UsageHour, days []analyzer.UsageDay) (*Dataset, error) {
+	if len(hours) == 0 || len(days) == 0 {
+		return nil, fmt.Errorf("no readings")
+	}
+	completeness := analyzer.Completeness(days)
+	out := &Dataset{
+		Readings:     len(csv),
+		Days:         completeness.Days,
+		CompleteDays: completeness.CompleteDays,
+		Coverage:     completeness.Coverage(),
+		FirstHour:    hours[0].StartTime(),
+		LastHour:     hours[len(hours)-1].StartTime(),
+		StartDay:     days[0].Day.Format(dayFormat),
+		EndDay:       days[len(days)-1].EndTime().Format(dayFormat),
+		MonthlyPeaks: make([]Peak, 0),
+	}
+	for _, row := range csv {
+		out.TotalUsageKwh += row.UsageKwh
+	}
+	peaks, err := analyzer.PeakDemandByMonth(csv)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range peaks {
+		out.MonthlyPeaks = append(out.MonthlyPeaks, Peak{Month: p.Start.Format(monthFormat), Kw: p.Kw, At: p.At})
+	}
+	loadDuration := analyzer.LoadDurationCurve(csv)
+	if len(loadDuration) > 0 {
+		// The demand exceeded 1% of the time is less sensitive to a single spike than the maximum.
+		out.Demand1PercentKw = loadDuration[len(loadDuration)/100].Kw
+	}
+	return out, nil
+}
+
+// NewBill bills the days on the plan. If monthly is set, it includes the statement of every calendar month.
+func NewBill(days []analyzer.UsageDay, plan costcalculator.TouPlan, monthly bool) Bill {
+	summary := costcalculator.CalculateWithTouPlan(days, plan)
+	completeness := summary.Completeness()
+	nbc := summary.NonBypassableCharges()
+	basicCharge := summary.TotalBasicCharge()
+	taxes := summary.Taxes()
+	baselineCredit := summary.BaselineCredit()
+	out := Bill{
+		Plan:                    plan.Name(),
+		EnergyImportedKwh:       summary.EnergyImported(),
+		EnergyExportedKwh:       math.Abs(summary.EnergyExported()),
+		NetUsageKwh:             summary.NetEnergyUsage(),
+		AverageDailyUsageKwh:    summary.AverageDailyUsage(),
+		Coverage:                completeness.Coverage(),
+		LowQualityShare:         summary.LowQualityShare(),
+		UsageByPeriod:           usageByPeriod(&summary, plan),
+		NonBypassableCharges:    nbc,
+		MaxBaselineAllowanceKwh: summary.MaxBaselineAllowance(),
+		BaselineCredit:          baselineCredit,
+		BasicCharge:             basicCharge,
+		Taxes:                   taxes,
+		MonthlyFees:             nbc + basicCharge + taxes,
+		NemTrueUp:               summary.NetMeteredCostNoBaseline() + baselineCredit,
+		DemandCharges:           summary.DemandCharges(),
+		TotalCost:               summary.TotalCost(),
+	}
+	if monthly {
+		out.Monthly = MonthlyStatements(days, plan)
+	}
+	return out
+}
+
+// usageByPeriod returns the usage of each TOU period that has readings, in the order of costcalculator.CostPeriod.
+func usageByPeriod(summary *costcalculator.TouBillSummary, plan costcalculator.TouPlan) []PeriodUsage {
+	usage := summary.UsageByPeriod()
+	imported, exported := summary.ImportedByPeriod(), summary.ExportedByPeriod()
+	out := make([]PeriodUsage, 0, len(usage))
+	for period := costcalculator.SummerSuperOffPeak; period <= costcalculator.WinterOnPeak; period++ {
+		kwh, ok := usage[period]
+		if !ok {
+			continue
+		}
+		rate := plan.Cost(period)
+		out = append(out, PeriodUsage{
+			Period:      period.Name(),
+			UsageKwh:    kwh,
+			ImportedKwh: imported[period],
+			ExportedKwh: math.Abs(exported[period]),
+			RatePerKwh:  rate,
+			Cost:        kwh * rate,
+		})
+	}
+	return out
+}
+
+// MonthlyStatements bills each calendar month of the days on its own.
+func MonthlyStatements(days []analyzer.UsageDay, plan costcalculator.TouPlan) []MonthlyStatement {
+	out := make([]MonthlyStatement, 0)
+	for start := 0; start < len(days); {
+		end := start
+		for end < len(days) && days[end].Day.Year() == days[start].Day.Year() && days[end].Day.Month() == days[start].Day.Month() {
+			end++
+		}
+		summary := costcalculator.CalculateWithTouPlan(days[start:end], plan)
+		s := MonthlyStatement{
+			Month:                days[start].Day.Format(monthFormat),
+			Days:                 end - start,
+			EnergyImportedKwh:    summary.EnergyImported(),
+			EnergyExportedKwh:    math.Abs(summary.EnergyExported()),
+			NetUsageKwh:          summary.NetEnergyUsage(),
+			EnergyCharges:        summary.NetMeteredCostNoBaseline(),
+			BaselineCredit:       summary.BaselineCredit(),
+			BasicCharge:          summary.TotalBasicCharge(),
+			NonBypassableCharges: summary.NonBypassableCharges(),
+			Taxes:                summary.Taxes(),
+			DemandCharges:        summary.DemandCharges(),
+		}
+		for _, m := range summary.MonthlyDemand() {
+			s.MaxDemandKw = math.Max(s.MaxDemandKw, m.MaxKw)
+		}
+		s.Total = s.EnergyCharges + s.BaselineCredit + s.BasicCharge + s.NonBypassableCharges + s.Taxes + s.DemandCharges
+		out = append(out, s)
+		start = end
+	}
+	return out
+}
+
+// NewDomesticBill converts a bill on the tiered Domestic plan.
+func NewDomesticBill(d costcalculator.DomesticBreakdown) *DomesticBill {
+	return &DomesticBill{
+		Days:                  d.Days,
+		BaselineAllocationKwh: d.BaselineAllocationKwh,
+		UsageKwh:              d.UsageKwh,
+		Tier1UsageKwh:         d.Tier1UsageKwh,
+		Tier2UsageKwh:         d.Tier2UsageKwh,
+		Tier3UsageKwh:         d.Tier3UsageKwh,
+		EnergyCost:            d.NemCost,
+		MinimumCharges:        d.MinCharges,
+		DailyCharges:          d.DailyCharges,
+		TotalCost:             d.NemCost + d.MinCharges + d.DailyCharges,
+	}
+}
+
+// NewProfile returns the baseload, evening ramp and load profiles of the days.
+func NewProfile(days []analyzer.UsageDay) *Profile {
+	ramp := analyzer.EveningRampStats(days)
+	out := &Profile{
+		BaseloadKw:  analyzer.BaseloadKw(days),
+		EveningRamp: EveningRamp{Days: ramp.Days, MeanKw: ramp.MeanKw, P90Kw: ramp.P90Kw, PeakShare: ramp.MeanPeakShare},
+		Shapes:      make([]LoadProfile, 0),
+	}
+	for _, p := range analyzer.LoadProfiles(days) {
+		shape := LoadProfile{
+			Profile:   p.Key.String(),
+			Season:    p.Key.Season.String(),
+			DayType:   p.Key.DayType.String(),
+			Days:      p.Days,
+			PeakHour:  p.PeakHour(),
+			PeakShare: p.WindowShare(16, 21),
+			Hours:     make([]HourStats, 0, len(p.Hours)),
+		}
+		for h, s := range p.Hours {
+			shape.Hours = append(shape.Hours, HourStats{Hour: h, Samples: s.Samples, MeanKw: s.Mean, P10Kw: s.P10, P50Kw: s.P50, P90Kw: s.P90})
+		}
+		out.Shapes = append(out.Shapes, shape)
+	}
+	return out
+}
+
+// NewHeatmap returns the hourly usage of each day.
+func NewHeatmap(days []analyzer.UsageDay) []DayUsage {
+	out := make([]DayUsage, 0, len(days))
+	for _, d := range days {
+		day := DayUsage{Day: d.Day.Format(dayFormat), HourlyKwh: make([]float64, 24)}
+		for _, h := range d.DataPoints {
+			day.HourlyKwh[h.StartTime().Hour()] += h.UsageKwh()
+		}
+		out = append(out, day)
+	}
+	return out
+}
+
+// NewUsageCharts returns the monthly and daily usage of the days, and their average week colored by the plan's TOU
+// periods.
+func NewUsageCharts(days []analyzer.UsageDay, plan costcalculator.TouPlan) *UsageCharts {
+	out := &UsageCharts{Plan: plan.Name(), Monthly: make([]MonthUsage, 0), Daily: make([]DailyUsage, 0, len(days))}
+	var sums [7][24]float64
+	var counts [7][24]int
+	var periods [7][24]map[costcalculator.CostPeriod]int
+	for _, d := range days {
+		month := d.Day.Format(monthFormat)
+		if len(out.Monthly) == 0 || out.Monthly[len(out.Monthly)-1].Month != month {
+			out.Monthly = append(out.Monthly, MonthUsage{Month: month})
+		}
+		out.Monthly[len(out.Monthly)-1].UsageKwh += d.UsageKwh
+		out.Daily = append(out.Daily, DailyUsage{Day: d.Day.Format(dayFormat), UsageKwh: d.UsageKwh})
+		for _, h := range d.DataPoints {
+			start := h.StartTime()
+			weekday, hour := start.Weekday(), start.Hour()
+			sums[weekday][hour] += h.UsageKwh()
+			counts[weekday][hour]++
+			if periods[weekday][hour] == nil {
+				periods[weekday][hour] = make(map[costcalculator.CostPeriod]int)
+			}
+			periods[weekday][hour][costcalculator.PeriodAt(start, plan)]++
+		}
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		usage := WeekdayUsage{Weekday: weekday.String(), Hours: make([]WeekHour, 0, 24)}
+		for hour := 0; hour < 24; hour++ {
+			h := WeekHour{Hour: hour}
+			if counts[weekday][hour] > 0 {
+				h.MeanKwh = sums[weekday][hour] / float64(counts[weekday][hour])
+				h.Period = mostCommonPeriod(periods[weekday][hour])
+			}
+			usage.Hours = append(usage.Hours, h)
+		}
+		out.Week = append(out.Week, usage)
+	}
+	return out
+}
+
+// mostCommonPeriod returns the name of the period with the most hours, preferring earlier periods on ties.
+func mostCommonPeriod(counts map[costcalculator.CostPeriod]int) string {
+	best, bestCount := costcalculator.SummerSuperOffPeak, 0
+	for period := costcalculator.SummerSuperOffPeak; period <= costcalculator.WinterOnPeak; period++ {
+		if counts[period] > bestCount {
+			best, bestCount = period, counts[period]
+		}
+	}
+	return best.Name()
+}
+
+// NewWeatherModel converts a fitted model, its usage in the normal weather, the savings after the baseline if any,
+// and the unusual days.
+func NewWeatherModel(m weather.Model, normal []weather.DegreeDays, savings *weather.Savings, outliers []weather.Outlier) *WeatherModel {
+	out := &WeatherModel{
+		Days:                m.Days,
+		BaseKwh:             m.BaseKwh,
+		HeatingKwhPerDegree: m.HeatingKwhPerDegree,
+		CoolingKwhPerDegree: m.CoolingKwhPerDegree,
+		RSquared:            m.RSquared,
+		NormalizedKwh:       m.NormalizedUsage(normal),
+		NormalizedDays:      len(normal),
+		UnusualDays:         make([]UnusualDay, 0, len(outliers)),
+	}
+	if savings != nil {
+		out.Savings = &WeatherSavings{Days: savings.Days, SavingsKwh: savings.SavingsKwh(), SavingsShare: savings.SavingsShare()}
+	}
+	for _, o := range outliers {
+		out.UnusualDays = append(out.UnusualDays, UnusualDay{Day: o.Day.Format(dayFormat), ActualKwh: o.ActualKwh, PredictedKwh: o.PredictedKwh})
+	}
+	return out
+}
+
+func NewAnomalies(anomalies []anomaly.Anomaly) []Anomaly {
+	out := make([]Anomaly, 0, len(anomalies))
+	for _, a := range anomalies {
+		out = append(out, Anomaly{
+			Kind:        a.Kind.String(),
+			Start:       a.Start,
+			End:         a.End,
+			ActualKwh:   a.ActualKwh,
+			ExpectedKwh: a.ExpectedKwh,
+			ZScore:      a.ZScore,
+			CostImpact:  a.CostImpact,
+		})
+	}
+	return out
+}
+
+func NewEvSessions(sessions []ev.Session) []EvSession {
+	out := make([]EvSession, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, EvSession{
+			Start:       s.Start,
+			End:         s.End,
+			EnergyKwh:   s.EnergyKwh,
+			AverageKw:   s.AverageKw(),
+			Cost:        s.Cost,
+			ShiftedCost: s.ShiftedCost,
+			Savings:     s.Savings(),
+		})
+	}
+	return out
+}
+
+// NewSolarHealth converts the low production days and weeks and the year-over-year changes.
+func NewSolarHealth(lowDays []solar.Outage, lowWeeks []solar.Outage, years []solar.YearOverYear) *SolarHealth {
+	out := &SolarHealth{
+		LowDays:      newSolarOutages(lowDays),
+		LowWeeks:     newSolarOutages(lowWeeks),
+		YearOverYear: make([]SolarYearChange, 0, len(years)),
+	}
+	for _, y := range years {
+		out.YearOverYear = append(out.YearOverYear, SolarYearChange{FromYear: y.FromYear, ToYear: y.ToYear, Change: y.Change, Months: y.Months})
+	}
+	return out
+}
+
+func newSolarOutages(outages []solar.Outage) []SolarOutage {
+	out := make([]SolarOutage, 0, len(outages))
+	for _, o := range outages {
+		out = append(out, SolarOutage{Start: o.Start.Format(dayFormat), ProductionKwh: o.ProductionKwh, ExpectedKwh: o.ExpectedKwh})
+	}
+	return out
+}
+
+// NewUncertainty summarizes the resampled plan costs.
+func NewUncertainty(result *simulator.MonteCarloResult, samples int) *Uncertainty {
+	out := &Uncertainty{Samples: samples, Plans: make([]PlanCost, 0), Comparisons: make([]PlanComparison, 0)}
+	cheapest := result.ProbabilityCheapest()
+	for i, plan := range result.Plans {
+		cost := result.Cost(i)
+		out.Plans = append(out.Plans, PlanCost{Plan: plan.Name(), MeanCost: cost.Mean, P5Cost: cost.P5, P95Cost: cost.P95, ProbabilityCheapest: cheapest[i]})
+	}
+	for a := range result.Plans {
+		for b := a + 1; b < len(result.Plans); b++ {
+			c := result.Compare(a, b)
+			out.Comparisons = append(out.Comparisons, PlanComparison{
+				PlanA:               result.Plans[a].Name(),
+				PlanB:               result.Plans[b].Name(),
+				ProbabilityACheaper: c.ProbabilityABeatsB,
+				MeanDifference:      c.Difference.Mean,
+				P5Difference:        c.Difference.P5,
+				P95Difference:       c.Difference.P95,
+			})
+		}
+	}
+	return out
+}
+
+func NewElectrification(r *simulator.ElectrificationReport) *Electrification {
+	out := &Electrification{HeatPumpKwh: r.Added.HeatPumpKwh, WaterHeaterKwh: r.Added.WaterHeaterKwh, Plans: make([]ElectrificationCost, 0)}
+	for _, result := range r.Results {
+		out.Plans = append(out.Plans, ElectrificationCost{
+			Plan:         result.Plan.Name(),
+			CostBefore:   result.Before.TotalCost(),
+			CostAfter:    result.After.TotalCost(),
+			CostIncrease: result.CostIncrease(),
+		})
+	}
+	return out
+}
+
+// NewSizing keeps the top candidates by net present value and by payback.
+func NewSizing(candidates []simulator.SizingCandidate, top int) *Sizing {
+	return &Sizing{
+		ByNpv:     newSizingCandidates(candidates, top),
+		ByPayback: newSizingCandidates(simulator.ByPayback(candidates), top),
+	}
+}
+
+func newSizingCandidates(candidates []simulator.SizingCandidate, top int) []SizingCandidate {
+	out := make([]SizingCandidate, 0, top)
+	for i := 0; i < top && i < len(candidates); i++ {
+		c := candidates[i]
+		out = append(out, SizingCandidate{
+			PVKw:          c.PVKw,
+			BatteryKwh:    c.BatteryKwh,
+			Plan:          c.Plan.Name(),
+			UpfrontCost:   c.UpfrontCost,
+			AnnualSavings: c.AnnualSavings,
+			PaybackYears:  finite(c.PaybackYears),
+			NPV:           c.NPV,
+		})
+	}
+	return out
+}
+
+// NewProjection converts the projection of a system of the given size and cost on the plan.
+func NewProjection(plan costcalculator.TouPlan, pvKw float64, batteryKwh float64, systemCost float64, p finance.Projection) *Projection {
+	out := &Projection{
+		Plan:       plan.Name(),
+		PVKw:       pvKw,
+		BatteryKwh: batteryKwh,
+		SystemCost: systemCost,
+		Years:      make([]ProjectionYear, 0, len(p.Years)),
+		NPV:        p.NPV,
+		IRR:        finite(p.IRR),
+	}
+	for _, y := range p.Years {
+		out.Years = append(out.Years, ProjectionYear{Year: y.Year, CostWithout: y.BaselineCost, CostWith: y.SystemCost, NetCashFlow: y.NetCashFlow, Cumulative: y.Cumulative})
+	}
+	if p.PaybackYear > 0 {
+		year := p.PaybackYear
+		out.PaybackYear = &year
+	}
+	return out
+}
+
+// NewDeviceCosts converts the device costs on the plan.
+func NewDeviceCosts(costs []costcalculator.DeviceCost, plan costcalculator.TouPlan) []DeviceCost {
+	out := make([]DeviceCost, 0, len(costs))
+	for _, c := range costs {
+		out = append(out, DeviceCost{
+			Device:      c.Name,
+			Plan:        plan.Name(),
+			Days:        c.Days,
+			EnergyKwh:   c.EnergyKwh,
+			TotalCost:   c.TotalCost(),
+			MonthlyCost: c.MonthlyCost(),
+			OnPeakShare: c.OnPeakShare(),
+		})
+	}
+	return out
+}
+
+// NewPlan describes the plan. The rates are of the periods that the plan uses in a year.
+func NewPlan(plan costcalculator.TouPlan) Plan {
+	out := Plan{
+		Name:                  plan.Name(),
+		DailyBasicCharge:      plan.DailyBasicCharge(),
+		MinimumDailyCharge:    plan.MinimumDailyCharge(),
+		HasBaselineAllocation: plan.HasBaselineAllocation(),
+		Rates:                 make([]PeriodRate, 0),
+	}
+	if demand, ok := plan.(costcalculator.DemandChargePlan); ok {
+		out.MonthlyMaxDemandChargePerKw = demand.MonthlyMaxDemandCharge()
+		out.OnPeakDemandChargePerKw = demand.OnPeakDemandCharge()
+	}
+	used := make(map[costcalculator.CostPeriod]bool)
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for t := start; t.Before(start.AddDate(1, 0, 0)); t = t.Add(time.Hour) {
+		used[costcalculator.PeriodAt(t, plan)] = true
+	}
+	for period := costcalculator.SummerSuperOffPeak; period <= costcalculator.WinterOnPeak; period++ {
+		if used[period] {
+			out.Rates = append(out.Rates, PeriodRate{Period: period.Name(), RatePerKwh: plan.Cost(period)})
+		}
+	}
+	return out
+}
+
+// finite returns nil for infinite and NaN values, which JSON can't represent.
+func finite(v float64) *float64 {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return nil
+	}
+	return &v
+}
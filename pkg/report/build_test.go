@@ -0,0 +1,175 @@
+package report
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/kodek/sce-greenbutton/pkg/finance"
+	"github.com/stretchr/testify/assert"
+)
+
+// summerWeekday is a Wednesday.
+var summerWeekday = time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+
+func toDaysOrDie(t *testing.T, rows csvparser.CsvFile) ([]analyzer.UsageHour, []analyzer.UsageDay) {
+	hours, err := analyzer.AggregateIntoHourWindows(rows)
+	assert.NoError(t, err)
+	days, err := analyzer.SplitByDay(hours)
+	assert.NoError(t, err)
+	return hours, days
+}
+
+func TestNewBill_UsageByPeriodInPeriodOrder(t *testing.T) {
+	_, days := toDaysOrDie(t, csvparser.CsvFile{
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(17*time.Hour), 1),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(10*time.Hour), 2),
+	})
+	plan := costcalculator.NewTouDPrime()
+
+	got := NewBill(days, plan, false)
+
+	assert.Equal(t, "TOU-D-PRIME", got.Plan)
+	assert.Len(t, got.UsageByPeriod, 2)
+	assert.Equal(t, "Summer - Off-Peak", got.UsageByPeriod[0].Period)
+	assert.Equal(t, "Summer - On-Peak", got.UsageByPeriod[1].Period)
+	assert.InEpsilon(t, 2*plan.Cost(costcalculator.SummerOffPeak), got.UsageByPeriod[0].Cost, 0.0001)
+	assert.Equal(t, 2.0, got.UsageByPeriod[0].ImportedKwh)
+	assert.Equal(t, 0.0, got.UsageByPeriod[0].ExportedKwh)
+	assert.Nil(t, got.Monthly)
+}
+
+func TestNewBill_ExportsArePositive(t *testing.T) {
+	_, days := toDaysOrDie(t, csvparser.CsvFile{
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(12*time.Hour), -3),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(18*time.Hour), 1),
+	})
+
+	got := NewBill(days, costcalculator.NewTouDPrime(), false)
+
+	assert.InEpsilon(t, 3.0, got.EnergyExportedKwh, 0.0001)
+	assert.InEpsilon(t, 1.0, got.EnergyImportedKwh, 0.0001)
+	assert.InEpsilon(t, -2.0, got.NetUsageKwh, 0.0001)
+}
+
+func TestNewBill_Monthly_OneStatementPerMonth(t *testing.T) {
+	_, days := toDaysOrDie(t, csvparser.CsvFile{
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(10*time.Hour), 2),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.AddDate(0, 0, 1).Add(10*time.Hour), 1),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.AddDate(0, 1, 0).Add(10*time.Hour), 3),
+	})
+	plan := costcalculator.WithDemandCharges(costcalculator.NewTouDPrime(), 10, 0)
+
+	got := NewBill(days, plan, true)
+
+	assert.Len(t, got.Monthly, 2)
+	july, august := got.Monthly[0], got.Monthly[1]
+	assert.Equal(t, "2020-07", july.Month)
+	assert.Equal(t, 2, july.Days)
+	assert.InEpsilon(t, 3.0, july.NetUsageKwh, 0.0001)
+	assert.InEpsilon(t, 8.0, july.MaxDemandKw, 0.0001)
+	assert.InEpsilon(t, 80.0, july.DemandCharges, 0.0001)
+	assert.Equal(t, "2020-08", august.Month)
+	assert.InEpsilon(t, 12.0, august.MaxDemandKw, 0.0001)
+	assert.InEpsilon(t, july.EnergyCharges+july.BaselineCredit+july.BasicCharge+july.NonBypassableCharges+july.Taxes+july.DemandCharges, july.Total, 0.0001)
+	assert.InEpsilon(t, got.DemandCharges, july.DemandCharges+august.DemandCharges, 0.0001)
+}
+
+func TestNewHeatmap_SumsEachHour(t *testing.T) {
+	_, days := toDaysOrDie(t, csvparser.CsvFile{
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(13*time.Hour), 1),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(13*time.Hour+15*time.Minute), -3),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.AddDate(0, 0, 1), 2),
+	})
+
+	got := NewHeatmap(days)
+
+	assert.Len(t, got, 2)
+	assert.Equal(t, "2020-07-01", got[0].Day)
+	assert.Len(t, got[0].HourlyKwh, 24)
+	assert.Equal(t, -2.0, got[0].HourlyKwh[13])
+	assert.Equal(t, 0.0, got[0].HourlyKwh[12])
+	assert.Equal(t, 2.0, got[1].HourlyKwh[0])
+}
+
+func TestNewUsageCharts_AveragesWeekAndSumsMonths(t *testing.T) {
+	// Two Wednesdays a week apart, and a Thursday in the next month.
+	_, days := toDaysOrDie(t, csvparser.CsvFile{
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(17*time.Hour), 1),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.AddDate(0, 0, 7).Add(17*time.Hour), 3),
+		csvparser.NewRowWith15MinuteDuration(time.Date(2020, 8, 6, 10, 0, 0, 0, time.UTC), 2),
+	})
+
+	got := NewUsageCharts(days, costcalculator.NewTouDPrime())
+
+	assert.Equal(t, "TOU-D-PRIME", got.Plan)
+	assert.Equal(t, []MonthUsage{{Month: "2020-07", UsageKwh: 4}, {Month: "2020-08", UsageKwh: 2}}, got.Monthly)
+	assert.Len(t, got.Daily, 3)
+	assert.Len(t, got.Week, 7)
+	assert.Equal(t, "Sunday", got.Week[0].Weekday)
+	wednesday := got.Week[time.Wednesday]
+	assert.Equal(t, WeekHour{Hour: 17, MeanKwh: 2, Period: "Summer - On-Peak"}, wednesday.Hours[17])
+	assert.Equal(t, WeekHour{Hour: 16}, wednesday.Hours[16])
+	assert.Equal(t, "Summer - Off-Peak", got.Week[time.Thursday].Hours[10].Period)
+}
+
+func TestNewQuality_ListsGaps(t *testing.T) {
+	rows := csvparser.CsvFile{
+		csvparser.NewRowWith15MinuteDuration(summerWeekday, 1),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(15*time.Minute), 1),
+		csvparser.NewRowWith15MinuteDuration(summerWeekday.Add(time.Hour), 1),
+	}
+
+	got := NewQuality(analyzer.CheckQuality(rows), len(rows))
+
+	assert.Equal(t, 3, got.Readings)
+	assert.Equal(t, 15.0, got.IntervalMinutes)
+	assert.Equal(t, []Gap{{Start: summerWeekday.Add(30 * time.Minute), End: summerWeekday.Add(time.Hour)}}, got.Gaps)
+	assert.Equal(t, 2, got.MissingIntervals)
+	assert.NotNil(t, got.DuplicateTimestamps)
+}
+
+func TestNewDataset_NoReadings_Error(t *testing.T) {
+	_, err := NewDataset(csvparser.CsvFile{}, nil, nil)
+
+	assert.Error(t, err)
+}
+
+func TestNewProjection_NoPaybackOrIrr_Null(t *testing.T) {
+	p := finance.Projection{Years: []finance.YearlyCashFlow{{Year: 1}}, IRR: math.NaN()}
+
+	got := NewProjection(costcalculator.NewTouDPrime(), 5, 0, 10000, p)
+
+	assert.Nil(t, got.PaybackYear)
+	assert.Nil(t, got.IRR)
+	assert.Len(t, got.Years, 1)
+}
+
+func TestNewProjection_Payback(t *testing.T) {
+	p := finance.Projection{PaybackYear: 7, IRR: 0.08}
+
+	got := NewProjection(costcalculator.NewTouDPrime(), 5, 0, 10000, p)
+
+	assert.Equal(t, 7, *got.PaybackYear)
+	assert.Equal(t, 0.08, *got.IRR)
+}
+
+func TestNewPlan_ListsUsedPeriods(t *testing.T) {
+	got := NewPlan(costcalculator.NewTouDAPlan())
+
+	assert.Equal(t, "TOU-D-A", got.Name)
+	assert.True(t, got.HasBaselineAllocation)
+	assert.Len(t, got.Rates, 6)
+	assert.Equal(t, PeriodRate{Period: "Summer - Super Off-Peak", RatePerKwh: 0.16}, got.Rates[0])
+	assert.Equal(t, 0.0, got.MonthlyMaxDemandChargePerKw)
+}
+
+func TestNewPlan_DemandCharges(t *testing.T) {
+	got := NewPlan(costcalculator.WithDemandCharges(costcalculator.NewTouDPrime(), 10, 5))
+
+	assert.Equal(t, 10.0, got.MonthlyMaxDemandChargePerKw)
+	assert.Equal(t, 5.0, got.OnPeakDemandChargePerKw)
+}
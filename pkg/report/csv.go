@@ -0,0 +1,132 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CsvHeader is the header of the CSV output.
+var CsvHeader = []string{"table", "key", "field", "value"}
+
+// keySeparator joins the keys of nested rows, such as a meter and a plan.
+const keySeparator = " / "
+
+// WriteCSV writes the document in long format, with one value per row, which spreadsheets can pivot:
+//
+//	table,key,field,value
+//	meters.bills,CA FOO ST / TOU-D-PRIME,total_cost,912.4
+//
+// The table is the path of the JSON object that holds the value, and the field is its JSON name. The key identifies
+// the object among its siblings: the meter, plan, period, month or similar, or its index for lists without one.
+// Keys of nested objects are joined with " / ". Null values are empty.
+func WriteCSV(out io.Writer, doc *Document) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(CsvHeader); err != nil {
+		return err
+	}
+	flattenStruct(reflect.ValueOf(*doc), "document", nil, func(table string, key []string, field string, value string) {
+		_ = w.Write([]string{table, strings.Join(key, keySeparator), field, value})
+	})
+	w.Flush()
+	return w.Error()
+}
+
+type emitFunc func(table string, key []string, field string, value string)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func flattenStruct(v reflect.Value, table string, key []string, emit emitFunc) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, omitEmpty := jsonName(t.Field(i))
+		if name == "-" {
+			continue
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				if !omitEmpty {
+					emit(table, key, name, "")
+				}
+				continue
+			}
+			field = field.Elem()
+		}
+		child := name
+		if table != "document" {
+			child = table + "." + name
+		}
+		switch {
+		case isScalar(field):
+			if !omitEmpty || !field.IsZero() {
+				emit(table, key, name, formatScalar(field))
+			}
+		case field.Kind() == reflect.Struct:
+			flattenStruct(field, child, key, emit)
+		case field.Kind() == reflect.Slice:
+			for j := 0; j < field.Len(); j++ {
+				elem := field.Index(j)
+				elemKey := append(append([]string{}, key...), elementKey(elem, j))
+				if isScalar(elem) {
+					emit(table, elemKey, name, formatScalar(elem))
+				} else {
+					flattenStruct(elem, child, elemKey, emit)
+				}
+			}
+		}
+	}
+}
+
+// jsonName returns the JSON name of a field and whether it is omitted when empty.
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := strings.Split(f.Tag.Get("json"), ",")
+	name := tag[0]
+	if name == "" {
+		name = f.Name
+	}
+	for _, option := range tag[1:] {
+		if option == "omitempty" {
+			return name, true
+		}
+	}
+	return name, false
+}
+
+// elementKey returns the value of the field tagged `report:"key"`, or the index if there is none.
+func elementKey(v reflect.Value, index int) string {
+	if v.Kind() == reflect.Struct && v.Type() != timeType {
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).Tag.Get("report") == "key" {
+				return formatScalar(v.Field(i))
+			}
+		}
+	}
+	return strconv.Itoa(index)
+}
+
+func isScalar(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64, reflect.String:
+		return true
+	}
+	return v.Type() == timeType
+}
+
+func formatScalar(v reflect.Value) string {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339)
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+	return v.String()
+}
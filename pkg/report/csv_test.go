@@ -0,0 +1,69 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readCsvOrDie(t *testing.T, doc *Document) [][]string {
+	var out bytes.Buffer
+	assert.NoError(t, WriteCSV(&out, doc))
+	rows, err := csv.NewReader(&out).ReadAll()
+	assert.NoError(t, err)
+	return rows
+}
+
+func TestWriteCSV_NestedKeys(t *testing.T) {
+	doc := NewDocument("bill")
+	doc.Meters = append(doc.Meters, Section{
+		Meter: "Meter 1",
+		Bills: []Bill{{
+			Plan:          "TOU-D-PRIME",
+			UsageByPeriod: []PeriodUsage{{Period: "Summer - On-Peak", UsageKwh: 1.5}},
+			TotalCost:     12.5,
+		}},
+	})
+
+	rows := readCsvOrDie(t, doc)
+
+	assert.Equal(t, CsvHeader, rows[0])
+	assert.Contains(t, rows, []string{"document", "", "command", "bill"})
+	assert.Contains(t, rows, []string{"meters.bills", "Meter 1 / TOU-D-PRIME", "total_cost", "12.5"})
+	assert.Contains(t, rows, []string{"meters.bills.usage_by_period", "Meter 1 / TOU-D-PRIME / Summer - On-Peak", "usage_kwh", "1.5"})
+}
+
+func TestWriteCSV_ListsWithoutKeyUseIndex(t *testing.T) {
+	doc := NewDocument("summary")
+	doc.Meters = append(doc.Meters, Section{
+		Meter:      "1",
+		EvSessions: []EvSession{{EnergyKwh: 10}, {EnergyKwh: 20}},
+	})
+
+	rows := readCsvOrDie(t, doc)
+
+	assert.Contains(t, rows, []string{"meters.ev_sessions", "1 / 0", "energy_kwh", "10"})
+	assert.Contains(t, rows, []string{"meters.ev_sessions", "1 / 1", "energy_kwh", "20"})
+}
+
+func TestWriteCSV_OmittedSectionsHaveNoRows(t *testing.T) {
+	doc := NewDocument("profile")
+	doc.Meters = append(doc.Meters, Section{Meter: "1"})
+
+	rows := readCsvOrDie(t, doc)
+
+	for _, row := range rows[1:] {
+		assert.Contains(t, []string{"document", "meters"}, row[0])
+	}
+}
+
+func TestWriteCSV_NullIsEmpty(t *testing.T) {
+	doc := NewDocument("simulate")
+	doc.Meters = append(doc.Meters, Section{Meter: "1", Projection: &Projection{Plan: "TOU-D-PRIME"}})
+
+	rows := readCsvOrDie(t, doc)
+
+	assert.Contains(t, rows, []string{"meters.projection", "1", "payback_year", ""})
+}
@@ -0,0 +1,194 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+)
+
+// htmlTemplate is a single page without external assets, so it can be shared as a file and viewed offline.
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Green Button {{.Command}} report</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; color: #222; }
+figure { margin: 1.5em 0; overflow-x: auto; }
+figcaption { font-weight: bold; margin-bottom: 0.5em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.2em 1em; border-bottom: 1px solid #ddd; }
+td.number { text-align: right; }
+pre { font-size: 0.85em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>Green Button {{.Command}} report</h1>
+{{range .Sections}}
+<section>
+{{if .Title}}<h2>{{.Title}}</h2>{{end}}
+{{if .Costs}}
+<table>
+<tr><th>Plan</th><th>Total cost</th></tr>
+{{range .Costs}}<tr><td>{{.Plan}}</td><td class="number">{{printf "$%.2f" .Total}}</td></tr>
+{{end}}
+</table>
+{{end}}
+{{range .Charts}}
+<figure>
+<figcaption>{{.Title}}</figcaption>
+{{.Svg}}
+</figure>
+{{end}}
+</section>
+{{end}}
+<details>
+<summary>Details</summary>
+<pre>{{.Details}}</pre>
+</details>
+</body>
+</html>
+`))
+
+type htmlPage struct {
+	Command  string
+	Sections []htmlSection
+	// Details is the text output.
+	Details string
+}
+
+type htmlSection struct {
+	Title  string
+	Costs  []htmlCost
+	Charts []htmlChart
+}
+
+type htmlCost struct {
+	Plan  string
+	Total float64
+}
+
+type htmlChart struct {
+	Title string
+	Svg   template.HTML
+}
+
+// WriteHTML writes the document as a self-contained HTML page. It charts the monthly cost of each plan, the hourly
+// usage heatmap, the load shapes and the imports and exports by TOU period, for the sections that have them. The
+// text output follows the charts.
+func WriteHTML(out io.Writer, doc *Document) error {
+	var details bytes.Buffer
+	if err := WriteText(&details, doc); err != nil {
+		return err
+	}
+	page := htmlPage{Command: doc.Command, Details: details.String()}
+	for i := range doc.Meters {
+		s := &doc.Meters[i]
+		section := htmlSection{Costs: planCosts(s), Charts: sectionCharts(s)}
+		if len(doc.Meters) > 1 {
+			section.Title = "Meter " + s.Meter
+			if s.Combined {
+				section.Title = "All meters"
+			}
+		}
+		page.Sections = append(page.Sections, section)
+	}
+	return htmlTemplate.Execute(out, page)
+}
+
+func planCosts(s *Section) []htmlCost {
+	out := make([]htmlCost, 0, len(s.Bills)+1)
+	if s.Domestic != nil {
+		out = append(out, htmlCost{Plan: "Domestic", Total: s.Domestic.TotalCost})
+	}
+	for _, b := range s.Bills {
+		out = append(out, htmlCost{Plan: b.Plan, Total: b.TotalCost})
+	}
+	return out
+}
+
+func sectionCharts(s *Section) []htmlChart {
+	out := make([]htmlChart, 0)
+	if categories, series := monthlyCostSeries(s.Bills); len(categories) > 0 {
+		out = append(out, htmlChart{Title: "Monthly cost by plan", Svg: barChart(categories, series, "$")})
+	}
+	if len(s.Heatmap) > 0 {
+		out = append(out, htmlChart{Title: "Hourly usage", Svg: heatmapChart(s.Heatmap)})
+	}
+	if s.Profile != nil && len(s.Profile.Shapes) > 0 {
+		labels, series := loadShapeSeries(s.Profile.Shapes)
+		out = append(out, htmlChart{Title: "Average load shape by season", Svg: lineChart(labels, series, "kW")})
+	}
+	for _, b := range s.Bills {
+		if len(b.UsageByPeriod) == 0 {
+			continue
+		}
+		categories, series := periodSeries(b.UsageByPeriod)
+		out = append(out, htmlChart{Title: fmt.Sprintf("Imports and exports by TOU period on %s", b.Plan), Svg: barChart(categories, series, "kWh")})
+	}
+	return out
+}
+
+// monthlyCostSeries returns the months of the bills with monthly statements and a series of costs per plan. Months
+// without a statement cost 0.
+func monthlyCostSeries(bills []Bill) ([]string, []chartSeries) {
+	months := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, b := range bills {
+		for _, m := range b.Monthly {
+			if !seen[m.Month] {
+				seen[m.Month] = true
+				months = append(months, m.Month)
+			}
+		}
+	}
+	sort.Strings(months)
+	series := make([]chartSeries, 0)
+	for _, b := range bills {
+		if len(b.Monthly) == 0 {
+			continue
+		}
+		byMonth := make(map[string]float64)
+		for _, m := range b.Monthly {
+			byMonth[m.Month] = m.Total
+		}
+		values := make([]float64, 0, len(months))
+		for _, month := range months {
+			values = append(values, byMonth[month])
+		}
+		series = append(series, chartSeries{Name: b.Plan, Values: values})
+	}
+	return months, series
+}
+
+// loadShapeSeries returns the hours of the day and the mean demand of each load shape.
+func loadShapeSeries(shapes []LoadProfile) ([]string, []chartSeries) {
+	labels := make([]string, 24)
+	for h := range labels {
+		labels[h] = fmt.Sprintf("%02d:00", h)
+	}
+	series := make([]chartSeries, 0, len(shapes))
+	for _, shape := range shapes {
+		values := make([]float64, 24)
+		for _, h := range shape.Hours {
+			values[h.Hour] = h.MeanKw
+		}
+		series = append(series, chartSeries{Name: shape.Profile, Values: values})
+	}
+	return labels, series
+}
+
+// periodSeries returns the periods and series of imports, drawn up, and exports, drawn down.
+func periodSeries(usage []PeriodUsage) ([]string, []chartSeries) {
+	periods := make([]string, 0, len(usage))
+	imported := chartSeries{Name: "Imported"}
+	exported := chartSeries{Name: "Exported"}
+	for _, p := range usage {
+		periods = append(periods, p.Period)
+		imported.Values = append(imported.Values, p.ImportedKwh)
+		exported.Values = append(exported.Values, -p.ExportedKwh)
+	}
+	return periods, []chartSeries{imported, exported}
+}
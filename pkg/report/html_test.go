@@ -0,0 +1,98 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func htmlTestDocument() *Document {
+	doc := NewDocument("compare")
+	doc.Meters = append(doc.Meters, Section{
+		Meter:    "1",
+		Domestic: &DomesticBill{TotalCost: 90},
+		Bills: []Bill{
+			{
+				Plan:          "TOU-D-A",
+				TotalCost:     100,
+				UsageByPeriod: []PeriodUsage{{Period: "Summer - On-Peak", ImportedKwh: 3, ExportedKwh: 1}},
+				Monthly:       []MonthlyStatement{{Month: "2020-07", Total: 40}, {Month: "2020-08", Total: 60}},
+			},
+			{
+				Plan:      "TOU-D-PRIME",
+				TotalCost: 80,
+				Monthly:   []MonthlyStatement{{Month: "2020-08", Total: 80}},
+			},
+		},
+		Profile: &Profile{Shapes: []LoadProfile{{Profile: "Summer Weekday", Hours: []HourStats{{Hour: 0, MeanKw: 1}, {Hour: 1, MeanKw: 2}}}}},
+		Heatmap: []DayUsage{{Day: "2020-07-01", HourlyKwh: make([]float64, 24)}},
+	})
+	return doc
+}
+
+func TestWriteHTML_ChartsEverySection(t *testing.T) {
+	var out bytes.Buffer
+
+	err := WriteHTML(&out, htmlTestDocument())
+
+	assert.NoError(t, err)
+	got := out.String()
+	assert.True(t, strings.HasPrefix(got, "<!DOCTYPE html>"))
+	assert.Contains(t, got, "Monthly cost by plan")
+	assert.Contains(t, got, "Hourly usage")
+	assert.Contains(t, got, "Average load shape by season")
+	assert.Contains(t, got, "Imports and exports by TOU period on TOU-D-A")
+	assert.NotContains(t, got, "Imports and exports by TOU period on TOU-D-PRIME")
+	assert.Equal(t, 4, strings.Count(got, "<svg "))
+	assert.Contains(t, got, "<td>Domestic</td><td class=\"number\">$90.00</td>")
+}
+
+func TestWriteHTML_NoExternalAssets(t *testing.T) {
+	var out bytes.Buffer
+
+	assert.NoError(t, WriteHTML(&out, htmlTestDocument()))
+
+	assert.NotContains(t, out.String(), "<script")
+	assert.NotContains(t, out.String(), "<link")
+	assert.NotContains(t, out.String(), "src=")
+}
+
+func TestWriteHTML_IncludesTextOutput(t *testing.T) {
+	var out bytes.Buffer
+
+	assert.NoError(t, WriteHTML(&out, htmlTestDocument()))
+
+	assert.Contains(t, out.String(), "<pre>")
+	assert.Contains(t, out.String(), "TOU-D-PRIME")
+}
+
+func TestWriteHTML_EscapesNames(t *testing.T) {
+	doc := NewDocument("bill")
+	doc.Meters = append(doc.Meters, Section{Meter: "1", Bills: []Bill{{Plan: "<b>", Monthly: []MonthlyStatement{{Month: "2020-07"}}}}})
+	var out bytes.Buffer
+
+	assert.NoError(t, WriteHTML(&out, doc))
+
+	assert.NotContains(t, out.String(), "<b>")
+	assert.Contains(t, out.String(), "&lt;b&gt;")
+}
+
+func TestMonthlyCostSeries_MissingMonthsAreZero(t *testing.T) {
+	months, series := monthlyCostSeries(htmlTestDocument().Meters[0].Bills)
+
+	assert.Equal(t, []string{"2020-07", "2020-08"}, months)
+	assert.Equal(t, []chartSeries{
+		{Name: "TOU-D-A", Values: []float64{40, 60}},
+		{Name: "TOU-D-PRIME", Values: []float64{0, 80}},
+	}, series)
+}
+
+func TestPeriodSeries_ExportsAreNegative(t *testing.T) {
+	periods, series := periodSeries([]PeriodUsage{{Period: "Summer - On-Peak", ImportedKwh: 3, ExportedKwh: 1}})
+
+	assert.Equal(t, []string{"Summer - On-Peak"}, periods)
+	assert.Equal(t, []float64{3}, series[0].Values)
+	assert.Equal(t, []float64{-1}, series[1].Values)
+}
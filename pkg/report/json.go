@@ -0,0 +1,18 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// NewDocument returns an empty document for the output of a command.
+func NewDocument(command string) *Document {
+	return &Document{SchemaVersion: SchemaVersion, Command: command, Meters: make([]Section, 0)}
+}
+
+// WriteJSON writes the document as indented JSON.
+func WriteJSON(out io.Writer, doc *Document) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
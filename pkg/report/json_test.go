@@ -0,0 +1,74 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJSON_FieldNames(t *testing.T) {
+	doc := NewDocument("bill")
+	doc.Meters = append(doc.Meters, Section{
+		Meter: "1",
+		Bills: []Bill{{
+			Plan:          "TOU-D-PRIME",
+			UsageByPeriod: []PeriodUsage{{Period: "Summer - On-Peak", UsageKwh: 1}},
+			TotalCost:     12.5,
+			Monthly:       []MonthlyStatement{{Month: "2020-07", Total: 12.5}},
+		}},
+	})
+	var out bytes.Buffer
+
+	err := WriteJSON(&out, doc)
+
+	assert.NoError(t, err)
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	assert.Equal(t, 1.0, got["schema_version"])
+	assert.Equal(t, "bill", got["command"])
+	section := got["meters"].([]interface{})[0].(map[string]interface{})
+	assert.NotContains(t, section, "dataset")
+	bill := section["bills"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, 12.5, bill["total_cost"])
+	assert.Equal(t, "Summer - On-Peak", bill["usage_by_period"].([]interface{})[0].(map[string]interface{})["period"])
+	assert.Equal(t, "2020-07", bill["monthly"].([]interface{})[0].(map[string]interface{})["month"])
+}
+
+func TestWriteJSON_MissingValuesAreNull(t *testing.T) {
+	doc := NewDocument("simulate")
+	doc.Meters = append(doc.Meters, Section{
+		Meter:  "1",
+		Sizing: &Sizing{ByNpv: []SizingCandidate{{PVKw: 4, PaybackYears: finite(math.Inf(1))}}},
+	})
+	var out bytes.Buffer
+
+	err := WriteJSON(&out, doc)
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), `"payback_years": null`)
+}
+
+func TestWriteJSON_TimestampsAreRfc3339(t *testing.T) {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	assert.NoError(t, err)
+	doc := NewDocument("summary")
+	doc.Meters = append(doc.Meters, Section{Meter: "1", Dataset: &Dataset{FirstHour: time.Date(2020, 7, 1, 0, 0, 0, 0, la)}})
+	var out bytes.Buffer
+
+	assert.NoError(t, WriteJSON(&out, doc))
+
+	assert.Contains(t, out.String(), `"first_hour": "2020-07-01T00:00:00-07:00"`)
+}
+
+func TestParseFormat(t *testing.T) {
+	got, err := ParseFormat("csv")
+	assert.NoError(t, err)
+	assert.Equal(t, CSV, got)
+
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
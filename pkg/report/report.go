@@ -0,0 +1,40 @@
+// Package report converts the results of the calculators into a Document with a stable JSON schema, and writes it
+// as text, JSON, CSV or HTML.
+package report
+
+import (
+	"fmt"
+	"io"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	Text Format = "text"
+	JSON Format = "json"
+	CSV  Format = "csv"
+	HTML Format = "html"
+)
+
+// ParseFormat parses the name of an output format.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case Text, JSON, CSV, HTML:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format '%s', expected text, json, csv or html", name)
+}
+
+// Write writes the document in the given format.
+func Write(out io.Writer, doc *Document, format Format) error {
+	switch format {
+	case JSON:
+		return WriteJSON(out, doc)
+	case CSV:
+		return WriteCSV(out, doc)
+	case HTML:
+		return WriteHTML(out, doc)
+	}
+	return WriteText(out, doc)
+}
@@ -0,0 +1,442 @@
+package report
+
+import "time"
+
+// SchemaVersion identifies the layout of Document. It is incremented when a field is removed, renamed or changes
+// meaning. New fields may be added without incrementing it, so consumers should ignore fields they don't know.
+const SchemaVersion = 1
+
+// Document is the output of one reporter command.
+//
+// Conventions:
+//   - Energy is in kWh (fields ending in _kwh) and demand in kW (fields ending in _kw).
+//   - Costs are in US dollars. Credits are negative.
+//   - Shares are fractions between 0 and 1, not percentages.
+//   - Timestamps are RFC 3339 in the meter's time zone. Days are "2006-01-02" and months are "2006-01".
+//   - Values that don't exist, such as the payback of a system that never pays back, are null.
+//   - Sections and lists that a command doesn't produce are omitted.
+type Document struct {
+	SchemaVersion int    `json:"schema_version"`
+	Command       string `json:"command"`
+	// Meters has a section per meter. With several meters, the last section has all of them combined.
+	Meters    []Section    `json:"meters"`
+	Conflicts []Conflict   `json:"conflicts,omitempty"`
+	Devices   []DeviceCost `json:"devices,omitempty"`
+}
+
+// Section is the output for the readings of one meter.
+type Section struct {
+	Meter string `json:"meter" report:"key"`
+	// Combined is true for the section with all meters. Its readings add up the readings of the meters that start at
+	// the same time.
+	Combined bool     `json:"combined,omitempty"`
+	Quality  *Quality `json:"quality,omitempty"`
+	// Notes describe how the readings were cleaned up before the analysis.
+	Notes       []string      `json:"notes,omitempty"`
+	Dataset     *Dataset      `json:"dataset,omitempty"`
+	Weather     *WeatherModel `json:"weather,omitempty"`
+	Anomalies   []Anomaly     `json:"anomalies,omitempty"`
+	EvSessions  []EvSession   `json:"ev_sessions,omitempty"`
+	Solar       *SolarHealth  `json:"solar,omitempty"`
+	Domestic    *DomesticBill `json:"domestic,omitempty"`
+	Bills       []Bill        `json:"bills,omitempty"`
+	Uncertainty *Uncertainty  `json:"uncertainty,omitempty"`
+	Profile     *Profile      `json:"profile,omitempty"`
+	UsageCharts *UsageCharts  `json:"usage_charts,omitempty"`
+	// Heatmap is only produced for the HTML format, which charts it.
+	Heatmap         []DayUsage       `json:"heatmap,omitempty"`
+	Electrification *Electrification `json:"electrification,omitempty"`
+	Sizing          *Sizing          `json:"sizing,omitempty"`
+	Projection      *Projection      `json:"projection,omitempty"`
+}
+
+// Quality lists the problems found in the readings before they were cleaned up.
+type Quality struct {
+	Readings            int          `json:"readings"`
+	IntervalMinutes     float64      `json:"interval_minutes"`
+	MissingIntervals    int          `json:"missing_intervals"`
+	Gaps                []Gap        `json:"gaps"`
+	DuplicateTimestamps []time.Time  `json:"duplicate_timestamps"`
+	PartialDays         []PartialDay `json:"partial_days"`
+}
+
+// Problems returns the number of gaps, duplicate timestamps and partial days.
+func (q *Quality) Problems() int {
+	return len(q.Gaps) + len(q.DuplicateTimestamps) + len(q.PartialDays)
+}
+
+type Gap struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type PartialDay struct {
+	Day               string `json:"day" report:"key"`
+	Intervals         int    `json:"intervals"`
+	ExpectedIntervals int    `json:"expected_intervals"`
+}
+
+// Conflict is an interval with different readings in different downloads.
+type Conflict struct {
+	Meter    string          `json:"meter"`
+	Start    time.Time       `json:"start"`
+	KeptKwh  float64         `json:"kept_kwh"`
+	KeptFrom string          `json:"kept_from"`
+	Readings []ConflictValue `json:"readings"`
+}
+
+type ConflictValue struct {
+	Source   string  `json:"source" report:"key"`
+	UsageKwh float64 `json:"usage_kwh"`
+	Quality  string  `json:"quality"`
+}
+
+// Dataset summarizes the readings after they were cleaned up.
+type Dataset struct {
+	Readings     int `json:"readings"`
+	Days         int `json:"days"`
+	CompleteDays int `json:"complete_days"`
+	// Coverage is the share of the days' hours that have readings.
+	Coverage float64 `json:"coverage"`
+	// FirstHour and LastHour are the start of the first and last hour with readings.
+	FirstHour     time.Time `json:"first_hour"`
+	LastHour      time.Time `json:"last_hour"`
+	StartDay      string    `json:"start_day"`
+	EndDay        string    `json:"end_day_exclusive"`
+	TotalUsageKwh float64   `json:"total_usage_kwh"`
+	MonthlyPeaks  []Peak    `json:"monthly_peaks"`
+	// Demand1PercentKw is the demand exceeded 1% of the time.
+	Demand1PercentKw float64 `json:"demand_1_percent_kw"`
+}
+
+// Peak is the highest demand of a month.
+type Peak struct {
+	Month string    `json:"month" report:"key"`
+	Kw    float64   `json:"kw"`
+	At    time.Time `json:"at"`
+}
+
+// Bill is the cost of the readings on a TOU plan.
+type Bill struct {
+	Plan                 string  `json:"plan" report:"key"`
+	EnergyImportedKwh    float64 `json:"energy_imported_kwh"`
+	EnergyExportedKwh    float64 `json:"energy_exported_kwh"`
+	NetUsageKwh          float64 `json:"net_usage_kwh"`
+	AverageDailyUsageKwh float64 `json:"average_daily_usage_kwh"`
+	Coverage             float64 `json:"coverage"`
+	// LowQualityShare is the share of the energy from estimated or missing readings.
+	LowQualityShare float64       `json:"low_quality_share"`
+	UsageByPeriod   []PeriodUsage `json:"usage_by_period"`
+
+	NonBypassableCharges    float64 `json:"non_bypassable_charges"`
+	MaxBaselineAllowanceKwh float64 `json:"max_baseline_allowance_kwh"`
+	BaselineCredit          float64 `json:"baseline_credit"`
+	BasicCharge             float64 `json:"basic_charge"`
+	Taxes                   float64 `json:"taxes"`
+	// MonthlyFees are the non-bypassable charges, basic charge and taxes, which are paid on the monthly bills.
+	MonthlyFees float64 `json:"monthly_fees"`
+	// NemTrueUp is the energy cost net of the baseline credit, which is settled once a year.
+	NemTrueUp     float64 `json:"nem_true_up"`
+	DemandCharges float64 `json:"demand_charges"`
+	TotalCost     float64 `json:"total_cost"`
+	// Monthly is only produced by the bill command.
+	Monthly []MonthlyStatement `json:"monthly,omitempty"`
+}
+
+// PeriodUsage is the net energy used in a TOU period, such as "Summer - On-Peak". ImportedKwh and ExportedKwh add
+// up the hours with net imports and net exports.
+type PeriodUsage struct {
+	Period      string  `json:"period" report:"key"`
+	UsageKwh    float64 `json:"usage_kwh"`
+	ImportedKwh float64 `json:"imported_kwh"`
+	ExportedKwh float64 `json:"exported_kwh"`
+	RatePerKwh  float64 `json:"rate_per_kwh"`
+	Cost        float64 `json:"cost"`
+}
+
+// MonthlyStatement is the bill of a calendar month. Under net metering, the energy charges accrue until the
+// true-up, so the statements don't add up to the yearly bill.
+type MonthlyStatement struct {
+	Month                string  `json:"month" report:"key"`
+	Days                 int     `json:"days"`
+	EnergyImportedKwh    float64 `json:"energy_imported_kwh"`
+	EnergyExportedKwh    float64 `json:"energy_exported_kwh"`
+	NetUsageKwh          float64 `json:"net_usage_kwh"`
+	EnergyCharges        float64 `json:"energy_charges"`
+	BaselineCredit       float64 `json:"baseline_credit"`
+	BasicCharge          float64 `json:"basic_charge"`
+	NonBypassableCharges float64 `json:"non_bypassable_charges"`
+	Taxes                float64 `json:"taxes"`
+	MaxDemandKw          float64 `json:"max_demand_kw"`
+	DemandCharges        float64 `json:"demand_charges"`
+	Total                float64 `json:"total"`
+}
+
+// DomesticBill is the cost of the readings on the tiered Domestic plan.
+type DomesticBill struct {
+	Days                  int     `json:"days"`
+	BaselineAllocationKwh float64 `json:"baseline_allocation_kwh"`
+	UsageKwh              float64 `json:"usage_kwh"`
+	Tier1UsageKwh         float64 `json:"tier1_usage_kwh"`
+	Tier2UsageKwh         float64 `json:"tier2_usage_kwh"`
+	Tier3UsageKwh         float64 `json:"tier3_usage_kwh"`
+	EnergyCost            float64 `json:"energy_cost"`
+	MinimumCharges        float64 `json:"minimum_charges"`
+	DailyCharges          float64 `json:"daily_charges"`
+	TotalCost             float64 `json:"total_cost"`
+}
+
+// Profile describes when the household uses energy.
+type Profile struct {
+	BaseloadKw  float64       `json:"baseload_kw"`
+	EveningRamp EveningRamp   `json:"evening_ramp"`
+	Shapes      []LoadProfile `json:"shapes"`
+}
+
+type EveningRamp struct {
+	Days   int     `json:"days"`
+	MeanKw float64 `json:"mean_kw"`
+	P90Kw  float64 `json:"p90_kw"`
+	// PeakShare is the average share of the daily energy used from 4 to 9pm.
+	PeakShare float64 `json:"peak_share"`
+}
+
+// LoadProfile is the average day of a season and day type, such as "Summer Weekday".
+type LoadProfile struct {
+	Profile   string  `json:"profile" report:"key"`
+	Season    string  `json:"season"`
+	DayType   string  `json:"day_type"`
+	Days      int     `json:"days"`
+	PeakHour  int     `json:"peak_hour"`
+	PeakShare float64 `json:"peak_share"`
+	// Hours has the demand of each hour of the day, starting at midnight.
+	Hours []HourStats `json:"hours"`
+}
+
+type HourStats struct {
+	Hour    int     `json:"hour" report:"key"`
+	Samples int     `json:"samples"`
+	MeanKw  float64 `json:"mean_kw"`
+	P10Kw   float64 `json:"p10_kw"`
+	P50Kw   float64 `json:"p50_kw"`
+	P90Kw   float64 `json:"p90_kw"`
+}
+
+// UsageCharts is the data of the terminal charts of the profile command.
+type UsageCharts struct {
+	// Plan is the plan whose TOU periods color the week.
+	Plan    string       `json:"plan"`
+	Monthly []MonthUsage `json:"monthly"`
+	Daily   []DailyUsage `json:"daily"`
+	// Week has the average hours of each day of the week, starting on Sunday.
+	Week []WeekdayUsage `json:"week"`
+}
+
+type MonthUsage struct {
+	Month    string  `json:"month" report:"key"`
+	UsageKwh float64 `json:"usage_kwh"`
+}
+
+type DailyUsage struct {
+	Day      string  `json:"day" report:"key"`
+	UsageKwh float64 `json:"usage_kwh"`
+}
+
+type WeekdayUsage struct {
+	Weekday string     `json:"weekday" report:"key"`
+	Hours   []WeekHour `json:"hours"`
+}
+
+// WeekHour is the average energy of an hour of the week.
+type WeekHour struct {
+	Hour    int     `json:"hour" report:"key"`
+	MeanKwh float64 `json:"mean_kwh"`
+	// Period is the most common TOU period of the hour, such as "Summer - On-Peak". It is empty for hours without
+	// readings.
+	Period string `json:"period"`
+}
+
+// DayUsage is the energy used in each hour of a day.
+type DayUsage struct {
+	Day string `json:"day" report:"key"`
+	// HourlyKwh has the energy of each hour of the day, starting at midnight. Hours without readings are 0.
+	HourlyKwh []float64 `json:"hourly_kwh"`
+}
+
+// WeatherModel is the regression of daily usage on heating and cooling degree days.
+type WeatherModel struct {
+	// Error explains why no model could be fitted. The other fields are empty when it is set.
+	Error               string  `json:"error,omitempty"`
+	Days                int     `json:"days"`
+	BaseKwh             float64 `json:"base_kwh"`
+	HeatingKwhPerDegree float64 `json:"heating_kwh_per_degree_day"`
+	CoolingKwhPerDegree float64 `json:"cooling_kwh_per_degree_day"`
+	RSquared            float64 `json:"r_squared"`
+	// NormalizedKwh is the usage the model predicts for a year of normal weather, the average weather of each calendar
+	// day in the weather file. NormalizedDays is the number of calendar days with weather, 365 for a full year.
+	NormalizedKwh  float64         `json:"normalized_kwh"`
+	NormalizedDays int             `json:"normalized_days"`
+	Savings        *WeatherSavings `json:"savings,omitempty"`
+	UnusualDays    []UnusualDay    `json:"unusual_days"`
+}
+
+// WeatherSavings compares the days after the baseline to what the model predicts for their weather.
+type WeatherSavings struct {
+	Days         int     `json:"days"`
+	SavingsKwh   float64 `json:"savings_kwh"`
+	SavingsShare float64 `json:"savings_share"`
+}
+
+type UnusualDay struct {
+	Day          string  `json:"day" report:"key"`
+	ActualKwh    float64 `json:"actual_kwh"`
+	PredictedKwh float64 `json:"predicted_kwh"`
+}
+
+// Anomaly is an hour or day with unusual usage.
+type Anomaly struct {
+	Kind        string    `json:"kind"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	ActualKwh   float64   `json:"actual_kwh"`
+	ExpectedKwh float64   `json:"expected_kwh"`
+	ZScore      float64   `json:"z_score"`
+	CostImpact  float64   `json:"cost_impact"`
+}
+
+// EvSession is a likely EV charging session.
+type EvSession struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	EnergyKwh float64   `json:"energy_kwh"`
+	AverageKw float64   `json:"average_kw"`
+	Cost      float64   `json:"cost"`
+	// ShiftedCost is the cost if the session had run in the cheapest hours of the day.
+	ShiftedCost float64 `json:"shifted_cost"`
+	Savings     float64 `json:"savings"`
+}
+
+// SolarHealth lists periods of unusually low solar production.
+type SolarHealth struct {
+	LowDays      []SolarOutage     `json:"low_days"`
+	LowWeeks     []SolarOutage     `json:"low_weeks"`
+	YearOverYear []SolarYearChange `json:"year_over_year"`
+}
+
+type SolarOutage struct {
+	Start         string  `json:"start" report:"key"`
+	ProductionKwh float64 `json:"production_kwh"`
+	ExpectedKwh   float64 `json:"expected_kwh"`
+}
+
+type SolarYearChange struct {
+	FromYear int `json:"from_year"`
+	ToYear   int `json:"to_year" report:"key"`
+	// Change is the relative change of production, such as -0.005 for 0.5% degradation.
+	Change float64 `json:"change"`
+	Months int     `json:"months"`
+}
+
+// Uncertainty is the distribution of yearly plan costs over resampled years.
+type Uncertainty struct {
+	Samples     int              `json:"samples"`
+	Plans       []PlanCost       `json:"plans"`
+	Comparisons []PlanComparison `json:"comparisons"`
+}
+
+type PlanCost struct {
+	Plan     string  `json:"plan" report:"key"`
+	MeanCost float64 `json:"mean_cost"`
+	// P5Cost and P95Cost bound the 90% interval.
+	P5Cost              float64 `json:"p5_cost"`
+	P95Cost             float64 `json:"p95_cost"`
+	ProbabilityCheapest float64 `json:"probability_cheapest"`
+}
+
+// PlanComparison compares plan A to plan B. Differences are plan A's cost minus plan B's.
+type PlanComparison struct {
+	PlanA               string  `json:"plan_a"`
+	PlanB               string  `json:"plan_b"`
+	ProbabilityACheaper float64 `json:"probability_a_cheaper"`
+	MeanDifference      float64 `json:"mean_difference"`
+	P5Difference        float64 `json:"p5_difference"`
+	P95Difference       float64 `json:"p95_difference"`
+}
+
+// Electrification is the extra cost of a simulated heat pump and heat pump water heater.
+type Electrification struct {
+	HeatPumpKwh    float64               `json:"heat_pump_kwh"`
+	WaterHeaterKwh float64               `json:"water_heater_kwh"`
+	Plans          []ElectrificationCost `json:"plans"`
+}
+
+type ElectrificationCost struct {
+	Plan         string  `json:"plan" report:"key"`
+	CostBefore   float64 `json:"cost_before"`
+	CostAfter    float64 `json:"cost_after"`
+	CostIncrease float64 `json:"cost_increase"`
+}
+
+// Sizing lists the best solar and battery systems.
+type Sizing struct {
+	ByNpv     []SizingCandidate `json:"by_npv"`
+	ByPayback []SizingCandidate `json:"by_payback"`
+}
+
+type SizingCandidate struct {
+	PVKw          float64  `json:"pv_kw"`
+	BatteryKwh    float64  `json:"battery_kwh"`
+	Plan          string   `json:"plan"`
+	UpfrontCost   float64  `json:"upfront_cost"`
+	AnnualSavings float64  `json:"annual_savings"`
+	PaybackYears  *float64 `json:"payback_years"`
+	NPV           float64  `json:"npv"`
+}
+
+// Projection is the cash flow of an installer quote over the system lifetime.
+type Projection struct {
+	Plan        string           `json:"plan"`
+	PVKw        float64          `json:"pv_kw"`
+	BatteryKwh  float64          `json:"battery_kwh"`
+	SystemCost  float64          `json:"system_cost"`
+	Years       []ProjectionYear `json:"years"`
+	PaybackYear *int             `json:"payback_year"`
+	NPV         float64          `json:"npv"`
+	IRR         *float64         `json:"irr"`
+}
+
+type ProjectionYear struct {
+	Year        int     `json:"year" report:"key"`
+	CostWithout float64 `json:"cost_without"`
+	CostWith    float64 `json:"cost_with"`
+	NetCashFlow float64 `json:"net_cash_flow"`
+	Cumulative  float64 `json:"cumulative"`
+}
+
+// DeviceCost is the cost of a device from a Sense export.
+type DeviceCost struct {
+	Device      string  `json:"device" report:"key"`
+	Plan        string  `json:"plan"`
+	Days        int     `json:"days"`
+	EnergyKwh   float64 `json:"energy_kwh"`
+	TotalCost   float64 `json:"total_cost"`
+	MonthlyCost float64 `json:"monthly_cost"`
+	OnPeakShare float64 `json:"on_peak_share"`
+}
+
+// Plan describes the rates of a TOU plan.
+type Plan struct {
+	Name                  string       `json:"name" report:"key"`
+	DailyBasicCharge      float64      `json:"daily_basic_charge"`
+	MinimumDailyCharge    float64      `json:"minimum_daily_charge"`
+	HasBaselineAllocation bool         `json:"has_baseline_allocation"`
+	Rates                 []PeriodRate `json:"rates"`
+	// Demand charges are in $/kW of the highest demand of each month.
+	MonthlyMaxDemandChargePerKw float64 `json:"monthly_max_demand_charge_per_kw,omitempty"`
+	OnPeakDemandChargePerKw     float64 `json:"on_peak_demand_charge_per_kw,omitempty"`
+}
+
+type PeriodRate struct {
+	Period     string  `json:"period" report:"key"`
+	RatePerKwh float64 `json:"rate_per_kwh"`
+}
@@ -0,0 +1,239 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+)
+
+// Chart layout, in SVG user units.
+const (
+	chartWidth   = 720
+	chartHeight  = 280
+	marginLeft   = 56
+	marginRight  = 16
+	marginTop    = 16
+	marginBottom = 56
+)
+
+// palette colors the series of a chart, in order.
+var palette = []string{"#1b9e77", "#d95f02", "#7570b3", "#e7298a", "#66a61e", "#e6ab02"}
+
+// chartSeries is a named list of values, one per category or x position.
+type chartSeries struct {
+	Name   string
+	Values []float64
+}
+
+// svgBuilder writes SVG elements. Text is escaped, so the result is safe to embed in HTML.
+type svgBuilder struct {
+	strings.Builder
+}
+
+func newSvg(width int, height int) *svgBuilder {
+	b := &svgBuilder{}
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" font-family="sans-serif" font-size="11">`,
+		width, height, width, height)
+	return b
+}
+
+func (b *svgBuilder) rect(x, y, width, height float64, fill string, title string) {
+	fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s">`, x, y, width, height, fill)
+	if title != "" {
+		fmt.Fprintf(b, `<title>%s</title>`, template.HTMLEscapeString(title))
+	}
+	b.WriteString(`</rect>`)
+}
+
+func (b *svgBuilder) line(x1, y1, x2, y2 float64, stroke string) {
+	fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`, x1, y1, x2, y2, stroke)
+}
+
+// text writes a label. Anchor is start, middle or end.
+func (b *svgBuilder) text(x, y float64, anchor string, label string) {
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`, x, y, anchor, template.HTMLEscapeString(label))
+}
+
+func (b *svgBuilder) polyline(points []string, stroke string, title string) {
+	fmt.Fprintf(b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2">`, strings.Join(points, " "), stroke)
+	if title != "" {
+		fmt.Fprintf(b, `<title>%s</title>`, template.HTMLEscapeString(title))
+	}
+	b.WriteString(`</polyline>`)
+}
+
+// legend writes a colored box and the name of each series below the plot.
+func (b *svgBuilder) legend(series []chartSeries) {
+	x := float64(marginLeft)
+	y := float64(chartHeight - 12)
+	for i, s := range series {
+		b.rect(x, y-9, 10, 10, palette[i%len(palette)], "")
+		b.text(x+14, y, "start", s.Name)
+		x += 24 + 7*float64(len(s.Name))
+	}
+}
+
+func (b *svgBuilder) html() template.HTML {
+	b.WriteString(`</svg>`)
+	// The builder escapes all text it writes.
+	return template.HTML(b.String())
+}
+
+// yAxis maps values between lo and hi to the plot area and draws gridlines with labels.
+type yAxis struct {
+	lo, hi float64
+}
+
+// newYAxis returns an axis that includes zero and all values, rounded out to tick steps.
+func newYAxis(series []chartSeries) yAxis {
+	lo, hi := 0.0, 0.0
+	for _, s := range series {
+		for _, v := range s.Values {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+	step := tickStep(hi - lo)
+	return yAxis{lo: math.Floor(lo/step) * step, hi: math.Ceil(hi/step) * step}
+}
+
+// tickStep returns a step of 1, 2 or 5 times a power of ten that splits span into about 5 ticks.
+func tickStep(span float64) float64 {
+	raw := span / 5
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+func (a yAxis) y(v float64) float64 {
+	plotHeight := float64(chartHeight - marginTop - marginBottom)
+	return marginTop + plotHeight*(a.hi-v)/(a.hi-a.lo)
+}
+
+func (a yAxis) draw(b *svgBuilder, unit string) {
+	step := tickStep(a.hi - a.lo)
+	for v := a.lo; v <= a.hi+step/2; v += step {
+		y := a.y(v)
+		stroke := "#ddd"
+		if math.Abs(v) < step/2 {
+			stroke = "#888"
+		}
+		b.line(marginLeft, y, chartWidth-marginRight, y, stroke)
+		b.text(marginLeft-6, y+4, "end", formatTick(v))
+	}
+	b.text(4, marginTop-4, "start", unit)
+}
+
+func formatTick(v float64) string {
+	if math.Abs(v) < 1e-9 {
+		return "0"
+	}
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+}
+
+// barChart draws a bar per series for each category. Negative values are drawn below zero.
+func barChart(categories []string, series []chartSeries, unit string) template.HTML {
+	b := newSvg(chartWidth, chartHeight)
+	axis := newYAxis(series)
+	axis.draw(b, unit)
+	groupWidth := float64(chartWidth-marginLeft-marginRight) / float64(len(categories))
+	barWidth := groupWidth * 0.8 / float64(len(series))
+	for c, category := range categories {
+		x := marginLeft + groupWidth*float64(c) + groupWidth*0.1
+		for i, s := range series {
+			v := s.Values[c]
+			top, bottom := axis.y(math.Max(v, 0)), axis.y(math.Min(v, 0))
+			b.rect(x+barWidth*float64(i), top, barWidth, bottom-top, palette[i%len(palette)],
+				fmt.Sprintf("%s, %s: %.2f %s", category, s.Name, v, unit))
+		}
+		// Long categories such as "Summer - Super Off-Peak" are split over two lines.
+		for l, part := range strings.SplitN(category, " - ", 2) {
+			b.text(marginLeft+groupWidth*(float64(c)+0.5), float64(chartHeight-marginBottom+14+12*l), "middle", part)
+		}
+	}
+	b.legend(series)
+	return b.html()
+}
+
+// lineChart draws a line per series over the x labels.
+func lineChart(labels []string, series []chartSeries, unit string) template.HTML {
+	b := newSvg(chartWidth, chartHeight)
+	axis := newYAxis(series)
+	axis.draw(b, unit)
+	step := float64(chartWidth-marginLeft-marginRight) / math.Max(1, float64(len(labels)-1))
+	for i, label := range labels {
+		if i%3 == 0 {
+			b.text(marginLeft+step*float64(i), chartHeight-marginBottom+14, "middle", label)
+		}
+	}
+	for i, s := range series {
+		points := make([]string, 0, len(s.Values))
+		for j, v := range s.Values {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", marginLeft+step*float64(j), axis.y(v)))
+		}
+		b.polyline(points, palette[i%len(palette)], s.Name)
+	}
+	b.legend(series)
+	return b.html()
+}
+
+// heatmapChart draws a cell for every hour of every day, with days from left to right and hours from top to bottom.
+// Imports are red and exports are blue, darker for more energy.
+func heatmapChart(days []DayUsage) template.HTML {
+	cellWidth := math.Max(2, math.Min(24, float64(chartWidth-marginLeft-marginRight)/float64(len(days))))
+	cellHeight := 10.0
+	// The chart is at least as wide as the others, so that the color scale below it fits.
+	width := marginLeft + marginRight + int(math.Ceil(cellWidth*float64(len(days))))
+	if width < chartWidth {
+		width = chartWidth
+	}
+	height := marginTop + marginBottom + int(24*cellHeight)
+	b := newSvg(width, height)
+
+	maxKwh := 0.0
+	for _, d := range days {
+		for _, kwh := range d.HourlyKwh {
+			maxKwh = math.Max(maxKwh, math.Abs(kwh))
+		}
+	}
+	for i, d := range days {
+		x := marginLeft + cellWidth*float64(i)
+		for hour, kwh := range d.HourlyKwh {
+			b.rect(x, marginTop+cellHeight*float64(hour), cellWidth, cellHeight, heatColor(kwh, maxKwh),
+				fmt.Sprintf("%s %02d:00: %.2f kWh", d.Day, hour, kwh))
+		}
+		// Label the first day of each month, or the first day shown.
+		if i == 0 || strings.HasSuffix(d.Day, "-01") {
+			b.text(x, float64(height-marginBottom+14), "start", d.Day[:7])
+		}
+	}
+	for hour := 0; hour < 24; hour += 6 {
+		b.text(marginLeft-6, marginTop+cellHeight*(float64(hour)+0.8), "end", fmt.Sprintf("%02d:00", hour))
+	}
+	b.text(marginLeft, float64(height-12), "start", fmt.Sprintf("Darkest red: %.2f kWh imported. Darkest blue: %.2f kWh exported.", maxKwh, maxKwh))
+	return b.html()
+}
+
+// heatColor blends white into red for imports and into blue for exports, in proportion to the share of maxKwh.
+func heatColor(kwh float64, maxKwh float64) string {
+	if maxKwh == 0 {
+		return "#ffffff"
+	}
+	share := math.Min(1, math.Abs(kwh)/maxKwh)
+	target := [3]float64{0xd7, 0x30, 0x1f}
+	if kwh < 0 {
+		target = [3]float64{0x2c, 0x7f, 0xb8}
+	}
+	var rgb [3]int
+	for i, t := range target {
+		rgb[i] = int(math.Round(255 + (t-255)*share))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+}
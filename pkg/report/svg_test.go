@@ -0,0 +1,40 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTickStep_RoundsToOneTwoOrFive(t *testing.T) {
+	assert.Equal(t, 1.0, tickStep(5))
+	assert.Equal(t, 2.0, tickStep(7))
+	assert.Equal(t, 50.0, tickStep(180))
+	assert.InDelta(t, 0.1, tickStep(0.4), 1e-9)
+}
+
+func TestNewYAxis_IncludesZeroAndNegatives(t *testing.T) {
+	got := newYAxis([]chartSeries{{Values: []float64{3.2, -1.1}}})
+
+	assert.Equal(t, yAxis{lo: -2, hi: 4}, got)
+}
+
+func TestNewYAxis_AllZero_NonEmptyRange(t *testing.T) {
+	got := newYAxis([]chartSeries{{Values: []float64{0}}})
+
+	assert.Less(t, got.lo, got.hi)
+}
+
+func TestHeatColor(t *testing.T) {
+	assert.Equal(t, "#ffffff", heatColor(0, 2))
+	assert.Equal(t, "#d7301f", heatColor(2, 2))
+	assert.Equal(t, "#2c7fb8", heatColor(-2, 2))
+	assert.Equal(t, "#ffffff", heatColor(1, 0))
+}
+
+func TestBarChart_EscapesLabels(t *testing.T) {
+	got := barChart([]string{"<x>"}, []chartSeries{{Name: "a&b", Values: []float64{1}}}, "kWh")
+
+	assert.Contains(t, string(got), "&lt;x&gt;")
+	assert.Contains(t, string(got), "a&amp;b")
+}
@@ -0,0 +1,318 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteText writes the document as aligned, human-readable tables.
+func WriteText(out io.Writer, doc *Document) error {
+	if doc.Command == "validate" {
+		writeValidation(out, doc)
+		return nil
+	}
+	for i := range doc.Meters {
+		s := &doc.Meters[i]
+		if len(doc.Meters) > 1 {
+			if s.Combined {
+				_, _ = fmt.Fprintf(out, "=== All meters ===\n")
+			} else {
+				_, _ = fmt.Fprintf(out, "=== Meter %s ===\n", s.Meter)
+			}
+		}
+		if err := writeSection(out, s); err != nil {
+			return err
+		}
+	}
+	writeDevices(out, doc.Devices)
+	return nil
+}
+
+// writeValidation lists every problem found by the validate command.
+func writeValidation(out io.Writer, doc *Document) {
+	problems := len(doc.Conflicts)
+	for _, s := range doc.Meters {
+		q := s.Quality
+		_, _ = fmt.Fprintf(out, "Meter %s: %d readings.\n", s.Meter, q.Readings)
+		for _, g := range q.Gaps {
+			_, _ = fmt.Fprintf(out, "  Gap from %s to %s (%s).\n", g.Start, g.End, g.End.Sub(g.Start))
+		}
+		for _, t := range q.DuplicateTimestamps {
+			_, _ = fmt.Fprintf(out, "  Duplicate readings at %s.\n", t)
+		}
+		for _, p := range q.PartialDays {
+			_, _ = fmt.Fprintf(out, "  Partial day %s: %d of %d intervals.\n", p.Day, p.Intervals, p.ExpectedIntervals)
+		}
+		problems += q.Problems()
+	}
+	for _, c := range doc.Conflicts {
+		_, _ = fmt.Fprintf(out, "Conflicting readings for %s at %s. Kept %.3f kWh from %s.\n", c.Meter, c.Start, c.KeptKwh, c.KeptFrom)
+	}
+	if problems == 0 {
+		_, _ = fmt.Fprintf(out, "No problems found.\n")
+	}
+}
+
+func writeSection(out io.Writer, s *Section) error {
+	if q := s.Quality; q != nil {
+		_, _ = fmt.Fprintf(out, "Data quality: %d missing intervals in %d gaps, %d duplicate timestamps, %d partial days.\n",
+			q.MissingIntervals, len(q.Gaps), len(q.DuplicateTimestamps), len(q.PartialDays))
+	}
+	for _, n := range s.Notes {
+		_, _ = fmt.Fprintln(out, n)
+	}
+	if s.Dataset != nil {
+		writeDataset(out, s.Dataset)
+	}
+	if s.Weather != nil {
+		writeWeather(out, s.Weather)
+	}
+	if s.Anomalies != nil {
+		for _, a := range s.Anomalies {
+			_, _ = fmt.Fprintf(out, "%s at %s: %.2f kWh, expected %.2f kWh. Estimated cost $%.2f\n",
+				a.Kind, a.Start.Format("2006-01-02 15:04"), a.ActualKwh, a.ExpectedKwh, a.CostImpact)
+		}
+		_, _ = fmt.Fprintln(out)
+	}
+	if s.EvSessions != nil {
+		writeEvSessions(out, s.EvSessions)
+	}
+	if s.Solar != nil {
+		writeSolar(out, s.Solar)
+	}
+	if s.Domestic != nil || len(s.Bills) > 0 {
+		// The bills share a table so that their columns line up.
+		w := newTabWriter(out)
+		if s.Domestic != nil {
+			writeDomestic(w, s.Domestic)
+		}
+		for i := range s.Bills {
+			writeBill(w, &s.Bills[i])
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if s.Uncertainty != nil {
+		writeUncertainty(out, s.Uncertainty)
+	}
+	if s.Profile != nil {
+		writeProfile(out, s.Profile)
+	}
+	if s.UsageCharts != nil {
+		writeUsageCharts(out, s.UsageCharts)
+	}
+	if s.Electrification != nil {
+		writeElectrification(out, s.Electrification)
+	}
+	if s.Sizing != nil {
+		writeSizing(out, s.Sizing)
+	}
+	if s.Projection != nil {
+		writeProjection(out, s.Projection)
+	}
+	return nil
+}
+
+func newTabWriter(out io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(out, 0, 0, 3, ' ', tabwriter.AlignRight)
+}
+
+func writeDataset(out io.Writer, d *Dataset) {
+	_, _ = fmt.Fprintf(out, "Read %d data points (%d days, %d complete).\n", d.Readings, d.Days, d.CompleteDays)
+	_, _ = fmt.Fprintf(out, "Data coverage: %.1f%%\n", 100*d.Coverage)
+	_, _ = fmt.Fprintf(out, "First date: %+v\n", d.FirstHour)
+	_, _ = fmt.Fprintf(out, "Last date: %+v\n", d.LastHour)
+	_, _ = fmt.Fprintf(out, "Total usage: %.2f kWh.\n\n", d.TotalUsageKwh)
+
+	w := newTabWriter(out)
+	_, _ = fmt.Fprintf(w, "Start\t%s\t\n", d.StartDay)
+	_, _ = fmt.Fprintf(w, "End (excl.)\t%s\t\n", d.EndDay)
+	_, _ = fmt.Fprintf(w, "Time\t%d\tDays\t\n", d.Days)
+	_, _ = fmt.Fprintln(w)
+	for _, p := range d.MonthlyPeaks {
+		_, _ = fmt.Fprintf(w, "Peak demand %s\t%.2f\tkW\t%s\t\n", p.Month, p.Kw, p.At.Format("2006-01-02 15:04"))
+	}
+	_, _ = fmt.Fprintf(w, "Demand exceeded 1%% of the time\t%.2f\tkW\t\n", d.Demand1PercentKw)
+	_, _ = fmt.Fprintln(w)
+	_ = w.Flush()
+}
+
+func writeWeather(out io.Writer, m *WeatherModel) {
+	if m.Error != "" {
+		_, _ = fmt.Fprintf(out, "Weather model: %s\n\n", m.Error)
+		return
+	}
+	_, _ = fmt.Fprintf(out, "Weather model: %.2f kWh/day + %.2f kWh/HDD + %.2f kWh/CDD (R² %.2f over %d days).\n",
+		m.BaseKwh, m.HeatingKwhPerDegree, m.CoolingKwhPerDegree, m.RSquared, m.Days)
+	if m.NormalizedDays > 0 {
+		_, _ = fmt.Fprintf(out, "Weather-normalized usage: %.0f kWh over a normal year of %d days (%.2f kWh/day).\n",
+			m.NormalizedKwh, m.NormalizedDays, m.NormalizedKwh/float64(m.NormalizedDays))
+	}
+	if s := m.Savings; s != nil {
+		_, _ = fmt.Fprintf(out, "Weather-adjusted savings: %.2f kWh (%.1f%%) over %d days.\n", s.SavingsKwh, 100*s.SavingsShare, s.Days)
+	}
+	for _, d := range m.UnusualDays {
+		_, _ = fmt.Fprintf(out, "Unusual day %s: %.2f kWh, expected %.2f kWh.\n", d.Day, d.ActualKwh, d.PredictedKwh)
+	}
+	_, _ = fmt.Fprintln(out)
+}
+
+func writeEvSessions(out io.Writer, sessions []EvSession) {
+	totalCost, totalSavings := 0.0, 0.0
+	for _, s := range sessions {
+		_, _ = fmt.Fprintf(out, "EV session %s to %s: %.1f kWh at %.1f kW, $%.2f. Shifting saves $%.2f\n",
+			s.Start.Format("2006-01-02 15:04"), s.End.Format("15:04"), s.EnergyKwh, s.AverageKw, s.Cost, s.Savings)
+		totalCost += s.Cost
+		totalSavings += s.Savings
+	}
+	_, _ = fmt.Fprintf(out, "%d EV sessions cost $%.2f. Shifting all of them saves $%.2f\n\n", len(sessions), totalCost, totalSavings)
+}
+
+func writeSolar(out io.Writer, s *SolarHealth) {
+	for _, o := range s.LowDays {
+		_, _ = fmt.Fprintf(out, "Low solar production on %s: %.1f kWh, expected %.1f kWh.\n", o.Start, o.ProductionKwh, o.ExpectedKwh)
+	}
+	for _, o := range s.LowWeeks {
+		_, _ = fmt.Fprintf(out, "Low solar production in week of %s: %.1f kWh, expected %.1f kWh.\n", o.Start, o.ProductionKwh, o.ExpectedKwh)
+	}
+	for _, y := range s.YearOverYear {
+		_, _ = fmt.Fprintf(out, "Solar production %d to %d: %+.1f%% (%d months compared).\n", y.FromYear, y.ToYear, 100*y.Change, y.Months)
+	}
+	_, _ = fmt.Fprintln(out)
+}
+
+func writeDomestic(w io.Writer, d *DomesticBill) {
+	_, _ = fmt.Fprintf(w, "Name\tDomestic\t\t\n")
+	_, _ = fmt.Fprintf(w, "Usage\t%.2f\tKWh\t\n", d.UsageKwh)
+	_, _ = fmt.Fprintf(w, "Baseline allocation\t%.2f\tKWh\t\n", d.BaselineAllocationKwh)
+	_, _ = fmt.Fprintf(w, "Tier 1 usage\t%.2f\tKWh\t\n", d.Tier1UsageKwh)
+	_, _ = fmt.Fprintf(w, "Tier 2 usage\t%.2f\tKWh\t\n", d.Tier2UsageKwh)
+	_, _ = fmt.Fprintf(w, "Tier 3 usage\t%.2f\tKWh\t\n", d.Tier3UsageKwh)
+	_, _ = fmt.Fprintf(w, "Energy cost\t%.2f\t$\t\n", d.EnergyCost)
+	_, _ = fmt.Fprintf(w, "Minimum charges\t%.2f\t$\t\n", d.MinimumCharges)
+	_, _ = fmt.Fprintf(w, "Daily charges\t%.2f\t$\t\n", d.DailyCharges)
+	_, _ = fmt.Fprintf(w, "Total\t%.2f\t$\t\n", d.TotalCost)
+	_, _ = fmt.Fprintln(w)
+}
+
+func writeBill(w io.Writer, b *Bill) {
+	_, _ = fmt.Fprintf(w, "Name\t%s\t\t\n", b.Plan)
+	_, _ = fmt.Fprintf(w, "Energy exported\t%.2f\tKWh\t\n", b.EnergyExportedKwh)
+	_, _ = fmt.Fprintf(w, "Energy imported\t%.2f\tKWh\t\n", b.EnergyImportedKwh)
+	_, _ = fmt.Fprintf(w, "Net usage\t%.2f\tKWh\t\n", b.NetUsageKwh)
+	_, _ = fmt.Fprintf(w, "Average daily usage\t%.2f\tKWh\t\n", b.AverageDailyUsageKwh)
+	_, _ = fmt.Fprintf(w, "Data coverage\t%.1f\t%%\t\n", 100*b.Coverage)
+	_, _ = fmt.Fprintf(w, "Estimated or missing\t%.1f\t%%\t\n", 100*b.LowQualityShare)
+
+	_, _ = fmt.Fprintf(w, "-------\t-------\t\n")
+	for _, p := range b.UsageByPeriod {
+		_, _ = fmt.Fprintf(w, "%s\t%.2f\tKWh\t\n", p.Period, p.UsageKwh)
+	}
+	_, _ = fmt.Fprintf(w, "-------\t-------\t\n")
+
+	_, _ = fmt.Fprintf(w, "Non-bypassable charges\t%.2f\t$\t\n", b.NonBypassableCharges)
+	_, _ = fmt.Fprintf(w, "Max baseline allocation\t%.2f\tKWh\t\n", b.MaxBaselineAllowanceKwh)
+	_, _ = fmt.Fprintf(w, "Baseline discount\t%.2f\t$\t\n", b.BaselineCredit)
+	_, _ = fmt.Fprintf(w, "Basic charge\t%.2f\t$\t\n", b.BasicCharge)
+	_, _ = fmt.Fprintf(w, "Taxes\t%.2f\t$\t\n", b.Taxes)
+	_, _ = fmt.Fprintf(w, "Fees from monthly bills\t%.2f\t$\t\n", b.MonthlyFees)
+	_, _ = fmt.Fprintf(w, "NEM true-up\t%.2f\t$\t\n", b.NemTrueUp)
+	if b.DemandCharges != 0 {
+		_, _ = fmt.Fprintf(w, "Demand charges\t%.2f\t$\t\n", b.DemandCharges)
+	}
+	_, _ = fmt.Fprintln(w)
+
+	if len(b.Monthly) > 0 {
+		_, _ = fmt.Fprintf(w, "Month\tDays\tNet usage\tEnergy\tFees\tDemand\tTotal\t\n")
+		for _, m := range b.Monthly {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t\n", m.Month, m.Days, m.NetUsageKwh,
+				m.EnergyCharges+m.BaselineCredit, m.BasicCharge+m.NonBypassableCharges+m.Taxes, m.DemandCharges, m.Total)
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+}
+
+func writeUncertainty(out io.Writer, u *Uncertainty) {
+	_, _ = fmt.Fprintf(out, "Annual cost over %d resampled years:\n", u.Samples)
+	for _, p := range u.Plans {
+		_, _ = fmt.Fprintf(out, "  %s: $%.0f (90%% interval $%.0f-$%.0f), cheapest in %.0f%% of samples\n", p.Plan, p.MeanCost, p.P5Cost, p.P95Cost, 100*p.ProbabilityCheapest)
+	}
+	for _, c := range u.Comparisons {
+		_, _ = fmt.Fprintf(out, "  %s vs %s: %.0f%% chance of being cheaper, difference $%.0f (90%% interval $%.0f to $%.0f)\n",
+			c.PlanA, c.PlanB, 100*c.ProbabilityACheaper, c.MeanDifference, c.P5Difference, c.P95Difference)
+	}
+	_, _ = fmt.Fprintln(out)
+}
+
+func writeProfile(out io.Writer, p *Profile) {
+	w := newTabWriter(out)
+	_, _ = fmt.Fprintf(w, "Baseload\t%.2f\tkW\t\n", p.BaseloadKw)
+	_, _ = fmt.Fprintf(w, "Evening ramp (avg)\t%.2f\tkW\t\n", p.EveningRamp.MeanKw)
+	_, _ = fmt.Fprintf(w, "Evening ramp (p90)\t%.2f\tkW\t\n", p.EveningRamp.P90Kw)
+	_, _ = fmt.Fprintf(w, "Usage 4-9pm\t%.1f\t%%\t\n", 100*p.EveningRamp.PeakShare)
+	_, _ = fmt.Fprintln(w)
+	for _, s := range p.Shapes {
+		_, _ = fmt.Fprintf(w, "%s\t%d\tDays\tpeak at %d:00, %.1f%% during 4-9pm\t\n", s.Profile, s.Days, s.PeakHour, 100*s.PeakShare)
+	}
+	_, _ = fmt.Fprintln(w)
+	_ = w.Flush()
+}
+
+func writeElectrification(out io.Writer, e *Electrification) {
+	_, _ = fmt.Fprintf(out, "Electrification adds %.1f kWh for the heat pump and %.1f kWh for the water heater.\n", e.HeatPumpKwh, e.WaterHeaterKwh)
+	for _, p := range e.Plans {
+		_, _ = fmt.Fprintf(out, "  %s: $%.2f -> $%.2f (%+.2f)\n", p.Plan, p.CostBefore, p.CostAfter, p.CostIncrease)
+	}
+	_, _ = fmt.Fprintln(out)
+}
+
+func writeSizing(out io.Writer, s *Sizing) {
+	writeCandidates := func(title string, candidates []SizingCandidate) {
+		_, _ = fmt.Fprintln(out, title)
+		for _, c := range candidates {
+			payback := "no"
+			if c.PaybackYears != nil {
+				payback = fmt.Sprintf("%.1f year", *c.PaybackYears)
+			}
+			_, _ = fmt.Fprintf(out, "  %.0f kW solar, %.0f kWh battery on %s: $%.0f upfront, $%.0f/year, %s payback, NPV $%.0f\n",
+				c.PVKw, c.BatteryKwh, c.Plan, c.UpfrontCost, c.AnnualSavings, payback, c.NPV)
+		}
+	}
+	writeCandidates("Best systems by net present value:", s.ByNpv)
+	writeCandidates("Best systems by payback:", s.ByPayback)
+	_, _ = fmt.Fprintln(out)
+}
+
+func writeProjection(out io.Writer, p *Projection) {
+	_, _ = fmt.Fprintf(out, "Projection of a %.1f kW solar, %.1f kWh battery system on %s:\n", p.PVKw, p.BatteryKwh, p.Plan)
+	w := newTabWriter(out)
+	_, _ = fmt.Fprintf(w, "Year\tWithout\tWith\tCash flow\tCumulative\t\n")
+	for _, y := range p.Years {
+		_, _ = fmt.Fprintf(w, "%d\t%.0f\t%.0f\t%.0f\t%.0f\t\n", y.Year, y.CostWithout, y.CostWith, y.NetCashFlow, y.Cumulative)
+	}
+	_ = w.Flush()
+	if p.PaybackYear != nil {
+		_, _ = fmt.Fprintf(out, "Pays back in year %d.\n", *p.PaybackYear)
+	} else {
+		_, _ = fmt.Fprintf(out, "Does not pay back within %d years.\n", len(p.Years))
+	}
+	irr := "none"
+	if p.IRR != nil {
+		irr = fmt.Sprintf("%.1f%%", 100**p.IRR)
+	}
+	_, _ = fmt.Fprintf(out, "NPV: $%.0f. IRR: %s\n\n", p.NPV, irr)
+}
+
+func writeDevices(out io.Writer, devices []DeviceCost) {
+	for i, d := range devices {
+		if i == 0 || devices[i-1].Plan != d.Plan {
+			_, _ = fmt.Fprintf(out, "Device costs with %s:\n", d.Plan)
+		}
+		_, _ = fmt.Fprintf(out, "  %s: $%.2f/month, %.0f%% of it during on-peak\n", d.Device, d.MonthlyCost, 100*d.OnPeakShare)
+		if i == len(devices)-1 || devices[i+1].Plan != d.Plan {
+			_, _ = fmt.Fprintln(out)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteText_SeveralMeters_Headers(t *testing.T) {
+	doc := NewDocument("profile")
+	doc.Meters = append(doc.Meters,
+		Section{Meter: "1", Profile: &Profile{BaseloadKw: 0.5}},
+		Section{Meter: "All meters", Combined: true, Profile: &Profile{BaseloadKw: 1}})
+	var out bytes.Buffer
+
+	assert.NoError(t, WriteText(&out, doc))
+
+	assert.Contains(t, out.String(), "=== Meter 1 ===")
+	assert.Contains(t, out.String(), "=== All meters ===")
+	assert.Contains(t, out.String(), "0.50")
+}
+
+func TestWriteText_ValidateClean(t *testing.T) {
+	doc := NewDocument("validate")
+	doc.Meters = append(doc.Meters, Section{Meter: "1", Quality: &Quality{Readings: 96}})
+	var out bytes.Buffer
+
+	assert.NoError(t, WriteText(&out, doc))
+
+	assert.Equal(t, "Meter 1: 96 readings.\nNo problems found.\n", out.String())
+}
+
+func TestWriteText_ValidateGap(t *testing.T) {
+	start := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	doc := NewDocument("validate")
+	doc.Meters = append(doc.Meters, Section{Meter: "1", Quality: &Quality{Readings: 95, Gaps: []Gap{{Start: start, End: start.Add(15 * time.Minute)}}}})
+	var out bytes.Buffer
+
+	assert.NoError(t, WriteText(&out, doc))
+
+	assert.Contains(t, out.String(), "Gap from 2020-07-01 00:00:00 +0000 UTC")
+	assert.NotContains(t, out.String(), "No problems found.")
+}
+
+func TestWriteText_NoPayback(t *testing.T) {
+	doc := NewDocument("simulate")
+	doc.Meters = append(doc.Meters, Section{Meter: "1", Projection: &Projection{Plan: "TOU-D-PRIME", Years: []ProjectionYear{{Year: 1}}}})
+	var out bytes.Buffer
+
+	assert.NoError(t, WriteText(&out, doc))
+
+	assert.Contains(t, out.String(), "Does not pay back within 1 years.")
+	assert.Contains(t, out.String(), "IRR: none")
+}
+
+func TestWriteText_WeatherNormalizedUsage(t *testing.T) {
+	doc := NewDocument("summary")
+	doc.Meters = append(doc.Meters, Section{Meter: "1", Weather: &WeatherModel{BaseKwh: 10, Days: 30, NormalizedKwh: 3650, NormalizedDays: 365}})
+	var out bytes.Buffer
+
+	assert.NoError(t, WriteText(&out, doc))
+
+	assert.Contains(t, out.String(), "Weather-normalized usage: 3650 kWh over a normal year of 365 days (10.00 kWh/day).")
+}
@@ -0,0 +1,187 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// barWidth is the width in characters of the longest bar.
+const barWidth = 40
+
+// sparklineDays is the number of days on each line of the sparkline.
+const sparklineDays = 60
+
+// eighths are the blocks that draw the end of a bar, from 1/8 to 8/8 of a character.
+var eighths = []rune("▏▎▍▌▋▊▉█")
+
+// sparks are the blocks of a sparkline, from lowest to highest.
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// shades fill the heatmap cells, from no usage to the highest usage.
+var shades = []string{"·", "░", "▒", "▓", "█"}
+
+// touClass is how a TOU period is drawn in the heatmap.
+type touClass struct {
+	suffix string
+	letter string
+	// color is an ANSI color code.
+	color int
+}
+
+// touClasses are matched against the end of period names, so "Super Off-Peak" comes before "Off-Peak".
+var touClasses = []touClass{
+	{suffix: "On-Peak", letter: "P", color: 31},
+	{suffix: "Mid-Peak", letter: "M", color: 33},
+	{suffix: "Super Off-Peak", letter: "S", color: 34},
+	{suffix: "Off-Peak", letter: "O", color: 32},
+}
+
+func classOf(period string) *touClass {
+	for i := range touClasses {
+		if strings.HasSuffix(period, touClasses[i].suffix) {
+			return &touClasses[i]
+		}
+	}
+	return nil
+}
+
+// useColor returns whether ANSI colors should be written to out: only for terminals, and not if NO_COLOR is set.
+func useColor(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func writeUsageCharts(out io.Writer, c *UsageCharts) {
+	writeMonthlyBars(out, c.Monthly)
+	writeSparkline(out, c.Daily)
+	writeWeekHeatmap(out, c, useColor(out))
+}
+
+// writeMonthlyBars draws a horizontal bar per month, proportional to the absolute usage.
+func writeMonthlyBars(out io.Writer, months []MonthUsage) {
+	maxKwh := 0.0
+	for _, m := range months {
+		maxKwh = math.Max(maxKwh, math.Abs(m.UsageKwh))
+	}
+	_, _ = fmt.Fprintln(out, "Monthly usage:")
+	for _, m := range months {
+		_, _ = fmt.Fprintf(out, "  %s %-*s %.1f kWh\n", m.Month, barWidth, bar(math.Abs(m.UsageKwh), maxKwh), m.UsageKwh)
+	}
+	_, _ = fmt.Fprintln(out)
+}
+
+// bar returns a bar of barWidth characters for maxValue, in steps of 1/8 of a character.
+func bar(value float64, maxValue float64) string {
+	if maxValue <= 0 {
+		return ""
+	}
+	steps := int(math.Round(value / maxValue * barWidth * 8))
+	out := strings.Repeat(string(eighths[7]), steps/8)
+	if steps%8 > 0 {
+		out += string(eighths[steps%8-1])
+	}
+	return out
+}
+
+// writeSparkline draws the usage of every day, scaled between the lowest and the highest day.
+func writeSparkline(out io.Writer, days []DailyUsage) {
+	if len(days) == 0 {
+		return
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, d := range days {
+		lo, hi = math.Min(lo, d.UsageKwh), math.Max(hi, d.UsageKwh)
+	}
+	_, _ = fmt.Fprintf(out, "Daily usage, from %.1f to %.1f kWh:\n", lo, hi)
+	for start := 0; start < len(days); start += sparklineDays {
+		end := start + sparklineDays
+		if end > len(days) {
+			end = len(days)
+		}
+		line := make([]rune, 0, end-start)
+		for _, d := range days[start:end] {
+			level := len(sparks) / 2
+			if hi > lo {
+				level = int((d.UsageKwh - lo) / (hi - lo) * float64(len(sparks)-1))
+			}
+			line = append(line, sparks[level])
+		}
+		_, _ = fmt.Fprintf(out, "  %s %s\n", days[start].Day, string(line))
+	}
+	_, _ = fmt.Fprintln(out)
+}
+
+// writeWeekHeatmap draws the average hours of the week, shaded by usage. With color, each cell is colored by its TOU
+// period. Without, the periods follow as a grid of letters.
+func writeWeekHeatmap(out io.Writer, c *UsageCharts, color bool) {
+	maxKwh := 0.0
+	for _, d := range c.Week {
+		for _, h := range d.Hours {
+			maxKwh = math.Max(maxKwh, math.Abs(h.MeanKwh))
+		}
+	}
+	_, _ = fmt.Fprintf(out, "Average kWh by hour of the week, up to %.2f kWh, with TOU periods of %s:\n", maxKwh, c.Plan)
+	writeHourHeader(out)
+	for _, d := range c.Week {
+		var line strings.Builder
+		for _, h := range d.Hours {
+			cell := strings.Repeat(shade(h.MeanKwh, maxKwh), 2)
+			if class := classOf(h.Period); color && class != nil {
+				cell = fmt.Sprintf("\x1b[%dm%s\x1b[0m", class.color, cell)
+			}
+			line.WriteString(cell)
+		}
+		_, _ = fmt.Fprintf(out, "  %s %s\n", d.Weekday[:3], line.String())
+	}
+	if !color {
+		writeHourHeader(out)
+		for _, d := range c.Week {
+			var line strings.Builder
+			for _, h := range d.Hours {
+				letter := " "
+				if class := classOf(h.Period); class != nil {
+					letter = class.letter
+				}
+				line.WriteString(strings.Repeat(letter, 2))
+			}
+			_, _ = fmt.Fprintf(out, "  %s %s\n", d.Weekday[:3], line.String())
+		}
+	}
+	legend := make([]string, 0, len(touClasses))
+	for _, class := range touClasses {
+		name := class.letter + " " + class.suffix
+		if color {
+			name = fmt.Sprintf("\x1b[%dm%s\x1b[0m", class.color, class.suffix)
+		}
+		legend = append(legend, name)
+	}
+	_, _ = fmt.Fprintf(out, "  Shades: %s (low to high). Periods: %s.\n\n", strings.Join(shades, ""), strings.Join(legend, ", "))
+}
+
+// writeHourHeader labels every third hour of the heatmap columns, which are two characters wide.
+func writeHourHeader(out io.Writer) {
+	var header strings.Builder
+	for hour := 0; hour < 24; hour += 3 {
+		header.WriteString(fmt.Sprintf("%-6d", hour))
+	}
+	_, _ = fmt.Fprintf(out, "      %s\n", strings.TrimRight(header.String(), " "))
+}
+
+// shade returns the block for the share of maxKwh. Hours without usage are a dot.
+func shade(kwh float64, maxKwh float64) string {
+	if kwh == 0 || maxKwh == 0 {
+		return shades[0]
+	}
+	level := int(math.Ceil(math.Abs(kwh) / maxKwh * float64(len(shades)-1)))
+	if level > len(shades)-1 {
+		level = len(shades) - 1
+	}
+	return shades[level]
+}
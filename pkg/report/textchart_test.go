@@ -0,0 +1,96 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBar_EighthsOfACharacter(t *testing.T) {
+	assert.Equal(t, strings.Repeat("█", barWidth), bar(10, 10))
+	assert.Equal(t, strings.Repeat("█", barWidth/2), bar(5, 10))
+	assert.Equal(t, "▏", bar(1, 8*barWidth))
+	assert.Equal(t, "", bar(0, 10))
+	assert.Equal(t, "", bar(1, 0))
+}
+
+func TestShade(t *testing.T) {
+	assert.Equal(t, "·", shade(0, 2))
+	assert.Equal(t, "░", shade(0.1, 2))
+	assert.Equal(t, "▒", shade(1, 2))
+	assert.Equal(t, "█", shade(2, 2))
+	assert.Equal(t, "█", shade(-2, 2))
+}
+
+func TestClassOf_SuperOffPeakBeforeOffPeak(t *testing.T) {
+	assert.Equal(t, "S", classOf("Summer - Super Off-Peak").letter)
+	assert.Equal(t, "O", classOf("Winter - Off-Peak").letter)
+	assert.Equal(t, "P", classOf("Summer - On-Peak").letter)
+	assert.Nil(t, classOf(""))
+}
+
+func TestWriteSparkline_ScalesBetweenLowestAndHighestDay(t *testing.T) {
+	var out bytes.Buffer
+
+	writeSparkline(&out, []DailyUsage{{Day: "2020-07-01", UsageKwh: 10}, {Day: "2020-07-02", UsageKwh: 20}, {Day: "2020-07-03", UsageKwh: 30}})
+
+	assert.Equal(t, "Daily usage, from 10.0 to 30.0 kWh:\n  2020-07-01 ▁▄█\n\n", out.String())
+}
+
+func TestWriteSparkline_WrapsLines(t *testing.T) {
+	days := make([]DailyUsage, sparklineDays+1)
+	for i := range days {
+		days[i] = DailyUsage{Day: "2020-07-01", UsageKwh: float64(i)}
+	}
+	var out bytes.Buffer
+
+	writeSparkline(&out, days)
+
+	assert.Equal(t, 4, strings.Count(out.String(), "\n"))
+}
+
+func TestWriteWeekHeatmap_NoColor_PrintsPeriodLetters(t *testing.T) {
+	c := &UsageCharts{Plan: "TOU-D-PRIME"}
+	for _, weekday := range []string{"Sunday", "Monday"} {
+		hours := make([]WeekHour, 24)
+		for h := range hours {
+			hours[h] = WeekHour{Hour: h, MeanKwh: 1, Period: "Summer - Off-Peak"}
+		}
+		hours[17] = WeekHour{Hour: 17, MeanKwh: 2, Period: "Summer - On-Peak"}
+		c.Week = append(c.Week, WeekdayUsage{Weekday: weekday, Hours: hours})
+	}
+	var out bytes.Buffer
+
+	writeWeekHeatmap(&out, c, false)
+
+	got := out.String()
+	assert.NotContains(t, got, "\x1b[")
+	assert.Contains(t, got, "  Sun "+strings.Repeat("▒▒", 17)+"██"+strings.Repeat("▒▒", 6)+"\n")
+	assert.Contains(t, got, "  Mon "+strings.Repeat("OO", 17)+"PP"+strings.Repeat("OO", 6)+"\n")
+}
+
+func TestWriteWeekHeatmap_Color_ColorsCells(t *testing.T) {
+	c := &UsageCharts{Plan: "TOU-D-PRIME", Week: []WeekdayUsage{{Weekday: "Sunday", Hours: []WeekHour{{Hour: 0, MeanKwh: 1, Period: "Summer - On-Peak"}}}}}
+	var out bytes.Buffer
+
+	writeWeekHeatmap(&out, c, true)
+
+	assert.Contains(t, out.String(), "\x1b[31m██\x1b[0m")
+	assert.NotContains(t, out.String(), "  Sun PP")
+}
+
+func TestWriteText_UsageCharts_NoColorForBuffers(t *testing.T) {
+	doc := NewDocument("profile")
+	doc.Meters = append(doc.Meters, Section{Meter: "1", UsageCharts: &UsageCharts{
+		Monthly: []MonthUsage{{Month: "2020-07", UsageKwh: 300}},
+		Daily:   []DailyUsage{{Day: "2020-07-01", UsageKwh: 10}},
+	}})
+	var out bytes.Buffer
+
+	assert.NoError(t, WriteText(&out, doc))
+
+	assert.Contains(t, out.String(), "Monthly usage:\n  2020-07 "+strings.Repeat("█", barWidth)+" 300.0 kWh\n")
+	assert.NotContains(t, out.String(), "\x1b[")
+}
@@ -1,7 +1,8 @@
 package sense
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"sort"
 	"time"
 )
@@ -18,20 +19,84 @@ func GroupByDeviceId(in []CsvRow) (map[string][]CsvRow, error) {
 	return out, nil
 }
 
+// GapPolicy decides what a Snapshot contains when Sense did not report a "mains" or "solar" row for a timestamp.
+type GapPolicy int
+
+const (
+	// GapAsZero treats missing rows as 0 kWh.
+	GapAsZero GapPolicy = iota
+	// GapInterpolate linearly interpolates between the nearest timestamps that have data.
+	GapInterpolate
+	// GapFail returns an error on the first missing row.
+	GapFail
+)
+
+// GroupOptions configures GroupByTime.
+type GroupOptions struct {
+	// HasSolar should be false for households without solar. Production is then always 0 and never missing.
+	HasSolar  bool
+	GapPolicy GapPolicy
+}
+
+// DefaultGroupOptions returns the options for a household with solar that treats gaps as zero.
+func DefaultGroupOptions() GroupOptions {
+	return GroupOptions{
+		HasSolar:  true,
+		GapPolicy: GapAsZero,
+	}
+}
+
+// DuplicateRowWarning reports a device that appears more than once within a timestamp. The last row wins.
+type DuplicateRowWarning struct {
+	DateTime time.Time
+	DeviceId string
+	Rows     int
+}
+
+func (w *DuplicateRowWarning) String() string {
+	return fmt.Sprintf("found device ID '%s' %d times within timestamp %+v", w.DeviceId, w.Rows, w.DateTime)
+}
+
+// SnapshotDuration is the resolution of Sense data exports.
+const SnapshotDuration = time.Hour
+
 type Snapshot struct {
 	DateTime       time.Time
-	ProductionKwh  *float64
-	ConsumptionKwh *float64
+	ProductionKwh  float64
+	ConsumptionKwh float64
+
+	// MissingProduction and MissingConsumption are true when Sense did not report the row for this timestamp.
+	// The corresponding value was then filled in according to the GapPolicy.
+	MissingProduction  bool
+	MissingConsumption bool
 }
 
 // NetUsageKwh returns the energy consumed from the grid.
 func (s *Snapshot) NetUsageKwh() float64 {
-	return *s.ConsumptionKwh + *s.ProductionKwh
+	return s.ConsumptionKwh + s.ProductionKwh
+}
+
+// UsageKwh returns the net usage, so that snapshots can be resampled with the timebucket package.
+func (s *Snapshot) UsageKwh() float64 {
+	return s.NetUsageKwh()
+}
+
+func (s *Snapshot) StartTime() time.Time {
+	return s.DateTime
+}
+
+func (s *Snapshot) EndTime() time.Time {
+	return s.DateTime.Add(SnapshotDuration)
+}
+
+// HasMissingData returns true if any of the values in the snapshot was filled in.
+func (s *Snapshot) HasMissingData() bool {
+	return s.MissingProduction || s.MissingConsumption
 }
 
 // GroupByTime returns a slice of Snapshot objects summarizing a single point in time.
-// The objects are returned in chronological order.
-func GroupByTime(in []CsvRow) ([]Snapshot, error) {
+// The objects are returned in chronological order, along with a warning for every duplicated device row.
+func GroupByTime(in []CsvRow, opts GroupOptions) ([]Snapshot, []DuplicateRowWarning, error) {
 	rowsByTime := make(map[time.Time][]CsvRow)
 
 	for _, point := range in {
@@ -40,38 +105,135 @@ func GroupByTime(in []CsvRow) ([]Snapshot, error) {
 	}
 
 	out := make([]Snapshot, 0)
+	warnings := make([]DuplicateRowWarning, 0)
 	for t, rows := range rowsByTime {
-		mainsEnergy, err := findEnergy("mains", rows)
-		if err != nil {
-			return nil, err
+		snapshot := Snapshot{DateTime: t}
+
+		mainsEnergy, warning := findEnergy("mains", rows)
+		if warning != nil {
+			warnings = append(warnings, *warning)
+		}
+		if mainsEnergy != nil {
+			snapshot.ConsumptionKwh = *mainsEnergy
+		} else {
+			snapshot.MissingConsumption = true
 		}
-		solarEnergy, err := findEnergy("solar", rows)
-		if err != nil {
-			return nil, err
+
+		if opts.HasSolar {
+			solarEnergy, warning := findEnergy("solar", rows)
+			if warning != nil {
+				warnings = append(warnings, *warning)
+			}
+			if solarEnergy != nil {
+				snapshot.ProductionKwh = *solarEnergy
+			} else {
+				snapshot.MissingProduction = true
+			}
 		}
 
-		out = append(out, Snapshot{
-			DateTime:       t,
-			ProductionKwh:  solarEnergy,
-			ConsumptionKwh: mainsEnergy,
-		})
+		out = append(out, snapshot)
 	}
 	sort.Slice(out, func(i, j int) bool {
 		return out[i].DateTime.Before(out[j].DateTime)
 	})
-	return out, nil
+	sort.Slice(warnings, func(i, j int) bool {
+		if warnings[i].DateTime.Equal(warnings[j].DateTime) {
+			return warnings[i].DeviceId < warnings[j].DeviceId
+		}
+		return warnings[i].DateTime.Before(warnings[j].DateTime)
+	})
+
+	if err := fillGaps(out, opts.GapPolicy); err != nil {
+		return nil, nil, err
+	}
+	return out, warnings, nil
 }
 
-func findEnergy(deviceId string, rows []CsvRow) (*float64, error) {
+// fillGaps replaces the missing values in the snapshots according to the policy.
+func fillGaps(snapshots []Snapshot, policy GapPolicy) error {
+	consumption := func(s *Snapshot) (*float64, bool) { return &s.ConsumptionKwh, s.MissingConsumption }
+	production := func(s *Snapshot) (*float64, bool) { return &s.ProductionKwh, s.MissingProduction }
+
+	for _, field := range []struct {
+		deviceId string
+		get      func(s *Snapshot) (*float64, bool)
+	}{{"mains", consumption}, {"solar", production}} {
+		for i := range snapshots {
+			value, missing := field.get(&snapshots[i])
+			if !missing {
+				continue
+			}
+			switch policy {
+			case GapAsZero:
+				*value = 0
+			case GapFail:
+				return fmt.Errorf("no '%s' row at %+v", field.deviceId, snapshots[i].DateTime)
+			case GapInterpolate:
+				interpolated, err := interpolate(snapshots, i, field.get)
+				if err != nil {
+					return fmt.Errorf("unable to interpolate '%s' at %+v: %w", field.deviceId, snapshots[i].DateTime, err)
+				}
+				*value = interpolated
+			default:
+				return fmt.Errorf("unknown gap policy %d", policy)
+			}
+		}
+	}
+	return nil
+}
+
+// interpolate returns a linear interpolation between the closest snapshots before and after index i that have a
+// value. If only one side has a value, it is used as is.
+func interpolate(snapshots []Snapshot, i int, get func(s *Snapshot) (*float64, bool)) (float64, error) {
+	before, after := -1, -1
+	for j := i - 1; j >= 0; j-- {
+		if _, missing := get(&snapshots[j]); !missing {
+			before = j
+			break
+		}
+	}
+	for j := i + 1; j < len(snapshots); j++ {
+		if _, missing := get(&snapshots[j]); !missing {
+			after = j
+			break
+		}
+	}
+
+	switch {
+	case before < 0 && after < 0:
+		return 0, errors.New("no data to interpolate from")
+	case before < 0:
+		v, _ := get(&snapshots[after])
+		return *v, nil
+	case after < 0:
+		v, _ := get(&snapshots[before])
+		return *v, nil
+	}
+	beforeValue, _ := get(&snapshots[before])
+	afterValue, _ := get(&snapshots[after])
+	span := snapshots[after].DateTime.Sub(snapshots[before].DateTime)
+	offset := snapshots[i].DateTime.Sub(snapshots[before].DateTime)
+	return *beforeValue + (*afterValue-*beforeValue)*float64(offset)/float64(span), nil
+}
+
+// findEnergy returns the energy of the device within rows, or nil if the device is not present.
+// If the device appears more than once, the last row wins and a warning is returned.
+func findEnergy(deviceId string, rows []CsvRow) (*float64, *DuplicateRowWarning) {
 	var found *float64 = nil
+	count := 0
 	for _, row := range rows {
 		if row.DeviceId == deviceId {
-			if found != nil {
-				log.Printf("found device ID '%s' twice within timestamp %+v\n", deviceId, row.DateTime)
-			}
+			count++
 			energy := row.EnergyKwh
 			found = &energy
 		}
 	}
+	if count > 1 {
+		return found, &DuplicateRowWarning{
+			DateTime: rows[0].DateTime.Value,
+			DeviceId: deviceId,
+			Rows:     count,
+		}
+	}
 	return found, nil
 }
@@ -34,6 +34,7 @@ func TestGroupByDeviceId_DifferentIdSplit(t *testing.T) {
 var (
 	t0 = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
 	t1 = t0.Add(1 * time.Hour)
+	t2 = t0.Add(2 * time.Hour)
 )
 
 func TestGroupByTime_SameTime_ReturnsOneElement(t *testing.T) {
@@ -42,8 +43,9 @@ func TestGroupByTime_SameTime_ReturnsOneElement(t *testing.T) {
 		{DeviceId: "solar", EnergyKwh: -5, DateTime: DateTime{Value: t0}},
 	}
 
-	got, err := GroupByTime(in)
+	got, warnings, err := GroupByTime(in, DefaultGroupOptions())
 	assert.NoError(t, err)
+	assert.Empty(t, warnings)
 
 	assert.Len(t, got, 1)
 }
@@ -56,8 +58,9 @@ func TestGroupByTime_DifferentTimes_ReturnsSeparateElements(t *testing.T) {
 		{DeviceId: "solar", EnergyKwh: -5, DateTime: DateTime{Value: t1}},
 	}
 
-	got, err := GroupByTime(in)
+	got, warnings, err := GroupByTime(in, DefaultGroupOptions())
 	assert.NoError(t, err)
+	assert.Empty(t, warnings)
 
 	assert.Len(t, got, 2)
 }
@@ -67,10 +70,82 @@ func TestGroupByTime_AggregatesProductionAndConsumptionFromMainsAndSolar(t *test
 		{DeviceId: "solar", EnergyKwh: -5, DateTime: DateTime{Value: t0}},
 	}
 
-	got, err := GroupByTime(in)
+	got, warnings, err := GroupByTime(in, DefaultGroupOptions())
 	assert.NoError(t, err)
+	assert.Empty(t, warnings)
 
 	assert.Len(t, got, 1)
-	assert.Equal(t, -5.0, *got[0].ProductionKwh)
-	assert.Equal(t, 10.0, *got[0].ConsumptionKwh)
+	assert.Equal(t, -5.0, got[0].ProductionKwh)
+	assert.Equal(t, 10.0, got[0].ConsumptionKwh)
+}
+
+func TestGroupByTime_NoSolarAccount_ProductionIsZeroAndNotMissing(t *testing.T) {
+	in := []CsvRow{
+		{DeviceId: "mains", EnergyKwh: 10, DateTime: DateTime{Value: t0}},
+	}
+
+	got, _, err := GroupByTime(in, GroupOptions{HasSolar: false, GapPolicy: GapFail})
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, 0.0, got[0].ProductionKwh)
+	assert.False(t, got[0].MissingProduction)
+	assert.Equal(t, 10.0, got[0].NetUsageKwh())
+}
+
+func TestGroupByTime_MissingSolarRow_MarkedAndZeroed(t *testing.T) {
+	in := []CsvRow{
+		{DeviceId: "mains", EnergyKwh: 10, DateTime: DateTime{Value: t0}},
+	}
+
+	got, _, err := GroupByTime(in, DefaultGroupOptions())
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 1)
+	assert.True(t, got[0].MissingProduction)
+	assert.False(t, got[0].MissingConsumption)
+	assert.Equal(t, 0.0, got[0].ProductionKwh)
+}
+
+func TestGroupByTime_GapFail_ReturnsError(t *testing.T) {
+	in := []CsvRow{
+		{DeviceId: "solar", EnergyKwh: -5, DateTime: DateTime{Value: t0}},
+	}
+
+	_, _, err := GroupByTime(in, GroupOptions{HasSolar: true, GapPolicy: GapFail})
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "mains")
+	}
+}
+
+func TestGroupByTime_GapInterpolate_UsesNeighbors(t *testing.T) {
+	in := []CsvRow{
+		{DeviceId: "mains", EnergyKwh: 10, DateTime: DateTime{Value: t0}},
+		{DeviceId: "solar", EnergyKwh: 0, DateTime: DateTime{Value: t0}},
+		{DeviceId: "solar", EnergyKwh: 0, DateTime: DateTime{Value: t1}},
+		{DeviceId: "mains", EnergyKwh: 20, DateTime: DateTime{Value: t2}},
+		{DeviceId: "solar", EnergyKwh: 0, DateTime: DateTime{Value: t2}},
+	}
+
+	got, _, err := GroupByTime(in, GroupOptions{HasSolar: true, GapPolicy: GapInterpolate})
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 3)
+	assert.True(t, got[1].MissingConsumption)
+	assert.Equal(t, 15.0, got[1].ConsumptionKwh)
+}
+
+func TestGroupByTime_DuplicateRows_ReturnsWarning(t *testing.T) {
+	in := []CsvRow{
+		{DeviceId: "mains", EnergyKwh: 10, DateTime: DateTime{Value: t0}},
+		{DeviceId: "mains", EnergyKwh: 12, DateTime: DateTime{Value: t0}},
+		{DeviceId: "solar", EnergyKwh: -5, DateTime: DateTime{Value: t0}},
+	}
+
+	got, warnings, err := GroupByTime(in, DefaultGroupOptions())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 12.0, got[0].ConsumptionKwh)
+	assert.Equal(t, []DuplicateRowWarning{{DateTime: t0, DeviceId: "mains", Rows: 2}}, warnings)
 }
@@ -1,13 +1,19 @@
-// DO NOT SUBMIT: This is the same as pkg/analyzer/daysplitter.go, but the types are different. Refactor
 package sense
 
-import "time"
+import (
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/timebucket"
+)
 
 type Day struct {
 	Day            time.Time
 	DataPoints     []Snapshot
 	ProductionKwh  float64
 	ConsumptionKwh float64
+
+	// MissingDataPoints is the number of snapshots with values filled in by the GapPolicy.
+	MissingDataPoints int
 }
 
 // NetUsageKwh returns the energy consumed from the grid.
@@ -16,53 +22,31 @@ func (d *Day) NetUsageKwh() float64 {
 }
 
 func SplitByDay(in []Snapshot) ([]Day, error) {
-	yearMonthDayMap := make(map[time.Time]*Day)
-	// Keep track of the order in which keys were added.
-	sortedKeys := make([]time.Time, 0)
-
-	for _, hr := range in {
-		dayBucket := truncateToDay(hr.DateTime)
-		day, ok := yearMonthDayMap[dayBucket]
-		if !ok {
-			sortedKeys = append(sortedKeys, dayBucket)
-			day = &Day{
-				Day:            dayBucket,
-				DataPoints:     make([]Snapshot, 0),
-				ProductionKwh:  0,
-				ConsumptionKwh: 0}
-			yearMonthDayMap[dayBucket] = day
-		}
-		// Append the data to the day
-		day.DataPoints = append(day.DataPoints, hr)
-		day.ProductionKwh += *hr.ProductionKwh
-		day.ConsumptionKwh += *hr.ConsumptionKwh
+	points := make([]timebucket.Interval, len(in))
+	for i := range in {
+		points[i] = &in[i]
 	}
-
-	values := make([]Day, 0)
-	for _, k := range sortedKeys {
-		values = append(values, *yearMonthDayMap[k])
+	buckets, err := timebucket.Resample(points, timebucket.Daily())
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate data points
-	for _, v := range values {
-		expectedYear := v.Day.Year()
-		expectedMonth := v.Day.Month()
-		expectedDay := v.Day.Day()
-		for _, p := range v.DataPoints {
-			if p.DateTime.Day() != expectedDay {
-				panic("Wrong day within UsageDay")
-			}
-			if p.DateTime.Month() != expectedMonth {
-				panic("Wrong month within UsageDay")
-			}
-			if p.DateTime.Year() != expectedYear {
-				panic("Wrong year within UsageDay")
+	values := make([]Day, 0, len(buckets))
+	for _, b := range buckets {
+		day := Day{
+			Day:        b.Start,
+			DataPoints: make([]Snapshot, 0, len(b.Points)),
+		}
+		for _, p := range b.Points {
+			snapshot := *p.(*Snapshot)
+			day.DataPoints = append(day.DataPoints, snapshot)
+			day.ProductionKwh += snapshot.ProductionKwh
+			day.ConsumptionKwh += snapshot.ConsumptionKwh
+			if snapshot.HasMissingData() {
+				day.MissingDataPoints++
 			}
 		}
+		values = append(values, day)
 	}
 	return values, nil
 }
-
-func truncateToDay(in time.Time) time.Time {
-	return time.Date(in.Year(), in.Month(), in.Day(), 0, 0, 0, 0, in.Location())
-}
@@ -0,0 +1,26 @@
+package sense
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitByDay_MissingSolar_CountsMissingDataPoints(t *testing.T) {
+	in := []CsvRow{
+		{DeviceId: "mains", EnergyKwh: 10, DateTime: DateTime{Value: t0}},
+		{DeviceId: "mains", EnergyKwh: 5, DateTime: DateTime{Value: t1}},
+		{DeviceId: "solar", EnergyKwh: -2, DateTime: DateTime{Value: t1}},
+	}
+	snapshots, _, err := GroupByTime(in, DefaultGroupOptions())
+	assert.NoError(t, err)
+
+	got, err := SplitByDay(snapshots)
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, 15.0, got[0].ConsumptionKwh)
+	assert.Equal(t, -2.0, got[0].ProductionKwh)
+	assert.Equal(t, 13.0, got[0].NetUsageKwh())
+	assert.Equal(t, 1, got[0].MissingDataPoints)
+}
@@ -0,0 +1,387 @@
+// Package server exposes the parsing, analysis and billing functions over a local HTTP JSON API. Responses use the
+// Document schema of the report package. The endpoints are described by openapi.json, which is served at
+// /openapi.json.
+package server
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/csvparser"
+	"github.com/kodek/sce-greenbutton/pkg/report"
+)
+
+//go:embed openapi.json
+var openAPI []byte
+
+// Options configure a Server.
+type Options struct {
+	// Plans are the plans that can be billed, by name.
+	Plans        []costcalculator.TouPlan
+	ParseOptions csvparser.ParseOptions
+	// MaxUploadBytes limits the size of an uploaded file.
+	MaxUploadBytes int64
+	// MaxDatasets is the number of datasets kept in memory. The oldest upload is dropped first.
+	MaxDatasets int
+}
+
+// DefaultOptions returns options for the given plans.
+func DefaultOptions(plans []costcalculator.TouPlan) Options {
+	return Options{
+		Plans:          plans,
+		ParseOptions:   csvparser.DefaultParseOptions(),
+		MaxUploadBytes: 64 << 20,
+		MaxDatasets:    16,
+	}
+}
+
+// Server is an http.Handler for the API. Uploaded datasets are cached in memory by the SHA-256 hash of the upload,
+// so uploading the same file again is cheap.
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+
+	mu       sync.Mutex
+	datasets map[string]*dataset
+	// order has the dataset IDs from the oldest upload to the newest.
+	order []string
+}
+
+// dataset is the parsed readings of an upload, split by meter.
+type dataset struct {
+	id       string
+	readings int
+	meters   []meterData
+}
+
+// meterData is the readings of a meter aggregated into hours and days. With several meters, the last one has the
+// readings of all of them added up.
+type meterData struct {
+	name     string
+	combined bool
+	rows     csvparser.CsvFile
+	hours    []analyzer.UsageHour
+	days     []analyzer.UsageDay
+}
+
+// Upload is the response to an upload.
+type Upload struct {
+	// ID is the hex-encoded SHA-256 hash of the upload.
+	ID       string   `json:"id"`
+	Readings int      `json:"readings"`
+	Meters   []string `json:"meters"`
+	// Cached is true if the same file was already uploaded.
+	Cached bool `json:"cached"`
+}
+
+// PlanList is the response listing the plans.
+type PlanList struct {
+	Plans []report.Plan `json:"plans"`
+}
+
+// Error is the response to a failed request.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// New returns a server with the given options.
+func New(opts Options) *Server {
+	s := &Server{opts: opts, mux: http.NewServeMux(), datasets: make(map[string]*dataset)}
+	s.mux.HandleFunc("/openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("/plans", s.handlePlans)
+	s.mux.HandleFunc("/datasets", s.handleUpload)
+	s.mux.HandleFunc("/datasets/", s.handleDataset)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// httpError is an error with the status code of its response.
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+func errorf(status int, format string, a ...interface{}) error {
+	return &httpError{status: status, msg: fmt.Sprintf(format, a...)}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+// writeError responds with the status of an *httpError, or 500 for other errors.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var httpErr *httpError
+	if errors.As(err, &httpErr) {
+		status = httpErr.status
+	}
+	writeJSON(w, status, Error{Error: err.Error()})
+}
+
+// allowMethod responds with 405 and returns false if the request doesn't use the method.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, errorf(http.StatusMethodNotAllowed, "method %s not allowed, use %s", r.Method, method))
+	return false
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPI)
+}
+
+func (s *Server) handlePlans(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	out := PlanList{Plans: make([]report.Plan, 0, len(s.opts.Plans))}
+	for _, p := range s.opts.Plans {
+		out.Plans = append(out.Plans, report.NewPlan(p))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleUpload parses an uploaded Green Button file, which may be gzip or zip compressed, and caches it.
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, s.opts.MaxUploadBytes))
+	if err != nil {
+		// MaxBytesReader doesn't return a distinct error type before Go 1.19.
+		if strings.Contains(err.Error(), "request body too large") {
+			err = errorf(http.StatusRequestEntityTooLarge, "upload is larger than %d bytes", s.opts.MaxUploadBytes)
+		} else {
+			err = errorf(http.StatusBadRequest, "unable to read upload: %v", err)
+		}
+		writeError(w, err)
+		return
+	}
+	sum := sha256.Sum256(body)
+	id := hex.EncodeToString(sum[:])
+
+	d, cached := s.lookup(id)
+	if !cached {
+		if d, err = s.parse(id, body); err != nil {
+			writeError(w, err)
+			return
+		}
+		s.store(d)
+	}
+	out := Upload{ID: d.id, Readings: d.readings, Meters: make([]string, 0, len(d.meters)), Cached: cached}
+	for _, m := range d.meters {
+		if !m.combined {
+			out.Meters = append(out.Meters, m.name)
+		}
+	}
+	status := http.StatusCreated
+	if cached {
+		status = http.StatusOK
+	}
+	w.Header().Set("Location", "/datasets/"+id)
+	writeJSON(w, status, out)
+}
+
+func (s *Server) parse(id string, body []byte) (*dataset, error) {
+	file, err := csvparser.OpenBytes(body)
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "unable to read upload: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	rows, _, err := csvparser.ParseDownload(file, s.opts.ParseOptions)
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "unable to parse upload: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, errorf(http.StatusBadRequest, "upload has no readings")
+	}
+
+	d := &dataset{id: id, readings: len(rows)}
+	meters := analyzer.SplitByMeter(rows)
+	for _, m := range meters {
+		data, err := newMeterData(m.Meter.String(), m.Rows)
+		if err != nil {
+			return nil, err
+		}
+		d.meters = append(d.meters, data)
+	}
+	if len(meters) > 1 {
+		data, err := newMeterData("All meters", analyzer.CombineMeters(meters))
+		if err != nil {
+			return nil, err
+		}
+		data.combined = true
+		d.meters = append(d.meters, data)
+	}
+	return d, nil
+}
+
+func newMeterData(name string, rows csvparser.CsvFile) (meterData, error) {
+	hours, err := analyzer.AggregateIntoHourWindows(rows)
+	if err != nil {
+		return meterData{}, errorf(http.StatusBadRequest, "meter %s: %v", name, err)
+	}
+	days, err := analyzer.SplitByDay(hours)
+	if err != nil {
+		return meterData{}, errorf(http.StatusBadRequest, "meter %s: %v", name, err)
+	}
+	return meterData{name: name, rows: rows, hours: hours, days: days}, nil
+}
+
+func (s *Server) lookup(id string) (*dataset, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.datasets[id]
+	return d, ok
+}
+
+// store caches the dataset, dropping the oldest ones beyond MaxDatasets.
+func (s *Server) store(d *dataset) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.datasets[d.id]; ok {
+		return
+	}
+	s.datasets[d.id] = d
+	s.order = append(s.order, d.id)
+	for len(s.order) > s.opts.MaxDatasets {
+		delete(s.datasets, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// handleDataset serves /datasets/{id}, /datasets/{id}/bills and /datasets/{id}/profile.
+func (s *Server) handleDataset(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/datasets/"), "/")
+	if len(parts) > 2 {
+		writeError(w, errorf(http.StatusNotFound, "no such endpoint %s", r.URL.Path))
+		return
+	}
+	d, ok := s.lookup(parts[0])
+	if !ok {
+		writeError(w, errorf(http.StatusNotFound, "no dataset '%s'; upload it to /datasets", parts[0]))
+		return
+	}
+	resource := ""
+	if len(parts) == 2 {
+		resource = parts[1]
+	}
+
+	var doc *report.Document
+	var err error
+	switch resource {
+	case "":
+		doc, err = summary(d)
+	case "bills":
+		doc, err = s.bills(d, r)
+	case "profile":
+		doc = profile(d)
+	default:
+		err = errorf(http.StatusNotFound, "no such endpoint %s", r.URL.Path)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// summary returns the data quality and dataset summary of each meter.
+func summary(d *dataset) (*report.Document, error) {
+	doc := report.NewDocument("summary")
+	for _, m := range d.meters {
+		section := report.Section{Meter: m.name, Combined: m.combined}
+		if !m.combined {
+			section.Quality = report.NewQuality(analyzer.CheckQuality(m.rows), len(m.rows))
+		}
+		var err error
+		if section.Dataset, err = report.NewDataset(m.rows, m.hours, m.days); err != nil {
+			return nil, err
+		}
+		doc.Meters = append(doc.Meters, section)
+	}
+	return doc, nil
+}
+
+// bills bills each meter on the plans named by the plan query parameters, or on every plan. With monthly=true, the
+// bills include monthly statements.
+func (s *Server) bills(d *dataset, r *http.Request) (*report.Document, error) {
+	query := r.URL.Query()
+	plans := s.opts.Plans
+	if names := query["plan"]; len(names) > 0 {
+		plans = make([]costcalculator.TouPlan, 0, len(names))
+		for _, name := range names {
+			plan := s.findPlan(name)
+			if plan == nil {
+				return nil, errorf(http.StatusBadRequest, "unknown plan '%s'", name)
+			}
+			plans = append(plans, plan)
+		}
+	}
+	monthly := false
+	if v := query.Get("monthly"); v != "" {
+		if v != "true" && v != "false" {
+			return nil, errorf(http.StatusBadRequest, "invalid monthly '%s', expected true or false", v)
+		}
+		monthly = v == "true"
+	}
+
+	doc := report.NewDocument("compare")
+	for _, m := range d.meters {
+		section := report.Section{Meter: m.name, Combined: m.combined}
+		for _, plan := range plans {
+			section.Bills = append(section.Bills, report.NewBill(m.days, plan, monthly))
+		}
+		doc.Meters = append(doc.Meters, section)
+	}
+	return doc, nil
+}
+
+func (s *Server) findPlan(name string) costcalculator.TouPlan {
+	for _, p := range s.opts.Plans {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// profile returns the load profile of each meter.
+func profile(d *dataset) *report.Document {
+	doc := report.NewDocument("profile")
+	for _, m := range d.meters {
+		doc.Meters = append(doc.Meters, report.Section{Meter: m.name, Combined: m.combined, Profile: report.NewProfile(m.days)})
+	}
+	return doc
+}
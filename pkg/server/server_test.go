@@ -0,0 +1,292 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/report"
+	"github.com/stretchr/testify/assert"
+)
+
+const testInput = "../csvparser/testdata/two_days_constant_power.csv"
+
+func newTestServer() *httptest.Server {
+	plans := []costcalculator.TouPlan{costcalculator.NewTouDAPlan(), costcalculator.NewTouDPrime()}
+	return httptest.NewServer(New(DefaultOptions(plans)))
+}
+
+func readTestInput(t *testing.T) []byte {
+	data, err := ioutil.ReadFile(testInput)
+	assert.NoError(t, err)
+	return data
+}
+
+// do sends a request and decodes the JSON response into out, if set.
+func do(t *testing.T, method string, url string, body []byte, out interface{}) *http.Response {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if out != nil {
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp
+}
+
+func uploadOrDie(t *testing.T, ts *httptest.Server, data []byte) Upload {
+	var got Upload
+	resp := do(t, http.MethodPost, ts.URL+"/datasets", data, &got)
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload failed with status %d", resp.StatusCode)
+	}
+	return got
+}
+
+func TestUpload_IdIsHashOfFile(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	data := readTestInput(t)
+
+	var got Upload
+	resp := do(t, http.MethodPost, ts.URL+"/datasets", data, &got)
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), got.ID)
+	assert.Equal(t, "/datasets/"+got.ID, resp.Header.Get("Location"))
+	assert.Equal(t, 192, got.Readings)
+	assert.Equal(t, []string{"CA FOO ST MY CITY 12345"}, got.Meters)
+	assert.False(t, got.Cached)
+}
+
+func TestUpload_SameFileTwice_Cached(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	data := readTestInput(t)
+	first := uploadOrDie(t, ts, data)
+
+	var got Upload
+	resp := do(t, http.MethodPost, ts.URL+"/datasets", data, &got)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, got.Cached)
+	assert.Equal(t, first.ID, got.ID)
+}
+
+func TestUpload_Gzip(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(readTestInput(t))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+
+	got := uploadOrDie(t, ts, buf.Bytes())
+
+	assert.Equal(t, 192, got.Readings)
+}
+
+func TestUpload_EspiXml_MeterPerUsagePoint(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	data, err := ioutil.ReadFile("../csvparser/testdata/espi_two_usage_points.xml")
+	assert.NoError(t, err)
+
+	got := uploadOrDie(t, ts, data)
+
+	assert.Equal(t, 4, got.Readings)
+	assert.Equal(t, []string{"1", "2"}, got.Meters)
+}
+
+func TestUpload_Malformed_BadRequest(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var got Error
+	resp := do(t, http.MethodPost, ts.URL+"/datasets", []byte("2020-05-01 00:00:00 to 2020-05-01 00:15:00,not a number\n"), &got)
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, got.Error, "unable to parse upload")
+}
+
+func TestUpload_Empty_BadRequest(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var got Error
+	resp := do(t, http.MethodPost, ts.URL+"/datasets", nil, &got)
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "upload has no readings", got.Error)
+}
+
+func TestUpload_TooLarge(t *testing.T) {
+	opts := DefaultOptions(nil)
+	opts.MaxUploadBytes = 10
+	ts := httptest.NewServer(New(opts))
+	defer ts.Close()
+
+	resp := do(t, http.MethodPost, ts.URL+"/datasets", readTestInput(t), nil)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
+
+func TestUpload_Get_MethodNotAllowed(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	resp := do(t, http.MethodGet, ts.URL+"/datasets", nil, nil)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, http.MethodPost, resp.Header.Get("Allow"))
+}
+
+func TestStore_DropsOldestDatasets(t *testing.T) {
+	opts := DefaultOptions(nil)
+	opts.MaxDatasets = 2
+	s := New(opts)
+
+	for _, id := range []string{"a", "b", "c"} {
+		s.store(&dataset{id: id})
+	}
+
+	_, ok := s.lookup("a")
+	assert.False(t, ok)
+	_, ok = s.lookup("c")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"b", "c"}, s.order)
+}
+
+func TestPlans_ListsRates(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var got PlanList
+	resp := do(t, http.MethodGet, ts.URL+"/plans", nil, &got)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, got.Plans, 2)
+	assert.Equal(t, "TOU-D-A", got.Plans[0].Name)
+	assert.NotEmpty(t, got.Plans[0].Rates)
+}
+
+func TestDataset_Summary(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	upload := uploadOrDie(t, ts, readTestInput(t))
+
+	var got report.Document
+	resp := do(t, http.MethodGet, ts.URL+"/datasets/"+upload.ID, nil, &got)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, report.SchemaVersion, got.SchemaVersion)
+	assert.Len(t, got.Meters, 1)
+	assert.Equal(t, 2, got.Meters[0].Dataset.Days)
+	assert.Equal(t, 192, got.Meters[0].Quality.Readings)
+}
+
+func TestDataset_Unknown_NotFound(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var got Error
+	resp := do(t, http.MethodGet, ts.URL+"/datasets/nope/bills", nil, &got)
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Contains(t, got.Error, "no dataset 'nope'")
+}
+
+func TestBills_AllPlans(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	upload := uploadOrDie(t, ts, readTestInput(t))
+
+	var got report.Document
+	resp := do(t, http.MethodGet, ts.URL+"/datasets/"+upload.ID+"/bills", nil, &got)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	bills := got.Meters[0].Bills
+	assert.Len(t, bills, 2)
+	assert.Equal(t, "TOU-D-A", bills[0].Plan)
+	assert.Equal(t, "TOU-D-PRIME", bills[1].Plan)
+	assert.Nil(t, bills[0].Monthly)
+	assert.Greater(t, bills[0].TotalCost, 0.0)
+}
+
+func TestBills_SelectedPlanMonthly(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	upload := uploadOrDie(t, ts, readTestInput(t))
+
+	var got report.Document
+	resp := do(t, http.MethodGet, ts.URL+"/datasets/"+upload.ID+"/bills?plan=TOU-D-PRIME&monthly=true", nil, &got)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	bills := got.Meters[0].Bills
+	assert.Len(t, bills, 1)
+	assert.Equal(t, "TOU-D-PRIME", bills[0].Plan)
+	assert.Len(t, bills[0].Monthly, 1)
+}
+
+func TestBills_UnknownPlan_BadRequest(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	upload := uploadOrDie(t, ts, readTestInput(t))
+
+	var got Error
+	resp := do(t, http.MethodGet, ts.URL+"/datasets/"+upload.ID+"/bills?plan=TOU-X", nil, &got)
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "unknown plan 'TOU-X'", got.Error)
+}
+
+func TestProfile(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	upload := uploadOrDie(t, ts, readTestInput(t))
+
+	var got report.Document
+	resp := do(t, http.MethodGet, ts.URL+"/datasets/"+upload.ID+"/profile", nil, &got)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.InDelta(t, 1.0, got.Meters[0].Profile.BaseloadKw, 0.0001)
+	assert.NotEmpty(t, got.Meters[0].Profile.Shapes)
+}
+
+func TestDataset_UnknownEndpoint_NotFound(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+	upload := uploadOrDie(t, ts, readTestInput(t))
+
+	resp := do(t, http.MethodGet, ts.URL+"/datasets/"+upload.ID+"/weather", nil, nil)
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestOpenAPI_DescribesEveryEndpoint(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var got map[string]interface{}
+	resp := do(t, http.MethodGet, ts.URL+"/openapi.json", nil, &got)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(got["openapi"].(string), "3."))
+	paths := got["paths"].(map[string]interface{})
+	for _, p := range []string{"/plans", "/datasets", "/datasets/{id}", "/datasets/{id}/bills", "/datasets/{id}/profile", "/openapi.json"} {
+		assert.Contains(t, paths, p)
+	}
+}
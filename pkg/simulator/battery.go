@@ -0,0 +1,64 @@
+package simulator
+
+import (
+	"math"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+)
+
+// Battery is a home battery.
+type Battery struct {
+	CapacityKwh float64
+	PowerKw     float64
+	// RoundTripEfficiency is the fraction of the charged energy that can be discharged, such as 0.9.
+	RoundTripEfficiency float64
+}
+
+// BatteryStats summarizes a dispatch.
+type BatteryStats struct {
+	ChargedKwh    float64
+	DischargedKwh float64
+}
+
+// Dispatch runs the battery over the hourly net load, which must be sorted chronologically. The battery only
+// charges from energy that would otherwise be exported, and discharges to cover the load in hours that cost more
+// than the cheapest hour of the day. It starts empty. Losses are applied when charging.
+func Dispatch(hours []analyzer.UsageHour, b Battery, plan costcalculator.TouPlan) ([]analyzer.UsageHour, BatteryStats) {
+	out := make([]analyzer.UsageHour, len(hours))
+	stats := BatteryStats{}
+	stored := 0.0
+	cheapest := make(map[time.Time]float64)
+	for i, hr := range hours {
+		usage := hr.UsageKwh()
+		maxEnergy := b.PowerKw * hr.EndTime().Sub(hr.StartTime()).Hours()
+		if usage < 0 {
+			charge := math.Min(math.Min(-usage, maxEnergy), (b.CapacityKwh-stored)/b.RoundTripEfficiency)
+			stored += charge * b.RoundTripEfficiency
+			stats.ChargedKwh += charge
+			usage += charge
+		} else if usage > 0 && costcalculator.MarginalRate(hr.StartTime(), plan) > cheapestOfDay(hr.StartTime(), plan, cheapest) {
+			discharge := math.Min(math.Min(usage, maxEnergy), stored)
+			stored -= discharge
+			stats.DischargedKwh += discharge
+			usage -= discharge
+		}
+		out[i] = analyzer.NewUsageHour(hr.StartTime(), hr.EndTime(), usage)
+	}
+	return out, stats
+}
+
+// cheapestOfDay returns the lowest rate of the plan on the day containing t, caching it by day.
+func cheapestOfDay(t time.Time, plan costcalculator.TouPlan, cache map[time.Time]float64) float64 {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if rate, ok := cache[day]; ok {
+		return rate
+	}
+	rate := math.Inf(1)
+	for h := 0; h < 24; h++ {
+		rate = math.Min(rate, costcalculator.MarginalRate(day.Add(time.Duration(h)*time.Hour), plan))
+	}
+	cache[day] = rate
+	return rate
+}
@@ -0,0 +1,47 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/stretchr/testify/assert"
+)
+
+// solarDay returns a day that exports 3 kWh per hour from 10am to 2pm and uses 1 kWh per hour otherwise.
+func solarDay(day time.Time) []analyzer.UsageHour {
+	return hourlyLoad(day, 1, func(_ int, h int) float64 {
+		if h >= 10 && h < 14 {
+			return -3
+		}
+		return 1
+	})
+}
+
+func TestDispatch_ChargesFromExportAndDischargesAtPeak(t *testing.T) {
+	b := Battery{CapacityKwh: 9, PowerKw: 5, RoundTripEfficiency: 0.9}
+
+	got, stats := Dispatch(solarDay(summerWeekday), b, costcalculator.NewTouDPrime())
+
+	// 10 kWh of the 12 exported kWh fit in a 9 kWh battery.
+	assert.InDelta(t, 10.0, stats.ChargedKwh, 0.0001)
+	assert.Equal(t, 0.0, got[10].UsageKwh())
+	assert.InDelta(t, -2.0, got[13].UsageKwh(), 0.0001)
+	// TOU-D-PRIME summer is off-peak outside 4-9pm, so the battery waits for 4pm.
+	assert.Equal(t, 1.0, got[15].UsageKwh())
+	for h := 16; h < 21; h++ {
+		assert.Equal(t, 0.0, got[h].UsageKwh())
+	}
+	// 4 kWh stay in the battery for the next day.
+	assert.InDelta(t, 5.0, stats.DischargedKwh, 0.0001)
+}
+
+func TestDispatch_NoExport_NothingCharged(t *testing.T) {
+	b := Battery{CapacityKwh: 10, PowerKw: 5, RoundTripEfficiency: 0.9}
+
+	got, stats := Dispatch(flatLoad(summerWeekday, 1), b, costcalculator.NewTouDPrime())
+
+	assert.Equal(t, BatteryStats{}, stats)
+	assert.Equal(t, 1.0, got[17].UsageKwh())
+}
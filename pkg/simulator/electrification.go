@@ -0,0 +1,189 @@
+package simulator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/weather"
+)
+
+// btuPerKwh converts thermal energy.
+const btuPerKwh = 3412.14
+
+// COPPoint is the coefficient of performance of a heat pump at an outdoor temperature.
+type COPPoint struct {
+	TemperatureF float64
+	COP          float64
+}
+
+// COPCurve is a heat pump's efficiency by outdoor temperature, sorted by temperature. Manufacturers publish it at
+// a few rating points, such as 5°F, 17°F and 47°F.
+type COPCurve []COPPoint
+
+// At interpolates the COP at the given temperature, holding it constant outside the curve.
+func (c COPCurve) At(temperatureF float64) float64 {
+	if temperatureF <= c[0].TemperatureF {
+		return c[0].COP
+	}
+	for i := 1; i < len(c); i++ {
+		if temperatureF <= c[i].TemperatureF {
+			fraction := (temperatureF - c[i-1].TemperatureF) / (c[i].TemperatureF - c[i-1].TemperatureF)
+			return c[i-1].COP + fraction*(c[i].COP-c[i-1].COP)
+		}
+	}
+	return c[len(c)-1].COP
+}
+
+// TypicalHeatPumpCOP is a rough curve for a modern ducted heat pump. Use the ratings of the actual unit when known.
+func TypicalHeatPumpCOP() COPCurve {
+	return COPCurve{
+		{TemperatureF: 5, COP: 1.8},
+		{TemperatureF: 17, COP: 2.4},
+		{TemperatureF: 47, COP: 3.6},
+		{TemperatureF: 62, COP: 4.2},
+	}
+}
+
+// HeatPump replaces gas space heating. Its heating need grows linearly as the outdoor temperature drops below the
+// balance point, the temperature at which the house needs no heating.
+type HeatPump struct {
+	// HeatLossBtuPerHourF is the heat the house loses per hour for each °F below the balance point. It can be
+	// estimated from winter gas bills: therms × 100,000 × furnace efficiency / heating degree hours.
+	HeatLossBtuPerHourF float64
+	BalancePointF       float64
+	COP                 COPCurve
+}
+
+// kwhAt returns the electricity used during an hour at the given temperature.
+func (h *HeatPump) kwhAt(temperatureF float64) float64 {
+	if temperatureF >= h.BalancePointF {
+		return 0
+	}
+	thermalKwh := h.HeatLossBtuPerHourF * (h.BalancePointF - temperatureF) / btuPerKwh
+	return thermalKwh / h.COP.At(temperatureF)
+}
+
+// WaterHeater replaces a gas water heater with a heat pump water heater that runs on a schedule.
+type WaterHeater struct {
+	// DailyThermalKwh is the heat delivered to the water each day. A gas water heater's daily therms times 29.3 and
+	// its efficiency gives the same number.
+	DailyThermalKwh float64
+	COP             float64
+	// Hours are the hours of the day in which the heater runs, such as 10-15 to soak up solar production. The
+	// energy is split evenly between them.
+	Hours []int
+}
+
+func (w *WaterHeater) kwhAt(hour int) float64 {
+	for _, h := range w.Hours {
+		if h == hour {
+			return w.DailyThermalKwh / w.COP / float64(len(w.Hours))
+		}
+	}
+	return 0
+}
+
+// Scenario is a set of electrification changes. Nil appliances are not added.
+type Scenario struct {
+	HeatPump    *HeatPump
+	WaterHeater *WaterHeater
+	// AllElectric bills the new load with the all-electric baseline allocation.
+	AllElectric bool
+}
+
+// AddedLoad is the electricity added by a scenario.
+type AddedLoad struct {
+	HeatPumpKwh    float64
+	WaterHeaterKwh float64
+}
+
+func (a *AddedLoad) TotalKwh() float64 {
+	return a.HeatPumpKwh + a.WaterHeaterKwh
+}
+
+// ApplyScenario returns the hourly load with the scenario's appliances added. The heat pump needs a temperature for
+// every hour; the daily mean is used for hours without an observation.
+func ApplyScenario(hours []analyzer.UsageHour, temperatures *weather.Temperatures, s Scenario) ([]analyzer.UsageHour, AddedLoad, error) {
+	out := make([]analyzer.UsageHour, 0, len(hours))
+	added := AddedLoad{}
+	for _, hr := range hours {
+		usage := hr.UsageKwh()
+		fraction := hr.EndTime().Sub(hr.StartTime()).Hours()
+		if s.HeatPump != nil {
+			temperature, ok := temperatures.At(hr.StartTime())
+			if !ok {
+				return nil, AddedLoad{}, fmt.Errorf("no temperature for %s", hr.StartTime().Format("2006-01-02"))
+			}
+			kwh := s.HeatPump.kwhAt(temperature) * fraction
+			added.HeatPumpKwh += kwh
+			usage += kwh
+		}
+		if s.WaterHeater != nil {
+			kwh := s.WaterHeater.kwhAt(hr.StartTime().Hour()) * fraction
+			added.WaterHeaterKwh += kwh
+			usage += kwh
+		}
+		out = append(out, analyzer.NewUsageHour(hr.StartTime(), hr.EndTime(), usage))
+	}
+	return out, added, nil
+}
+
+// ElectrificationResult compares the bill of one plan before and after a scenario.
+type ElectrificationResult struct {
+	Plan   costcalculator.TouPlan
+	Before costcalculator.TouBillSummary
+	After  costcalculator.TouBillSummary
+}
+
+// CostIncrease returns the extra electricity cost of the scenario. Subtract it from the avoided gas cost to get the
+// savings.
+func (r *ElectrificationResult) CostIncrease() float64 {
+	return r.After.TotalCost() - r.Before.TotalCost()
+}
+
+// ElectrificationReport is the outcome of a scenario on several plans.
+type ElectrificationReport struct {
+	Added   AddedLoad
+	Results []ElectrificationResult
+}
+
+// SimulateElectrification applies the scenario and re-bills the load on each plan, sorted by the cost after the
+// change.
+func SimulateElectrification(hours []analyzer.UsageHour, temperatures *weather.Temperatures, s Scenario, plans []costcalculator.TouPlan) (*ElectrificationReport, error) {
+	if s.AllElectric {
+		if err := costcalculator.CheckServiceType(costcalculator.AllElectricService); err != nil {
+			return nil, err
+		}
+	}
+	after, added, err := ApplyScenario(hours, temperatures, s)
+	if err != nil {
+		return nil, err
+	}
+	beforeDays, err := analyzer.SplitByDay(hours)
+	if err != nil {
+		return nil, err
+	}
+	afterDays, err := analyzer.SplitByDay(after)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ElectrificationReport{Added: added, Results: make([]ElectrificationResult, 0, len(plans))}
+	for _, plan := range plans {
+		afterPlan := plan
+		if s.AllElectric {
+			afterPlan = costcalculator.WithServiceType(plan, costcalculator.AllElectricService)
+		}
+		report.Results = append(report.Results, ElectrificationResult{
+			Plan:   plan,
+			Before: costcalculator.CalculateWithTouPlan(beforeDays, plan),
+			After:  costcalculator.CalculateWithTouPlan(afterDays, afterPlan),
+		})
+	}
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].After.TotalCost() < report.Results[j].After.TotalCost()
+	})
+	return report, nil
+}
@@ -0,0 +1,111 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/weather"
+	"github.com/stretchr/testify/assert"
+)
+
+var winterDay = time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+// hourlyLoad returns n days of hourly usage from usage(day, hour).
+func hourlyLoad(start time.Time, n int, usage func(day int, hour int) float64) []analyzer.UsageHour {
+	hours := make([]analyzer.UsageHour, 0)
+	for d := 0; d < n; d++ {
+		for h := 0; h < 24; h++ {
+			s := start.AddDate(0, 0, d).Add(time.Duration(h) * time.Hour)
+			hours = append(hours, analyzer.NewUsageHour(s, s.Add(time.Hour), usage(d, h)))
+		}
+	}
+	return hours
+}
+
+func toDaysOrDie(t *testing.T, hours []analyzer.UsageHour) []analyzer.UsageDay {
+	days, err := analyzer.SplitByDay(hours)
+	assert.NoError(t, err)
+	return days
+}
+
+// flatLoad returns n days of hourly usage at 1 kWh.
+func flatLoad(start time.Time, days int) []analyzer.UsageHour {
+	return hourlyLoad(start, days, func(int, int) float64 { return 1 })
+}
+
+func dailyTemperature(start time.Time, days int, temperatureF float64) *weather.Temperatures {
+	rows := make([]weather.CsvRow, 0)
+	for d := 0; d < days; d++ {
+		rows = append(rows, weather.CsvRow{DateTime: weather.DateTime{Value: start.AddDate(0, 0, d)}, TemperatureF: temperatureF})
+	}
+	return weather.NewTemperatures(rows)
+}
+
+func TestCOPCurve_At_InterpolatesAndClamps(t *testing.T) {
+	curve := COPCurve{{TemperatureF: 17, COP: 2}, {TemperatureF: 47, COP: 3.5}}
+
+	assert.Equal(t, 2.0, curve.At(0))
+	assert.InDelta(t, 2.75, curve.At(32), 0.0001)
+	assert.Equal(t, 3.5, curve.At(70))
+}
+
+func TestApplyScenario_HeatPumpFollowsTemperature(t *testing.T) {
+	hp := &HeatPump{
+		HeatLossBtuPerHourF: btuPerKwh,
+		BalancePointF:       65,
+		COP:                 COPCurve{{TemperatureF: 45, COP: 2}},
+	}
+
+	got, added, err := ApplyScenario(flatLoad(winterDay, 1), dailyTemperature(winterDay, 1, 45), Scenario{HeatPump: hp})
+	assert.NoError(t, err)
+
+	// 20°F below the balance point is 20 kWh of heat per hour, or 10 kWh of electricity at COP 2.
+	assert.InDelta(t, 11.0, got[0].UsageKwh(), 0.0001)
+	assert.InDelta(t, 240.0, added.HeatPumpKwh, 0.0001)
+}
+
+func TestApplyScenario_WaterHeaterRunsOnSchedule(t *testing.T) {
+	wh := &WaterHeater{DailyThermalKwh: 12, COP: 3, Hours: []int{11, 12}}
+
+	got, added, err := ApplyScenario(flatLoad(winterDay, 1), nil, Scenario{WaterHeater: wh})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1.0, got[10].UsageKwh())
+	assert.InDelta(t, 3.0, got[11].UsageKwh(), 0.0001)
+	assert.InDelta(t, 4.0, added.TotalKwh(), 0.0001)
+}
+
+func TestApplyScenario_MissingTemperature_Fails(t *testing.T) {
+	hp := &HeatPump{HeatLossBtuPerHourF: 1000, BalancePointF: 65, COP: TypicalHeatPumpCOP()}
+
+	_, _, err := ApplyScenario(flatLoad(winterDay, 2), dailyTemperature(winterDay, 1, 45), Scenario{HeatPump: hp})
+
+	assert.Error(t, err)
+}
+
+func TestSimulateElectrification_RebillsEachPlan(t *testing.T) {
+	wh := &WaterHeater{DailyThermalKwh: 9, COP: 3, Hours: []int{12}}
+	plans := []costcalculator.TouPlan{costcalculator.NewTouDAPlan(), costcalculator.NewTouDPrime()}
+
+	got, err := SimulateElectrification(flatLoad(winterDay, 7), nil, Scenario{WaterHeater: wh}, plans)
+	assert.NoError(t, err)
+
+	assert.InDelta(t, 21.0, got.Added.TotalKwh(), 0.0001)
+	assert.Len(t, got.Results, 2)
+	for _, r := range got.Results {
+		assert.InDelta(t, 21.0, r.After.NetEnergyUsage()-r.Before.NetEnergyUsage(), 0.0001)
+		assert.Greater(t, r.CostIncrease(), 0.0)
+	}
+	assert.LessOrEqual(t, got.Results[0].After.TotalCost(), got.Results[1].After.TotalCost())
+}
+
+func TestSimulateElectrification_AllElectricAllocationNotSet_Fails(t *testing.T) {
+	wh := &WaterHeater{DailyThermalKwh: 9, COP: 3, Hours: []int{12}}
+
+	_, err := SimulateElectrification(flatLoad(winterDay, 1), nil, Scenario{WaterHeater: wh, AllElectric: true},
+		[]costcalculator.TouPlan{costcalculator.NewTouDAPlan()})
+
+	assert.Error(t, err)
+}
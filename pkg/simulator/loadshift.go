@@ -0,0 +1,269 @@
+package simulator
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/sense"
+)
+
+// ShiftRule describes when a single Sense device is allowed to run.
+type ShiftRule struct {
+	DeviceId string
+
+	// The device may only run in hours of the day within [WindowStartHour, WindowEndHour). The window wraps past
+	// midnight when WindowEndHour is smaller than WindowStartHour (e.g. 21 to 6).
+	WindowStartHour int
+	WindowEndHour   int
+
+	// MaxDelay is the longest a load may be postponed to reach the window. Loads that can't reach the window in
+	// time are left where they were.
+	MaxDelay time.Duration
+
+	// Splittable loads are moved hour by hour. Otherwise, each contiguous run of the device is moved as a block.
+	Splittable bool
+}
+
+func (r *ShiftRule) inWindow(t time.Time) bool {
+	h := t.Hour()
+	if r.WindowStartHour <= r.WindowEndHour {
+		return h >= r.WindowStartHour && h < r.WindowEndHour
+	}
+	return h >= r.WindowStartHour || h < r.WindowEndHour
+}
+
+// ShiftResult is the outcome of applying one or more rules.
+type ShiftResult struct {
+	Rules        []ShiftRule
+	ShiftedKwh   float64
+	UnshiftedKwh float64
+	Bill         costcalculator.TouBillSummary
+	Savings      float64
+}
+
+// LoadShiftReport compares the original bill against the bill after each rule, and after all rules combined.
+type LoadShiftReport struct {
+	Plan     costcalculator.TouPlan
+	Original costcalculator.TouBillSummary
+	PerRule  []ShiftResult
+	Combined ShiftResult
+}
+
+// SimulateLoadShift moves the energy of the devices named in the rules into their allowed windows, rebuilds the
+// whole-house net load from the "mains" and "solar" series and re-bills it with the given plan.
+// byDevice is the output of sense.GroupByDeviceId.
+func SimulateLoadShift(byDevice map[string][]sense.CsvRow, rules []ShiftRule, plan costcalculator.TouPlan) (*LoadShiftReport, error) {
+	house := netLoadByHour(byDevice)
+	if len(house) == 0 {
+		return nil, fmt.Errorf("no mains data found in Sense export")
+	}
+	for _, r := range rules {
+		if _, ok := byDevice[r.DeviceId]; !ok {
+			return nil, fmt.Errorf("no Sense data for device ID '%s'", r.DeviceId)
+		}
+	}
+
+	original, err := billHourlyLoad(house, plan)
+	if err != nil {
+		return nil, err
+	}
+	report := &LoadShiftReport{
+		Plan:     plan,
+		Original: original,
+	}
+
+	for _, r := range rules {
+		result, err := applyRules(house, byDevice, []ShiftRule{r}, plan)
+		if err != nil {
+			return nil, err
+		}
+		result.Savings = original.TotalCost() - result.Bill.TotalCost()
+		report.PerRule = append(report.PerRule, result)
+	}
+	combined, err := applyRules(house, byDevice, rules, plan)
+	if err != nil {
+		return nil, err
+	}
+	combined.Savings = original.TotalCost() - combined.Bill.TotalCost()
+	report.Combined = combined
+	return report, nil
+}
+
+func applyRules(house map[time.Time]float64, byDevice map[string][]sense.CsvRow, rules []ShiftRule, plan costcalculator.TouPlan) (ShiftResult, error) {
+	shifted := make(map[time.Time]float64, len(house))
+	for t, v := range house {
+		shifted[t] = v
+	}
+	result := ShiftResult{Rules: rules}
+	for i := range rules {
+		moved, stuck := shiftDevice(shifted, energyByHour(byDevice[rules[i].DeviceId]), &rules[i])
+		result.ShiftedKwh += moved
+		result.UnshiftedKwh += stuck
+	}
+	bill, err := billHourlyLoad(shifted, plan)
+	if err != nil {
+		return ShiftResult{}, err
+	}
+	result.Bill = bill
+	return result, nil
+}
+
+// shiftDevice moves the device energy within the house load in place. It returns the energy that was moved, and
+// the out-of-window energy that could not be moved. Device hours without a house load, such as hours missing from
+// the mains series, can't be taken out of it and stay where they are. Splittable hours are moved into hours in which
+// the device doesn't run yet, so that it never draws more than in one of its own hours.
+func shiftDevice(house map[time.Time]float64, device map[time.Time]float64, rule *ShiftRule) (float64, float64) {
+	moved, stuck := 0.0, 0.0
+	filled := make(map[time.Time]bool)
+	for t, v := range device {
+		if v > 0 && rule.inWindow(t) {
+			filled[t] = true
+		}
+	}
+	for _, run := range contiguousRuns(device) {
+		outOfWindow := 0.0
+		for _, t := range run {
+			if !rule.inWindow(t) {
+				outOfWindow += device[t]
+			}
+		}
+		if outOfWindow == 0 {
+			continue
+		}
+
+		if rule.Splittable {
+			for _, t := range run {
+				if rule.inWindow(t) {
+					continue
+				}
+				if _, ok := house[t]; !ok {
+					stuck += device[t]
+					continue
+				}
+				delay, ok := findTarget(house, []time.Time{t}, rule, filled)
+				if !ok {
+					stuck += device[t]
+					continue
+				}
+				house[t] -= device[t]
+				house[t.Add(delay)] += device[t]
+				filled[t.Add(delay)] = true
+				moved += device[t]
+			}
+			continue
+		}
+
+		if !hasHouseLoad(house, run) {
+			stuck += outOfWindow
+			continue
+		}
+		delay, ok := findTarget(house, run, rule, nil)
+		if !ok {
+			stuck += outOfWindow
+			continue
+		}
+		for _, t := range run {
+			house[t] -= device[t]
+			house[t.Add(delay)] += device[t]
+			moved += device[t]
+		}
+	}
+	return moved, stuck
+}
+
+// findTarget returns the shortest whole-hour delay that puts every hour of the run within the rule's window, in
+// hours that are not filled.
+func findTarget(house map[time.Time]float64, run []time.Time, rule *ShiftRule, filled map[time.Time]bool) (time.Duration, bool) {
+	for delay := time.Hour; delay <= rule.MaxDelay; delay += time.Hour {
+		fits := true
+		for _, t := range run {
+			target := t.Add(delay)
+			if _, ok := house[target]; !ok || !rule.inWindow(target) || filled[target] {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return delay, true
+		}
+	}
+	return 0, false
+}
+
+// hasHouseLoad returns whether the house has a load for every hour of the run.
+func hasHouseLoad(house map[time.Time]float64, run []time.Time) bool {
+	for _, t := range run {
+		if _, ok := house[t]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// contiguousRuns splits the hours with positive energy into runs of consecutive hours.
+func contiguousRuns(device map[time.Time]float64) [][]time.Time {
+	hours := make([]time.Time, 0, len(device))
+	for t, v := range device {
+		if v > 0 {
+			hours = append(hours, t)
+		}
+	}
+	sort.Slice(hours, func(i, j int) bool {
+		return hours[i].Before(hours[j])
+	})
+
+	runs := make([][]time.Time, 0)
+	for i, t := range hours {
+		if i == 0 || t.Sub(hours[i-1]) != time.Hour {
+			runs = append(runs, make([]time.Time, 0))
+		}
+		runs[len(runs)-1] = append(runs[len(runs)-1], t)
+	}
+	return runs
+}
+
+func energyByHour(rows []sense.CsvRow) map[time.Time]float64 {
+	out := make(map[time.Time]float64)
+	for _, row := range rows {
+		out[truncateToHour(row.DateTime.Value)] += row.EnergyKwh
+	}
+	return out
+}
+
+// netLoadByHour returns the energy drawn from the grid each hour, based on the "mains" and "solar" series.
+func netLoadByHour(byDevice map[string][]sense.CsvRow) map[time.Time]float64 {
+	out := energyByHour(byDevice["mains"])
+	for t, v := range energyByHour(byDevice["solar"]) {
+		if _, ok := out[t]; ok {
+			out[t] += v
+		}
+	}
+	return out
+}
+
+// billHourlyLoad bills an hourly net load with the given plan.
+func billHourlyLoad(load map[time.Time]float64, plan costcalculator.TouPlan) (costcalculator.TouBillSummary, error) {
+	days, err := analyzer.SplitByDay(toUsageHours(load))
+	if err != nil {
+		return costcalculator.TouBillSummary{}, err
+	}
+	return costcalculator.CalculateWithTouPlan(days, plan), nil
+}
+
+func toUsageHours(load map[time.Time]float64) []analyzer.UsageHour {
+	hours := make([]analyzer.UsageHour, 0, len(load))
+	for t, v := range load {
+		hours = append(hours, analyzer.NewUsageHour(t, t.Add(time.Hour), v))
+	}
+	sort.Slice(hours, func(i, j int) bool {
+		return hours[i].StartTime().Before(hours[j].StartTime())
+	})
+	return hours
+}
+
+func truncateToHour(in time.Time) time.Time {
+	return time.Date(in.Year(), in.Month(), in.Day(), in.Hour(), 0, 0, 0, in.Location())
+}
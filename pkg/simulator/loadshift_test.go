@@ -0,0 +1,148 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/kodek/sce-greenbutton/pkg/sense"
+	"github.com/stretchr/testify/assert"
+)
+
+// Monday, Aug 3, 2020.
+var summerWeekday = time.Date(2020, 8, 3, 0, 0, 0, 0, time.UTC)
+
+func row(id string, t time.Time, kwh float64) sense.CsvRow {
+	return sense.CsvRow{DeviceId: id, DateTime: sense.DateTime{Value: t}, EnergyKwh: kwh}
+}
+
+// houseWithDryer returns a day of constant 1kWh mains usage, with a 2-hour 3kWh dryer run starting at 5pm.
+func houseWithDryer() map[string][]sense.CsvRow {
+	out := make(map[string][]sense.CsvRow)
+	for h := 0; h < 24; h++ {
+		t := summerWeekday.Add(time.Duration(h) * time.Hour)
+		usage := 1.0
+		if h == 17 || h == 18 {
+			usage += 3
+			out["dryer"] = append(out["dryer"], row("dryer", t, 3))
+		}
+		out["mains"] = append(out["mains"], row("mains", t, usage))
+		out["solar"] = append(out["solar"], row("solar", t, 0))
+	}
+	return out
+}
+
+func TestSimulateLoadShift_MovesOnPeakLoadIntoWindow_Saves(t *testing.T) {
+	rules := []ShiftRule{
+		{DeviceId: "dryer", WindowStartHour: 21, WindowEndHour: 6, MaxDelay: 6 * time.Hour},
+	}
+
+	got, err := SimulateLoadShift(houseWithDryer(), rules, costcalculator.NewTouDAPlan())
+	assert.NoError(t, err)
+
+	assert.Len(t, got.PerRule, 1)
+	assert.Equal(t, 6.0, got.PerRule[0].ShiftedKwh)
+	assert.Equal(t, 0.0, got.PerRule[0].UnshiftedKwh)
+	assert.Positive(t, got.PerRule[0].Savings)
+	assert.InDelta(t, got.Original.NetEnergyUsage(), got.Combined.Bill.NetEnergyUsage(), 0.0001)
+}
+
+func TestSimulateLoadShift_MaxDelayTooShort_LeavesLoad(t *testing.T) {
+	rules := []ShiftRule{
+		{DeviceId: "dryer", WindowStartHour: 21, WindowEndHour: 6, MaxDelay: 2 * time.Hour},
+	}
+
+	got, err := SimulateLoadShift(houseWithDryer(), rules, costcalculator.NewTouDAPlan())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0.0, got.Combined.ShiftedKwh)
+	assert.Equal(t, 6.0, got.Combined.UnshiftedKwh)
+	assert.Equal(t, 0.0, got.Combined.Savings)
+}
+
+func TestSimulateLoadShift_Splittable_MovesEachHourSeparately(t *testing.T) {
+	// Only the 7pm hour can reach the window within 2 hours.
+	rules := []ShiftRule{
+		{DeviceId: "dryer", WindowStartHour: 20, WindowEndHour: 6, MaxDelay: 2 * time.Hour, Splittable: true},
+	}
+
+	got, err := SimulateLoadShift(houseWithDryer(), rules, costcalculator.NewTouDAPlan())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3.0, got.Combined.ShiftedKwh)
+	assert.Equal(t, 3.0, got.Combined.UnshiftedKwh)
+}
+
+func TestShiftDevice_Splittable_SpreadsRunOverConsecutiveHours(t *testing.T) {
+	house := make(map[time.Time]float64)
+	device := make(map[time.Time]float64)
+	for h := 0; h < 48; h++ {
+		hour := summerWeekday.Add(time.Duration(h) * time.Hour)
+		house[hour] = 1
+		if h >= 17 && h <= 20 {
+			house[hour] += 3
+			device[hour] = 3
+		}
+	}
+	rule := ShiftRule{DeviceId: "dryer", WindowStartHour: 21, WindowEndHour: 6, MaxDelay: 8 * time.Hour, Splittable: true}
+
+	moved, stuck := shiftDevice(house, device, &rule)
+
+	assert.Equal(t, 12.0, moved)
+	assert.Equal(t, 0.0, stuck)
+	for h := 0; h < 48; h++ {
+		want := 1.0
+		if h >= 21 && h <= 24 {
+			want = 4
+		}
+		assert.Equal(t, want, house[summerWeekday.Add(time.Duration(h)*time.Hour)], "hour %d", h)
+	}
+}
+
+func TestSimulateLoadShift_NotSplittable_RunThatDoesNotFitStays(t *testing.T) {
+	// The 6pm hour could reach the window, but the 5pm hour can't, so the run stays.
+	rules := []ShiftRule{
+		{DeviceId: "dryer", WindowStartHour: 20, WindowEndHour: 6, MaxDelay: 2 * time.Hour},
+	}
+
+	got, err := SimulateLoadShift(houseWithDryer(), rules, costcalculator.NewTouDAPlan())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0.0, got.Combined.ShiftedKwh)
+	assert.Equal(t, 6.0, got.Combined.UnshiftedKwh)
+}
+
+func TestSimulateLoadShift_MissingMainsHour_LeavesLoadWithoutExport(t *testing.T) {
+	house := houseWithDryer()
+	// Drop the 6pm mains reading.
+	house["mains"] = append(house["mains"][:18], house["mains"][19:]...)
+
+	for _, splittable := range []bool{true, false} {
+		rules := []ShiftRule{
+			{DeviceId: "dryer", WindowStartHour: 21, WindowEndHour: 6, MaxDelay: 6 * time.Hour, Splittable: splittable},
+		}
+
+		got, err := SimulateLoadShift(house, rules, costcalculator.NewTouDAPlan())
+		assert.NoError(t, err)
+
+		assert.Equal(t, 0.0, got.Combined.Bill.EnergyExported(), "splittable: %v", splittable)
+		assert.Equal(t, 6.0, got.Combined.ShiftedKwh+got.Combined.UnshiftedKwh, "splittable: %v", splittable)
+		assert.InDelta(t, got.Original.NetEnergyUsage(), got.Combined.Bill.NetEnergyUsage(), 0.0001)
+	}
+}
+
+func TestSimulateLoadShift_UnknownDevice_Fails(t *testing.T) {
+	rules := []ShiftRule{{DeviceId: "nope", WindowStartHour: 21, WindowEndHour: 6, MaxDelay: time.Hour}}
+
+	_, err := SimulateLoadShift(houseWithDryer(), rules, costcalculator.NewTouDAPlan())
+
+	assert.Error(t, err)
+}
+
+func TestShiftRule_InWindow_WrapsPastMidnight(t *testing.T) {
+	rule := ShiftRule{WindowStartHour: 21, WindowEndHour: 6}
+
+	assert.True(t, rule.inWindow(summerWeekday.Add(23*time.Hour)))
+	assert.True(t, rule.inWindow(summerWeekday.Add(2*time.Hour)))
+	assert.False(t, rule.inWindow(summerWeekday.Add(12*time.Hour)))
+}
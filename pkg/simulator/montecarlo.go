@@ -0,0 +1,165 @@
+package simulator
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+)
+
+// MonteCarloOptions configures the bootstrap.
+type MonteCarloOptions struct {
+	Samples int
+	// MeanBlockDays is the average number of consecutive historical days copied at once, which keeps multi-day
+	// patterns like heat waves and trips together.
+	MeanBlockDays float64
+	Seed          int64
+}
+
+// DefaultMonteCarloOptions draws 200 samples with blocks of about a week.
+func DefaultMonteCarloOptions() MonteCarloOptions {
+	return MonteCarloOptions{
+		Samples:       200,
+		MeanBlockDays: 7,
+		Seed:          1,
+	}
+}
+
+// MonteCarloResult holds the annualized cost of every sample on every plan.
+type MonteCarloResult struct {
+	Plans []costcalculator.TouPlan
+	// Costs is indexed by plan, then by sample.
+	Costs [][]float64
+}
+
+// CostInterval summarizes a distribution of costs in $/year.
+type CostInterval struct {
+	Mean float64
+	// P5 and P95 bound the 90% interval.
+	P5  float64
+	P95 float64
+}
+
+// PlanComparison compares plan A to plan B.
+type PlanComparison struct {
+	// ProbabilityABeatsB is the fraction of samples in which plan A costs less than plan B.
+	ProbabilityABeatsB float64
+	// Difference is the distribution of plan A's cost minus plan B's cost.
+	Difference CostInterval
+}
+
+// Cost returns the distribution of the cost of plan i.
+func (r *MonteCarloResult) Cost(i int) CostInterval {
+	return interval(r.Costs[i])
+}
+
+// Compare returns how plan a compares to plan b.
+func (r *MonteCarloResult) Compare(a int, b int) PlanComparison {
+	differences := make([]float64, len(r.Costs[a]))
+	wins := 0
+	for s := range differences {
+		differences[s] = r.Costs[a][s] - r.Costs[b][s]
+		if differences[s] < 0 {
+			wins++
+		}
+	}
+	return PlanComparison{
+		ProbabilityABeatsB: float64(wins) / float64(len(differences)),
+		Difference:         interval(differences),
+	}
+}
+
+// ProbabilityCheapest returns, for each plan, the fraction of samples in which it is the cheapest.
+func (r *MonteCarloResult) ProbabilityCheapest() []float64 {
+	out := make([]float64, len(r.Plans))
+	samples := len(r.Costs[0])
+	for s := 0; s < samples; s++ {
+		best := 0
+		for p := range r.Plans {
+			if r.Costs[p][s] < r.Costs[best][s] {
+				best = p
+			}
+		}
+		out[best] += 1 / float64(samples)
+	}
+	return out
+}
+
+// SimulatePlanUncertainty re-bills resampled versions of the historical days on every plan. Each sample keeps the
+// calendar of the historical days, but fills it with blocks of historical days of the same season and day type, so
+// weather and behavior vary while TOU periods stay consistent. Costs are scaled to a year.
+func SimulatePlanUncertainty(days []analyzer.UsageDay, plans []costcalculator.TouPlan, opts MonteCarloOptions) (*MonteCarloResult, error) {
+	if len(days) == 0 {
+		return nil, errors.New("no usage data")
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+	toYear := costcalculator.YearScale(days)
+
+	result := &MonteCarloResult{Plans: plans, Costs: make([][]float64, len(plans))}
+	for s := 0; s < opts.Samples; s++ {
+		sample := bootstrapDays(days, opts.MeanBlockDays, rng)
+		for p, plan := range plans {
+			bill := costcalculator.CalculateWithTouPlan(sample, plan)
+			result.Costs[p] = append(result.Costs[p], bill.TotalCost()*toYear)
+		}
+	}
+	return result, nil
+}
+
+// bootstrapDays returns a stationary block bootstrap of the days, stratified by season and day type. Days must be
+// sorted chronologically.
+func bootstrapDays(days []analyzer.UsageDay, meanBlockDays float64, rng *rand.Rand) []analyzer.UsageDay {
+	strata := make(map[analyzer.ProfileKey][]int)
+	for i, d := range days {
+		key := profileKey(d)
+		strata[key] = append(strata[key], i)
+	}
+
+	out := make([]analyzer.UsageDay, len(days))
+	source := -1
+	for i, slot := range days {
+		key := profileKey(slot)
+		// Continue the current block with the next historical day while it's in the same stratum.
+		next := source + 1
+		if source < 0 || next >= len(days) || profileKey(days[next]) != key || rng.Float64() < 1/meanBlockDays {
+			candidates := strata[key]
+			next = candidates[rng.Intn(len(candidates))]
+		}
+		source = next
+		out[i] = retime(days[source], slot)
+	}
+	return out
+}
+
+func profileKey(d analyzer.UsageDay) analyzer.ProfileKey {
+	return analyzer.ProfileKey{Season: analyzer.SeasonOf(d.Day), DayType: analyzer.DayTypeOf(d.Day)}
+}
+
+// retime moves the readings of day onto the date of slot, keeping their time of day.
+func retime(day analyzer.UsageDay, slot analyzer.UsageDay) analyzer.UsageDay {
+	out := day
+	out.Day = slot.Day
+	out.DataPoints = make([]analyzer.UsageHour, len(day.DataPoints))
+	for i, hr := range day.DataPoints {
+		start := slot.Day.Add(hr.StartTime().Sub(day.Day))
+		out.DataPoints[i] = analyzer.NewUsageHour(start, start.Add(hr.EndTime().Sub(hr.StartTime())), hr.UsageKwh())
+	}
+	return out
+}
+
+func interval(values []float64) CostInterval {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	total := 0.0
+	for _, v := range sorted {
+		total += v
+	}
+	return CostInterval{
+		Mean: total / float64(len(sorted)),
+		P5:   analyzer.Percentile(sorted, 0.05),
+		P95:  analyzer.Percentile(sorted, 0.95),
+	}
+}
@@ -0,0 +1,67 @@
+package simulator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/stretchr/testify/assert"
+)
+
+// variedDays returns n days of constant hourly usage, where day d uses d+1 kWh per hour.
+func variedDays(t *testing.T, start time.Time, n int) []analyzer.UsageDay {
+	return toDaysOrDie(t, hourlyLoad(start, n, func(d int, _ int) float64 { return float64(d + 1) }))
+}
+
+func TestBootstrapDays_KeepsCalendarAndStrata(t *testing.T) {
+	days := variedDays(t, summerWeekday, 28)
+	rng := rand.New(rand.NewSource(1))
+
+	got := bootstrapDays(days, 3, rng)
+
+	assert.Len(t, got, len(days))
+	for i := range got {
+		assert.Equal(t, days[i].Day, got[i].Day)
+		assert.Equal(t, days[i].Day, got[i].DataPoints[0].StartTime())
+		// Usage identifies the source day, which must be of the same day type.
+		source := days[int(got[i].DataPoints[0].UsageKwh())-1]
+		assert.Equal(t, profileKey(days[i]), profileKey(source))
+	}
+}
+
+func TestSimulatePlanUncertainty_SamplesEveryPlan(t *testing.T) {
+	days := variedDays(t, summerWeekday, 28)
+	plans := []costcalculator.TouPlan{costcalculator.NewTouDAPlan(), costcalculator.NewTouDPrime()}
+	opts := DefaultMonteCarloOptions()
+	opts.Samples = 50
+
+	got, err := SimulatePlanUncertainty(days, plans, opts)
+	assert.NoError(t, err)
+
+	assert.Len(t, got.Costs, 2)
+	assert.Len(t, got.Costs[1], 50)
+	cost := got.Cost(0)
+	assert.LessOrEqual(t, cost.P5, cost.Mean)
+	assert.LessOrEqual(t, cost.Mean, cost.P95)
+	ab, ba := got.Compare(0, 1), got.Compare(1, 0)
+	assert.InDelta(t, 1.0, ab.ProbabilityABeatsB+ba.ProbabilityABeatsB, 0.0001)
+	assert.InDelta(t, -ab.Difference.Mean, ba.Difference.Mean, 0.0001)
+	cheapest := got.ProbabilityCheapest()
+	assert.InDelta(t, 1.0, cheapest[0]+cheapest[1], 0.0001)
+}
+
+func TestSimulatePlanUncertainty_SameSeed_SameResult(t *testing.T) {
+	days := variedDays(t, summerWeekday, 14)
+	plans := []costcalculator.TouPlan{costcalculator.NewTouDPrime()}
+	opts := DefaultMonteCarloOptions()
+	opts.Samples = 10
+
+	a, err := SimulatePlanUncertainty(days, plans, opts)
+	assert.NoError(t, err)
+	b, err := SimulatePlanUncertainty(days, plans, opts)
+	assert.NoError(t, err)
+
+	assert.Equal(t, a.Costs, b.Costs)
+}
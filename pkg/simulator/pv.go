@@ -0,0 +1,62 @@
+package simulator
+
+import (
+	"math"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+)
+
+// Defaults for a south-facing array in Southern California.
+const (
+	DefaultLatitude       = 34.3
+	DefaultAnnualKwhPerKw = 1600
+)
+
+// PVModel produces a synthetic clear-sky solar production curve from the sun's elevation, scaled to a yearly yield
+// per kW of panels. It uses wall-clock time as solar time, so production is shifted an hour during daylight saving
+// time when timestamps are local.
+type PVModel struct {
+	Latitude       float64
+	AnnualKwhPerKw float64
+
+	// scale converts the sine of the sun's elevation into kWh per kW.
+	scale float64
+}
+
+// NewPVModel returns a model for the latitude in degrees and the yearly yield in kWh per kW.
+func NewPVModel(latitude float64, annualKwhPerKw float64) *PVModel {
+	m := &PVModel{Latitude: latitude, AnnualKwhPerKw: annualKwhPerKw, scale: 1}
+	// Scale so that a non-leap year adds up to the annual yield.
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	total := 0.0
+	for t := start; t.Year() == 2019; t = t.Add(time.Hour) {
+		total += m.ProductionKwhPerKw(t)
+	}
+	m.scale = annualKwhPerKw / total
+	return m
+}
+
+// ProductionKwhPerKw returns the energy produced by 1 kW of panels in the hour starting at t.
+func (m *PVModel) ProductionKwhPerKw(t time.Time) float64 {
+	declination := 23.44 * math.Sin(2*math.Pi*float64(284+t.YearDay())/365)
+	// Use the middle of the hour.
+	hourAngle := 15 * (float64(t.Hour()) + 0.5 - 12)
+	lat, dec, ha := radians(m.Latitude), radians(declination), radians(hourAngle)
+	sinElevation := math.Sin(lat)*math.Sin(dec) + math.Cos(lat)*math.Cos(dec)*math.Cos(ha)
+	return math.Max(0, sinElevation) * m.scale
+}
+
+// AddPV returns the load minus the production of sizeKw of panels.
+func AddPV(hours []analyzer.UsageHour, model *PVModel, sizeKw float64) []analyzer.UsageHour {
+	out := make([]analyzer.UsageHour, len(hours))
+	for i, hr := range hours {
+		production := sizeKw * model.ProductionKwhPerKw(hr.StartTime()) * hr.EndTime().Sub(hr.StartTime()).Hours()
+		out[i] = analyzer.NewUsageHour(hr.StartTime(), hr.EndTime(), hr.UsageKwh()-production)
+	}
+	return out
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
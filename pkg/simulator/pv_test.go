@@ -0,0 +1,37 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPVModel_AnnualYieldMatches(t *testing.T) {
+	m := NewPVModel(DefaultLatitude, 1600)
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	total := 0.0
+	for h := start; h.Year() == 2021; h = h.Add(time.Hour) {
+		total += m.ProductionKwhPerKw(h)
+	}
+
+	assert.InDelta(t, 1600.0, total, 0.1)
+}
+
+func TestPVModel_NoProductionAtNightAndMoreInSummer(t *testing.T) {
+	m := NewPVModel(DefaultLatitude, DefaultAnnualKwhPerKw)
+
+	assert.Equal(t, 0.0, m.ProductionKwhPerKw(winterDay.Add(2*time.Hour)))
+	assert.Greater(t, m.ProductionKwhPerKw(summerWeekday.Add(12*time.Hour)), m.ProductionKwhPerKw(winterDay.Add(12*time.Hour)))
+}
+
+func TestAddPV_SubtractsProduction(t *testing.T) {
+	m := NewPVModel(DefaultLatitude, DefaultAnnualKwhPerKw)
+	noon := winterDay.Add(12 * time.Hour)
+
+	got := AddPV(flatLoad(winterDay, 1), m, 5)
+
+	assert.Equal(t, 1.0, got[0].UsageKwh())
+	assert.InDelta(t, 1-5*m.ProductionKwhPerKw(noon), got[12].UsageKwh(), 0.0001)
+}
@@ -0,0 +1,187 @@
+package simulator
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+)
+
+// ExportCompensation decides how exported energy is credited.
+type ExportCompensation struct {
+	// NetBilling credits exports at ExportRatePerKwh instead of netting them against imports at retail rates, like
+	// SCE's Net Billing Tariff. When false, the plan's NEM 2 rules apply.
+	NetBilling bool
+	// ExportRatePerKwh is the average export credit under net billing. NBT credits vary by hour and year, so use the
+	// average of the current avoided cost calculator values.
+	ExportRatePerKwh float64
+}
+
+// BillWithExport bills an hourly net load, crediting exports as configured.
+func BillWithExport(hours []analyzer.UsageHour, plan costcalculator.TouPlan, export ExportCompensation) (float64, error) {
+	bill, exportCredit, err := BillBreakdown(hours, plan, export)
+	if err != nil {
+		return 0, err
+	}
+	return bill.TotalCost() - exportCredit, nil
+}
+
+// BillBreakdown bills an hourly net load. Under NEM 2, the bill includes the exports and the export credit is 0.
+// Under net billing, the bill only covers the imports and exports are returned as a separate credit in $.
+func BillBreakdown(hours []analyzer.UsageHour, plan costcalculator.TouPlan, export ExportCompensation) (costcalculator.TouBillSummary, float64, error) {
+	billed := hours
+	exportCredit := 0.0
+	if export.NetBilling {
+		billed = make([]analyzer.UsageHour, len(hours))
+		for i, hr := range hours {
+			billed[i] = analyzer.NewUsageHour(hr.StartTime(), hr.EndTime(), math.Max(0, hr.UsageKwh()))
+			exportCredit += math.Max(0, -hr.UsageKwh()) * export.ExportRatePerKwh
+		}
+	}
+	days, err := analyzer.SplitByDay(billed)
+	if err != nil {
+		return costcalculator.TouBillSummary{}, 0, err
+	}
+	return costcalculator.CalculateWithTouPlan(days, plan), exportCredit, nil
+}
+
+// SizingOptions describes the systems to compare and their costs.
+type SizingOptions struct {
+	PVSizesKw       []float64
+	BatterySizesKwh []float64
+
+	PVCostPerKw       float64
+	BatteryCostPerKwh float64
+	// IncentiveShare is the fraction of the cost covered by incentives, such as 0.3 for the federal tax credit.
+	IncentiveShare float64
+	LifetimeYears  int
+	// DiscountRate is the yearly rate used for the net present value, such as 0.05.
+	DiscountRate float64
+
+	PV *PVModel
+	// BatteryPowerKwPerKwh is the battery's power relative to its capacity, such as 0.5 for a 5 kW 10 kWh battery.
+	BatteryPowerKwPerKwh       float64
+	BatteryRoundTripEfficiency float64
+
+	Plans  []costcalculator.TouPlan
+	Export ExportCompensation
+}
+
+// DefaultSizingOptions returns options for the given plans with typical Southern California production. Costs and
+// incentives must be filled in.
+func DefaultSizingOptions(plans []costcalculator.TouPlan) SizingOptions {
+	return SizingOptions{
+		PVSizesKw:                  []float64{0, 2, 4, 6, 8, 10},
+		BatterySizesKwh:            []float64{0, 5, 10, 15, 20},
+		LifetimeYears:              25,
+		DiscountRate:               0.05,
+		PV:                         NewPVModel(DefaultLatitude, DefaultAnnualKwhPerKw),
+		BatteryPowerKwPerKwh:       0.5,
+		BatteryRoundTripEfficiency: 0.9,
+		Plans:                      plans,
+	}
+}
+
+// SizingCandidate is a simulated system on one plan.
+type SizingCandidate struct {
+	PVKw       float64
+	BatteryKwh float64
+	Plan       costcalculator.TouPlan
+	// UpfrontCost is after incentives.
+	UpfrontCost float64
+	// AnnualSavings compares the yearly bill to the current load billed on the same plan under NEM 2.
+	AnnualSavings float64
+	// PaybackYears is the upfront cost divided by the annual savings, or +Inf if the system doesn't save money.
+	PaybackYears float64
+	NPV          float64
+}
+
+// OptimizeSizing simulates every combination of PV and battery size on every plan over the historical hourly load,
+// and returns them from the highest to the lowest net present value. Savings are scaled from the billed days to a
+// year with costcalculator.YearScale. The combination without PV or battery is skipped.
+func OptimizeSizing(hours []analyzer.UsageHour, opts SizingOptions) ([]SizingCandidate, error) {
+	if len(hours) == 0 {
+		return nil, errors.New("no usage data")
+	}
+	days, err := analyzer.SplitByDay(hours)
+	if err != nil {
+		return nil, err
+	}
+	toYear := costcalculator.YearScale(days)
+
+	out := make([]SizingCandidate, 0)
+	for _, plan := range opts.Plans {
+		baseline, err := BillWithExport(hours, plan, ExportCompensation{})
+		if err != nil {
+			return nil, err
+		}
+		for _, pvKw := range opts.PVSizesKw {
+			withPV := AddPV(hours, opts.PV, pvKw)
+			for _, batteryKwh := range opts.BatterySizesKwh {
+				if pvKw == 0 && batteryKwh == 0 {
+					continue
+				}
+				cost, err := BillWithExport(addBattery(withPV, batteryKwh, plan, opts), plan, opts.Export)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, newCandidate(pvKw, batteryKwh, plan, (baseline-cost)*toYear, opts))
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].NPV > out[j].NPV
+	})
+	return out, nil
+}
+
+// SimulateSystem returns the hourly load with pvKw of panels and a batteryKwh battery, using the PV model and
+// battery characteristics of the options.
+func SimulateSystem(hours []analyzer.UsageHour, pvKw float64, batteryKwh float64, plan costcalculator.TouPlan, opts SizingOptions) []analyzer.UsageHour {
+	return addBattery(AddPV(hours, opts.PV, pvKw), batteryKwh, plan, opts)
+}
+
+func addBattery(hours []analyzer.UsageHour, batteryKwh float64, plan costcalculator.TouPlan, opts SizingOptions) []analyzer.UsageHour {
+	if batteryKwh == 0 {
+		return hours
+	}
+	out, _ := Dispatch(hours, Battery{
+		CapacityKwh:         batteryKwh,
+		PowerKw:             batteryKwh * opts.BatteryPowerKwPerKwh,
+		RoundTripEfficiency: opts.BatteryRoundTripEfficiency,
+	}, plan)
+	return out
+}
+
+func newCandidate(pvKw float64, batteryKwh float64, plan costcalculator.TouPlan, annualSavings float64, opts SizingOptions) SizingCandidate {
+	upfront := (pvKw*opts.PVCostPerKw + batteryKwh*opts.BatteryCostPerKwh) * (1 - opts.IncentiveShare)
+	npv := -upfront
+	for y := 1; y <= opts.LifetimeYears; y++ {
+		npv += annualSavings / math.Pow(1+opts.DiscountRate, float64(y))
+	}
+	payback := math.Inf(1)
+	if annualSavings > 0 {
+		payback = upfront / annualSavings
+	}
+	return SizingCandidate{
+		PVKw:          pvKw,
+		BatteryKwh:    batteryKwh,
+		Plan:          plan,
+		UpfrontCost:   upfront,
+		AnnualSavings: annualSavings,
+		PaybackYears:  payback,
+		NPV:           npv,
+	}
+}
+
+// ByPayback returns the candidates sorted from the shortest to the longest payback.
+func ByPayback(candidates []SizingCandidate) []SizingCandidate {
+	out := make([]SizingCandidate, len(candidates))
+	copy(out, candidates)
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].PaybackYears < out[j].PaybackYears
+	})
+	return out
+}
@@ -0,0 +1,81 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/costcalculator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBillWithExport_NetBillingCreditsExportsAtExportRate(t *testing.T) {
+	plan := costcalculator.NewTouDPrime()
+	hours := solarDay(summerWeekday)
+	importsOnly := make([]analyzer.UsageHour, len(hours))
+	for i, hr := range hours {
+		importsOnly[i] = analyzer.NewUsageHour(hr.StartTime(), hr.EndTime(), math.Max(0, hr.UsageKwh()))
+	}
+
+	got, err := BillWithExport(hours, plan, ExportCompensation{NetBilling: true, ExportRatePerKwh: 0.05})
+	assert.NoError(t, err)
+	importCost, err := BillWithExport(importsOnly, plan, ExportCompensation{})
+	assert.NoError(t, err)
+	netMetered, err := BillWithExport(hours, plan, ExportCompensation{})
+	assert.NoError(t, err)
+
+	assert.InDelta(t, importCost-12*0.05, got, 0.0001)
+	assert.Less(t, netMetered, got)
+}
+
+func TestOptimizeSizing_RanksByNPV(t *testing.T) {
+	hours := make([]analyzer.UsageHour, 0)
+	for d := 0; d < 30; d++ {
+		hours = append(hours, flatLoad(summerWeekday.AddDate(0, 0, d), 1)...)
+	}
+	opts := DefaultSizingOptions([]costcalculator.TouPlan{costcalculator.NewTouDPrime()})
+	opts.PVSizesKw = []float64{0, 3}
+	opts.BatterySizesKwh = []float64{0, 10}
+	opts.PVCostPerKw = 2500
+	opts.BatteryCostPerKwh = 1000
+	opts.IncentiveShare = 0.3
+
+	got, err := OptimizeSizing(hours, opts)
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 3)
+	for i := 1; i < len(got); i++ {
+		assert.GreaterOrEqual(t, got[i-1].NPV, got[i].NPV)
+	}
+	for _, c := range got {
+		if c.PVKw == 0 {
+			// A battery without solar has nothing to charge from.
+			assert.InDelta(t, 0.0, c.AnnualSavings, 0.0001)
+			assert.True(t, math.IsInf(c.PaybackYears, 1))
+			assert.InDelta(t, -7000.0, c.NPV, 0.0001)
+		}
+		if c.PVKw == 3 && c.BatteryKwh == 0 {
+			assert.InDelta(t, 5250.0, c.UpfrontCost, 0.0001)
+			assert.Greater(t, c.AnnualSavings, 0.0)
+			assert.InDelta(t, c.UpfrontCost/c.AnnualSavings, c.PaybackYears, 0.0001)
+		}
+	}
+	assert.Equal(t, 3.0, ByPayback(got)[0].PVKw)
+}
+
+func TestOptimizeSizing_MissingDay_ScalesBilledDaysToYear(t *testing.T) {
+	opts := DefaultSizingOptions([]costcalculator.TouPlan{costcalculator.NewTouDPrime()})
+	opts.PVSizesKw = []float64{3}
+	opts.BatterySizesKwh = []float64{0}
+	withGap := append(flatLoad(summerWeekday, 1), flatLoad(summerWeekday.AddDate(0, 0, 2), 1)...)
+
+	first, err := OptimizeSizing(flatLoad(summerWeekday, 1), opts)
+	assert.NoError(t, err)
+	last, err := OptimizeSizing(flatLoad(summerWeekday.AddDate(0, 0, 2), 1), opts)
+	assert.NoError(t, err)
+	got, err := OptimizeSizing(withGap, opts)
+	assert.NoError(t, err)
+
+	// The missing day is not billed, so it does not dilute the savings of the other two.
+	assert.InDelta(t, (first[0].AnnualSavings+last[0].AnnualSavings)/2, got[0].AnnualSavings, 0.0001)
+}
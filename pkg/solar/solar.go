@@ -0,0 +1,221 @@
+// Package solar estimates solar production from net meter readings and flags outages and degradation.
+package solar
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/kodek/sce-greenbutton/pkg/timebucket"
+)
+
+// Daylight hours in which production is estimated. Production outside these hours is negligible in Southern
+// California.
+const (
+	daylightStartHour = 6
+	daylightEndHour   = 20
+)
+
+// DailyProduction is the estimated solar production of a day.
+type DailyProduction struct {
+	Day time.Time
+	// ProductionKwh assumes that consumption during daylight stays at the overnight baseload, so it is a lower bound:
+	// daytime loads above the baseload hide some of the production.
+	ProductionKwh float64
+	BaseloadKw    float64
+}
+
+// EstimateProduction estimates each day's production as the daylight export against the day's overnight baseload.
+func EstimateProduction(days []analyzer.UsageDay) []DailyProduction {
+	out := make([]DailyProduction, 0, len(days))
+	for _, d := range days {
+		baseload := analyzer.BaseloadKw([]analyzer.UsageDay{d})
+		production := 0.0
+		for _, hr := range d.DataPoints {
+			h := hr.StartTime().Hour()
+			if h < daylightStartHour || h >= daylightEndHour {
+				continue
+			}
+			expectedConsumption := baseload * hr.EndTime().Sub(hr.StartTime()).Hours()
+			production += math.Max(0, expectedConsumption-hr.UsageKwh())
+		}
+		out = append(out, DailyProduction{Day: d.Day, ProductionKwh: production, BaseloadKw: baseload})
+	}
+	return out
+}
+
+// Options tunes outage detection.
+type Options struct {
+	// EnvelopeWindowDays is how many days around the same day of the year, in any year, define its clear-sky
+	// production.
+	EnvelopeWindowDays int
+	// EnvelopePercentile picks the clear-sky production out of the window, leaving out the best days in case of
+	// unusual reflections or readings.
+	EnvelopePercentile float64
+	// DailyThreshold and WeeklyThreshold are the fractions of the clear-sky production below which a day or week
+	// is flagged. Weeks use a higher threshold since a few cloudy days don't bring a whole week down much.
+	DailyThreshold  float64
+	WeeklyThreshold float64
+	// MinExpectedKwh skips periods where hardly any production is expected.
+	MinExpectedKwh float64
+}
+
+// DefaultOptions flags days below 25% and weeks below 60% of the clear-sky production.
+func DefaultOptions() Options {
+	return Options{
+		EnvelopeWindowDays: 15,
+		EnvelopePercentile: 0.9,
+		DailyThreshold:     0.25,
+		WeeklyThreshold:    0.6,
+		MinExpectedKwh:     1,
+	}
+}
+
+// ClearSkyEnvelope returns the expected production of each day on a clear day: a high percentile of the production
+// of the days within opts.EnvelopeWindowDays of the same day of the year.
+func ClearSkyEnvelope(production []DailyProduction, opts Options) []float64 {
+	out := make([]float64, len(production))
+	for i, p := range production {
+		window := make([]float64, 0)
+		for _, other := range production {
+			if dayOfYearDistance(p.Day, other.Day) <= opts.EnvelopeWindowDays {
+				window = append(window, other.ProductionKwh)
+			}
+		}
+		sort.Float64s(window)
+		out[i] = analyzer.Percentile(window, opts.EnvelopePercentile)
+	}
+	return out
+}
+
+// Outage is a day or week with far less production than the clear-sky envelope.
+type Outage struct {
+	Start         time.Time
+	End           time.Time
+	ProductionKwh float64
+	ExpectedKwh   float64
+}
+
+// FindOutages returns the days and the weeks whose production is far below the clear-sky envelope.
+func FindOutages(production []DailyProduction, opts Options) (days []Outage, weeks []Outage, err error) {
+	envelope := ClearSkyEnvelope(production, opts)
+	days = make([]Outage, 0)
+	points := make([]timebucket.Interval, len(production))
+	for i, p := range production {
+		points[i] = &productionDay{production: p, expected: envelope[i]}
+		if envelope[i] >= opts.MinExpectedKwh && p.ProductionKwh < opts.DailyThreshold*envelope[i] {
+			days = append(days, Outage{Start: p.Day, End: p.Day.AddDate(0, 0, 1), ProductionKwh: p.ProductionKwh, ExpectedKwh: envelope[i]})
+		}
+	}
+
+	buckets, err := timebucket.Resample(points, timebucket.Weekly())
+	if err != nil {
+		return nil, nil, err
+	}
+	weeks = make([]Outage, 0)
+	for _, b := range buckets {
+		expected := 0.0
+		for _, p := range b.Points {
+			expected += p.(*productionDay).expected
+		}
+		if expected >= opts.MinExpectedKwh && b.UsageKwh < opts.WeeklyThreshold*expected {
+			weeks = append(weeks, Outage{Start: b.Start, End: b.End, ProductionKwh: b.UsageKwh, ExpectedKwh: expected})
+		}
+	}
+	return days, weeks, nil
+}
+
+// YearOverYear is the change in clear-sky production between two consecutive years.
+type YearOverYear struct {
+	FromYear int
+	ToYear   int
+	// Change is the median relative change of the monthly clear-sky production, such as -0.005 for 0.5%
+	// degradation.
+	Change float64
+	// Months is the number of months present in both years.
+	Months int
+}
+
+// minDaysPerMonth is the fewest days a month needs to be compared across years.
+const minDaysPerMonth = 10
+
+// EstimateDegradation compares the clear-sky production of each month to the same month of the previous year.
+// Using clear days makes the comparison mostly independent of how cloudy each year was.
+func EstimateDegradation(production []DailyProduction, opts Options) []YearOverYear {
+	type yearMonth struct {
+		year  int
+		month time.Month
+	}
+	byMonth := make(map[yearMonth][]float64)
+	years := make(map[int]bool)
+	for _, p := range production {
+		key := yearMonth{year: p.Day.Year(), month: p.Day.Month()}
+		byMonth[key] = append(byMonth[key], p.ProductionKwh)
+		years[p.Day.Year()] = true
+	}
+	clearSky := func(key yearMonth) (float64, bool) {
+		values := byMonth[key]
+		if len(values) < minDaysPerMonth {
+			return 0, false
+		}
+		sorted := make([]float64, len(values))
+		copy(sorted, values)
+		sort.Float64s(sorted)
+		return analyzer.Percentile(sorted, opts.EnvelopePercentile), true
+	}
+
+	sortedYears := make([]int, 0, len(years))
+	for y := range years {
+		sortedYears = append(sortedYears, y)
+	}
+	sort.Ints(sortedYears)
+
+	out := make([]YearOverYear, 0)
+	for i := 1; i < len(sortedYears); i++ {
+		from, to := sortedYears[i-1], sortedYears[i]
+		if to != from+1 {
+			continue
+		}
+		changes := make([]float64, 0)
+		for m := time.January; m <= time.December; m++ {
+			before, ok := clearSky(yearMonth{year: from, month: m})
+			if !ok || before < opts.MinExpectedKwh {
+				continue
+			}
+			after, ok := clearSky(yearMonth{year: to, month: m})
+			if !ok {
+				continue
+			}
+			changes = append(changes, after/before-1)
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		sort.Float64s(changes)
+		out = append(out, YearOverYear{FromYear: from, ToYear: to, Change: analyzer.Percentile(changes, 0.5), Months: len(changes)})
+	}
+	return out
+}
+
+// dayOfYearDistance returns the number of days between the days of the year of a and b, wrapping around new year.
+func dayOfYearDistance(a time.Time, b time.Time) int {
+	d := a.YearDay() - b.YearDay()
+	if d < 0 {
+		d = -d
+	}
+	if d > 183 {
+		d = 366 - d
+	}
+	return d
+}
+
+// productionDay adapts a DailyProduction to a timebucket.Interval.
+type productionDay struct {
+	production DailyProduction
+	expected   float64
+}
+
+func (d *productionDay) UsageKwh() float64    { return d.production.ProductionKwh }
+func (d *productionDay) StartTime() time.Time { return d.production.Day }
+func (d *productionDay) EndTime() time.Time   { return d.production.Day.AddDate(0, 0, 1) }
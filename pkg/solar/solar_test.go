@@ -0,0 +1,102 @@
+package solar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/stretchr/testify/assert"
+)
+
+// monday is the first day of the test data.
+var monday = time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+
+// hourlyLoad returns n days of hourly usage from usage(day, hour).
+func hourlyLoad(start time.Time, n int, usage func(day int, hour int) float64) []analyzer.UsageHour {
+	hours := make([]analyzer.UsageHour, 0)
+	for d := 0; d < n; d++ {
+		for h := 0; h < 24; h++ {
+			s := start.AddDate(0, 0, d).Add(time.Duration(h) * time.Hour)
+			hours = append(hours, analyzer.NewUsageHour(s, s.Add(time.Hour), usage(d, h)))
+		}
+	}
+	return hours
+}
+
+func toDaysOrDie(t *testing.T, hours []analyzer.UsageHour) []analyzer.UsageDay {
+	days, err := analyzer.SplitByDay(hours)
+	assert.NoError(t, err)
+	return days
+}
+
+// netUsage is the usage of a house using 1 kW that produces productionKw(day) between 9am and 3pm.
+func netUsage(productionKw func(day int) float64) func(day int, hour int) float64 {
+	return func(d int, h int) float64 {
+		if h >= 9 && h < 15 {
+			return 1 - productionKw(d)
+		}
+		return 1
+	}
+}
+
+func TestEstimateProduction_DaylightExportAgainstBaseload(t *testing.T) {
+	days := toDaysOrDie(t, hourlyLoad(monday, 1, netUsage(func(int) float64 { return 4 })))
+
+	got := EstimateProduction(days)
+
+	assert.Equal(t, []DailyProduction{{Day: monday, ProductionKwh: 24, BaseloadKw: 1}}, got)
+}
+
+func TestFindOutages_FlagsDayAndWeek(t *testing.T) {
+	days := toDaysOrDie(t, hourlyLoad(monday, 28, netUsage(func(d int) float64 {
+		if d >= 14 && d < 19 {
+			// Inverter failure for most of the third week.
+			return 0
+		}
+		return 4
+	})))
+
+	gotDays, gotWeeks, err := FindOutages(EstimateProduction(days), DefaultOptions())
+	assert.NoError(t, err)
+
+	assert.Len(t, gotDays, 5)
+	assert.Equal(t, monday.AddDate(0, 0, 14), gotDays[0].Start)
+	assert.InDelta(t, 24.0, gotDays[0].ExpectedKwh, 0.0001)
+	assert.Len(t, gotWeeks, 1)
+	assert.Equal(t, monday.AddDate(0, 0, 14), gotWeeks[0].Start)
+	assert.InDelta(t, 48.0, gotWeeks[0].ProductionKwh, 0.0001)
+}
+
+func TestFindOutages_NoSolar_NothingFlagged(t *testing.T) {
+	days := toDaysOrDie(t, hourlyLoad(monday, 14, netUsage(func(int) float64 { return 0 })))
+
+	gotDays, gotWeeks, err := FindOutages(EstimateProduction(days), DefaultOptions())
+	assert.NoError(t, err)
+
+	assert.Empty(t, gotDays)
+	assert.Empty(t, gotWeeks)
+}
+
+func TestEstimateDegradation_ComparesClearSkyProduction(t *testing.T) {
+	production := make([]DailyProduction, 0)
+	for d := 0; d < 2*366; d++ {
+		day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, d)
+		kwh := 20.0
+		if day.Year() == 2021 {
+			kwh = 19.9
+		}
+		if d%5 == 0 {
+			// Cloudy days don't affect the clear-sky comparison.
+			kwh = 5
+		}
+		production = append(production, DailyProduction{Day: day, ProductionKwh: kwh})
+	}
+
+	got := EstimateDegradation(production, DefaultOptions())
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, 2020, got[0].FromYear)
+	assert.Equal(t, 2021, got[0].ToYear)
+	assert.Equal(t, 12, got[0].Months)
+	assert.InDelta(t, -0.005, got[0].Change, 0.0001)
+}
@@ -0,0 +1,227 @@
+// Package timebucket rolls timestamped energy series up into calendar buckets (hours, days, months, etc).
+package timebucket
+
+import (
+	"fmt"
+	"time"
+)
+
+// Interval is a single timestamped energy value. analyzer.UsageHour implements it.
+type Interval interface {
+	UsageKwh() float64
+	StartTime() time.Time
+	EndTime() time.Time
+}
+
+// Sparse is implemented by intervals that may not have data for their whole window, like an hour that is missing
+// some of its 15-minute readings. Other intervals are assumed to cover [StartTime, EndTime).
+type Sparse interface {
+	CoveredDuration() time.Duration
+}
+
+// Resolution defines the bucket boundaries.
+type Resolution interface {
+	// Name is a human-readable name for a single bucket, such as "hour".
+	Name() string
+	// Truncate returns the start of the bucket containing t.
+	Truncate(t time.Time) time.Time
+	// Next returns the start of the bucket that follows the bucket starting at start.
+	Next(start time.Time) time.Time
+}
+
+// Bucket holds the intervals that fall within [Start, End).
+type Bucket struct {
+	Start    time.Time
+	End      time.Time
+	Points   []Interval
+	UsageKwh float64
+	// Covered is the amount of time within the bucket that has data.
+	Covered time.Duration
+}
+
+// Coverage returns the fraction of the bucket that has data.
+func (b *Bucket) Coverage() float64 {
+	return float64(b.Covered) / float64(b.End.Sub(b.Start))
+}
+
+// Complete returns true if the whole bucket has data.
+func (b *Bucket) Complete() bool {
+	return b.Covered >= b.End.Sub(b.Start)
+}
+
+// Resample groups the intervals into buckets of the given resolution. Buckets are returned in the order in which
+// they are first seen, so chronologically sorted input gives chronologically sorted output. Buckets without any
+// intervals are not returned.
+// Every interval must fall within a single bucket.
+func Resample(in []Interval, res Resolution) ([]Bucket, error) {
+	bucketsByStart := make(map[time.Time]*Bucket)
+	// Keep track of the order in which keys were added.
+	sortedKeys := make([]time.Time, 0)
+
+	for _, p := range in {
+		start := res.Truncate(p.StartTime())
+		bucket, ok := bucketsByStart[start]
+		if !ok {
+			sortedKeys = append(sortedKeys, start)
+			bucket = &Bucket{
+				Start:  start,
+				End:    res.Next(start),
+				Points: make([]Interval, 0),
+			}
+			bucketsByStart[start] = bucket
+		}
+		if p.EndTime().After(bucket.End) {
+			return nil, fmt.Errorf("start and end of data point should be within the same %s for %+v", res.Name(), p)
+		}
+		bucket.Points = append(bucket.Points, p)
+		bucket.UsageKwh += p.UsageKwh()
+		bucket.Covered += coveredDuration(p)
+	}
+
+	out := make([]Bucket, 0, len(sortedKeys))
+	for _, k := range sortedKeys {
+		out = append(out, *bucketsByStart[k])
+	}
+	return out, nil
+}
+
+func coveredDuration(p Interval) time.Duration {
+	if s, ok := p.(Sparse); ok {
+		return s.CoveredDuration()
+	}
+	return p.EndTime().Sub(p.StartTime())
+}
+
+type fixedResolution struct {
+	name     string
+	duration time.Duration
+}
+
+// FifteenMinutes returns a resolution of 15-minute buckets aligned to the hour.
+func FifteenMinutes() Resolution {
+	return &fixedResolution{name: "15 minutes", duration: 15 * time.Minute}
+}
+
+// Hourly returns a resolution of hourly buckets.
+func Hourly() Resolution {
+	return &fixedResolution{name: "hour", duration: time.Hour}
+}
+
+func (r *fixedResolution) Name() string { return r.name }
+
+func (r *fixedResolution) Truncate(t time.Time) time.Time {
+	minutes := int(r.duration / time.Minute)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()-t.Minute()%minutes, 0, 0, t.Location())
+}
+
+func (r *fixedResolution) Next(start time.Time) time.Time {
+	return start.Add(r.duration)
+}
+
+type dailyResolution struct{}
+
+// Daily returns a resolution of calendar days.
+func Daily() Resolution {
+	return &dailyResolution{}
+}
+
+func (r *dailyResolution) Name() string { return "day" }
+
+func (r *dailyResolution) Truncate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func (r *dailyResolution) Next(start time.Time) time.Time {
+	return start.AddDate(0, 0, 1)
+}
+
+type weeklyResolution struct{}
+
+// Weekly returns a resolution of weeks starting on Monday.
+func Weekly() Resolution {
+	return &weeklyResolution{}
+}
+
+func (r *weeklyResolution) Name() string { return "week" }
+
+func (r *weeklyResolution) Truncate(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+}
+
+func (r *weeklyResolution) Next(start time.Time) time.Time {
+	return start.AddDate(0, 0, 7)
+}
+
+type monthlyResolution struct{}
+
+// Monthly returns a resolution of calendar months.
+func Monthly() Resolution {
+	return &monthlyResolution{}
+}
+
+func (r *monthlyResolution) Name() string { return "month" }
+
+func (r *monthlyResolution) Truncate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+func (r *monthlyResolution) Next(start time.Time) time.Time {
+	return start.AddDate(0, 1, 0)
+}
+
+type yearlyResolution struct{}
+
+// Yearly returns a resolution of calendar years.
+func Yearly() Resolution {
+	return &yearlyResolution{}
+}
+
+func (r *yearlyResolution) Name() string { return "year" }
+
+func (r *yearlyResolution) Truncate(t time.Time) time.Time {
+	return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+}
+
+func (r *yearlyResolution) Next(start time.Time) time.Time {
+	return start.AddDate(1, 0, 0)
+}
+
+type billingCycleResolution struct {
+	startDay int
+}
+
+// BillingCycle returns a resolution of monthly billing cycles that start on the given day of the month. In months
+// shorter than startDay, the cycle starts on the last day of the month.
+func BillingCycle(startDay int) Resolution {
+	return &billingCycleResolution{startDay: startDay}
+}
+
+func (r *billingCycleResolution) Name() string { return "billing cycle" }
+
+func (r *billingCycleResolution) Truncate(t time.Time) time.Time {
+	start := r.cycleStart(t.Year(), t.Month(), t.Location())
+	if t.Before(start) {
+		start = r.cycleStart(t.Year(), t.Month()-1, t.Location())
+	}
+	return start
+}
+
+func (r *billingCycleResolution) Next(start time.Time) time.Time {
+	// The start of the cycle may have been clamped, so use the first of the month to find the next month.
+	return r.cycleStart(start.Year(), start.Month()+1, start.Location())
+}
+
+// cycleStart returns the start of the cycle within the given month. Months outside 1-12 are normalized.
+func (r *billingCycleResolution) cycleStart(year int, month time.Month, loc *time.Location) time.Time {
+	firstOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	day := r.startDay
+	if day > lastDay {
+		day = lastDay
+	}
+	if day < 1 {
+		day = 1
+	}
+	return time.Date(firstOfMonth.Year(), firstOfMonth.Month(), day, 0, 0, 0, 0, loc)
+}
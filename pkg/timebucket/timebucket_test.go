@@ -0,0 +1,109 @@
+package timebucket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type point struct {
+	start time.Time
+	end   time.Time
+	kwh   float64
+}
+
+func (p *point) UsageKwh() float64    { return p.kwh }
+func (p *point) StartTime() time.Time { return p.start }
+func (p *point) EndTime() time.Time   { return p.end }
+
+func hourAt(t time.Time, kwh float64) Interval {
+	return &point{start: t, end: t.Add(time.Hour), kwh: kwh}
+}
+
+var jan1 = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestResample_Daily_SumsAndKeepsOrder(t *testing.T) {
+	in := []Interval{
+		hourAt(jan1.Add(1*time.Hour), 1),
+		hourAt(jan1.Add(2*time.Hour), 2),
+		hourAt(jan1.Add(25*time.Hour), 4),
+	}
+
+	got, err := Resample(in, Daily())
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 2)
+	assert.Equal(t, jan1, got[0].Start)
+	assert.Equal(t, jan1.AddDate(0, 0, 1), got[0].End)
+	assert.Equal(t, 3.0, got[0].UsageKwh)
+	assert.Equal(t, in[:2], got[0].Points)
+	assert.Equal(t, 4.0, got[1].UsageKwh)
+}
+
+func TestResample_PartialBucket_IsIncomplete(t *testing.T) {
+	in := make([]Interval, 0)
+	for h := 0; h < 20; h++ {
+		in = append(in, hourAt(jan1.Add(time.Duration(h)*time.Hour), 1))
+	}
+
+	got, err := Resample(in, Daily())
+	assert.NoError(t, err)
+
+	assert.Len(t, got, 1)
+	assert.False(t, got[0].Complete())
+	assert.InEpsilon(t, 20.0/24, got[0].Coverage(), 0.0001)
+}
+
+func TestResample_FullBucket_IsComplete(t *testing.T) {
+	in := []Interval{hourAt(jan1, 1)}
+
+	got, err := Resample(in, Hourly())
+	assert.NoError(t, err)
+
+	assert.True(t, got[0].Complete())
+}
+
+func TestResample_IntervalCrossesBucket_Fails(t *testing.T) {
+	in := []Interval{hourAt(jan1.Add(30*time.Minute), 1)}
+
+	_, err := Resample(in, Hourly())
+
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "within the same hour")
+	}
+}
+
+func TestResolutions_Truncate(t *testing.T) {
+	// Thursday, Mar 19, 2020.
+	at := time.Date(2020, 3, 19, 13, 47, 12, 0, time.UTC)
+	tests := []struct {
+		name string
+		res  Resolution
+		want time.Time
+	}{
+		{"15 minutes", FifteenMinutes(), time.Date(2020, 3, 19, 13, 45, 0, 0, time.UTC)},
+		{"hour", Hourly(), time.Date(2020, 3, 19, 13, 0, 0, 0, time.UTC)},
+		{"day", Daily(), time.Date(2020, 3, 19, 0, 0, 0, 0, time.UTC)},
+		{"week", Weekly(), time.Date(2020, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"month", Monthly(), time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"year", Yearly(), time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"billing cycle after start day", BillingCycle(10), time.Date(2020, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{"billing cycle before start day", BillingCycle(25), time.Date(2020, 2, 25, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.res.Truncate(at))
+		})
+	}
+}
+
+func TestBillingCycle_ShortMonth_ClampsToLastDay(t *testing.T) {
+	res := BillingCycle(31)
+	feb := time.Date(2021, 2, 28, 12, 0, 0, 0, time.UTC)
+
+	start := res.Truncate(feb)
+
+	assert.Equal(t, time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2021, 3, 31, 0, 0, 0, 0, time.UTC), res.Next(start))
+}
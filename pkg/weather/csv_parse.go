@@ -0,0 +1,53 @@
+// Package weather reads local temperature records and relates daily energy usage to the weather.
+package weather
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/gocarina/gocsv"
+)
+
+// CsvRow is a single temperature observation. Files may have hourly or daily observations.
+// Header:
+// DateTime,Temperature
+// where DateTime is "2006-01-02 15:04:05" or "2006-01-02" and Temperature is in °F.
+type CsvRow struct {
+	DateTime     DateTime `csv:"DateTime"`
+	TemperatureF float64  `csv:"Temperature"`
+}
+
+type DateTime struct {
+	Value time.Time
+}
+
+func (date *DateTime) UnmarshalCSV(csv string) error {
+	layout := "2006-01-02 15:04:05"
+	if len(csv) == len("2006-01-02") {
+		layout = "2006-01-02"
+	}
+	um, err := time.ParseInLocation(layout, csv, time.UTC)
+	if err != nil {
+		return err
+	}
+	date.Value = um
+	return nil
+}
+
+// ParseCSV parses a weather file. Lines starting with # are ignored.
+func ParseCSV(fileIn string) ([]CsvRow, error) {
+	gocsv.SetCSVReader(func(reader io.Reader) gocsv.CSVReader {
+		r := csv.NewReader(reader)
+		r.Comment = '#'
+		r.FieldsPerRecord = 2
+		r.TrimLeadingSpace = true
+		return r
+	})
+
+	out := make([]CsvRow, 0)
+	if err := gocsv.UnmarshalString(fileIn, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCSV_HourlyAndDaily(t *testing.T) {
+	in := `# Exported from a home weather station
+DateTime,Temperature
+2020-01-01 00:00:00,50.5
+2020-01-02,60`
+
+	got, err := ParseCSV(in)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []CsvRow{
+		{DateTime: DateTime{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}, TemperatureF: 50.5},
+		{DateTime: DateTime{time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}, TemperatureF: 60},
+	}, got)
+}
+
+func TestParseCSV_BadDate_Fails(t *testing.T) {
+	in := `DateTime,Temperature
+01/01/2020,50`
+
+	_, err := ParseCSV(in)
+
+	assert.Error(t, err)
+}
+
+func TestDailyDegreeDays_AveragesObservations(t *testing.T) {
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	in := []CsvRow{
+		{DateTime: DateTime{day}, TemperatureF: 50},
+		{DateTime: DateTime{day.Add(12 * time.Hour)}, TemperatureF: 60},
+		{DateTime: DateTime{day.AddDate(0, 0, 1)}, TemperatureF: 75},
+	}
+
+	got := DailyDegreeDays(in, BaseTemperatureF)
+
+	assert.Equal(t, []DegreeDays{
+		{Day: day, MeanF: 55, MinF: 50, MaxF: 60, HeatingDegree: 10, CoolingDegree: 0},
+		{Day: day.AddDate(0, 0, 1), MeanF: 75, MinF: 75, MaxF: 75, HeatingDegree: 0, CoolingDegree: 10},
+	}, got)
+}
+
+func TestNormalWeather_AveragesEachCalendarDay(t *testing.T) {
+	in := []DegreeDays{
+		{Day: time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC), MeanF: 60, MinF: 50, MaxF: 70, HeatingDegree: 5},
+		{Day: time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC), MeanF: 40, HeatingDegree: 25},
+		{Day: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), MeanF: 70, MinF: 60, MaxF: 80, CoolingDegree: 5},
+		{Day: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), MeanF: 50, MinF: 50, MaxF: 50, HeatingDegree: 15},
+	}
+
+	got := NormalWeather(in)
+
+	assert.Equal(t, []DegreeDays{
+		{Day: time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC), MeanF: 50, MinF: 50, MaxF: 50, HeatingDegree: 15},
+		{Day: time.Date(2001, 3, 1, 0, 0, 0, 0, time.UTC), MeanF: 65, MinF: 55, MaxF: 75, HeatingDegree: 2.5, CoolingDegree: 2.5},
+	}, got)
+}
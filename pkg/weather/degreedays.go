@@ -0,0 +1,92 @@
+package weather
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// BaseTemperatureF is the customary balance point for degree days: no heating or cooling is needed at 65°F.
+const BaseTemperatureF = 65.0
+
+// DegreeDays is the weather of a single day.
+type DegreeDays struct {
+	Day           time.Time
+	MeanF         float64
+	MinF          float64
+	MaxF          float64
+	HeatingDegree float64
+	CoolingDegree float64
+}
+
+// DailyDegreeDays averages the observations of each day and computes heating and cooling degree days against the
+// base temperature. Days are returned in the order they first appear.
+func DailyDegreeDays(rows []CsvRow, baseF float64) []DegreeDays {
+	out := make([]DegreeDays, 0)
+	counts := make([]int, 0)
+	index := make(map[time.Time]int)
+	for _, r := range rows {
+		t := r.DateTime.Value
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		i, ok := index[day]
+		if !ok {
+			i = len(out)
+			index[day] = i
+			out = append(out, DegreeDays{Day: day, MinF: math.Inf(1), MaxF: math.Inf(-1)})
+			counts = append(counts, 0)
+		}
+		out[i].MeanF += r.TemperatureF
+		out[i].MinF = math.Min(out[i].MinF, r.TemperatureF)
+		out[i].MaxF = math.Max(out[i].MaxF, r.TemperatureF)
+		counts[i]++
+	}
+	for i := range out {
+		out[i].MeanF /= float64(counts[i])
+		out[i].HeatingDegree = math.Max(0, baseF-out[i].MeanF)
+		out[i].CoolingDegree = math.Max(0, out[i].MeanF-baseF)
+	}
+	return out
+}
+
+// normalYear is the year of the days returned by NormalWeather. It is not a leap year.
+const normalYear = 2001
+
+// NormalWeather returns the normal weather of each calendar day: the average over all years of the degree days of
+// that day. Days are dated in 2001 and sorted. February 29 is left out, so a full year of weather gives 365 days.
+func NormalWeather(weather []DegreeDays) []DegreeDays {
+	sums := make(map[time.Time]*DegreeDays)
+	counts := make(map[time.Time]int)
+	for _, dd := range weather {
+		if dd.Day.Month() == time.February && dd.Day.Day() == 29 {
+			continue
+		}
+		day := time.Date(normalYear, dd.Day.Month(), dd.Day.Day(), 0, 0, 0, 0, time.UTC)
+		sum, ok := sums[day]
+		if !ok {
+			sum = &DegreeDays{Day: day}
+			sums[day] = sum
+		}
+		sum.MeanF += dd.MeanF
+		sum.MinF += dd.MinF
+		sum.MaxF += dd.MaxF
+		sum.HeatingDegree += dd.HeatingDegree
+		sum.CoolingDegree += dd.CoolingDegree
+		counts[day]++
+	}
+	out := make([]DegreeDays, 0, len(sums))
+	for day, sum := range sums {
+		n := float64(counts[day])
+		out = append(out, DegreeDays{
+			Day:           day,
+			MeanF:         sum.MeanF / n,
+			MinF:          sum.MinF / n,
+			MaxF:          sum.MaxF / n,
+			HeatingDegree: sum.HeatingDegree / n,
+			CoolingDegree: sum.CoolingDegree / n,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Day.Before(out[j].Day)
+	})
+	return out
+}
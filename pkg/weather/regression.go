@@ -0,0 +1,232 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+)
+
+// minFitDays is the fewest days with both usage and weather needed to fit a Model.
+const minFitDays = 7
+
+// Model explains daily usage as a base load plus a linear response to heating and cooling degree days:
+// UsageKwh = BaseKwh + HeatingKwhPerDegree*HDD + CoolingKwhPerDegree*CDD.
+type Model struct {
+	BaseKwh             float64
+	HeatingKwhPerDegree float64
+	CoolingKwhPerDegree float64
+	Days                int
+	RSquared            float64
+	// ResidualStdDev is the standard deviation of the difference between actual and predicted usage.
+	ResidualStdDev float64
+}
+
+// Predict returns the expected usage of a day with the given weather.
+func (m *Model) Predict(dd DegreeDays) float64 {
+	return m.BaseKwh + m.HeatingKwhPerDegree*dd.HeatingDegree + m.CoolingKwhPerDegree*dd.CoolingDegree
+}
+
+// NormalizedUsage returns the usage the model expects over days with the given weather, such as a typical year.
+func (m *Model) NormalizedUsage(weather []DegreeDays) float64 {
+	total := 0.0
+	for _, dd := range weather {
+		total += m.Predict(dd)
+	}
+	return total
+}
+
+// observation is a day with both usage and weather.
+type observation struct {
+	day      time.Time
+	usageKwh float64
+	weather  DegreeDays
+}
+
+// join matches usage days to the weather of the same calendar date. Days without weather are skipped.
+func join(days []analyzer.UsageDay, weather []DegreeDays) []observation {
+	byDate := make(map[string]DegreeDays, len(weather))
+	for _, dd := range weather {
+		byDate[dd.Day.Format("2006-01-02")] = dd
+	}
+	out := make([]observation, 0, len(days))
+	for _, d := range days {
+		if dd, ok := byDate[d.Day.Format("2006-01-02")]; ok {
+			out = append(out, observation{day: d.Day, usageKwh: d.UsageKwh, weather: dd})
+		}
+	}
+	return out
+}
+
+// Fit fits a Model to the days that have weather, using ordinary least squares. Heating or cooling terms are left
+// at 0 when the weather has no heating or cooling degree days.
+func Fit(days []analyzer.UsageDay, weather []DegreeDays) (Model, error) {
+	obs := join(days, weather)
+	if len(obs) < minFitDays {
+		return Model{}, fmt.Errorf("need at least %d days with usage and weather to fit a model, got %d", minFitDays, len(obs))
+	}
+
+	hasHeating, hasCooling := false, false
+	for _, o := range obs {
+		hasHeating = hasHeating || o.weather.HeatingDegree > 0
+		hasCooling = hasCooling || o.weather.CoolingDegree > 0
+	}
+	features := func(dd DegreeDays) []float64 {
+		x := []float64{1}
+		if hasHeating {
+			x = append(x, dd.HeatingDegree)
+		}
+		if hasCooling {
+			x = append(x, dd.CoolingDegree)
+		}
+		return x
+	}
+
+	x := make([][]float64, len(obs))
+	y := make([]float64, len(obs))
+	for i, o := range obs {
+		x[i] = features(o.weather)
+		y[i] = o.usageKwh
+	}
+	coefficients, err := leastSquares(x, y)
+	if err != nil {
+		return Model{}, err
+	}
+
+	model := Model{BaseKwh: coefficients[0], Days: len(obs)}
+	next := 1
+	if hasHeating {
+		model.HeatingKwhPerDegree = coefficients[next]
+		next++
+	}
+	if hasCooling {
+		model.CoolingKwhPerDegree = coefficients[next]
+	}
+
+	mean := 0.0
+	for _, v := range y {
+		mean += v
+	}
+	mean /= float64(len(y))
+	residualSquares, totalSquares := 0.0, 0.0
+	for _, o := range obs {
+		residual := o.usageKwh - model.Predict(o.weather)
+		residualSquares += residual * residual
+		totalSquares += (o.usageKwh - mean) * (o.usageKwh - mean)
+	}
+	if totalSquares > 0 {
+		model.RSquared = 1 - residualSquares/totalSquares
+	}
+	if dof := len(obs) - len(coefficients); dof > 0 {
+		model.ResidualStdDev = math.Sqrt(residualSquares / float64(dof))
+	}
+	return model, nil
+}
+
+// leastSquares solves the normal equations (XᵀX)b = Xᵀy with Gaussian elimination.
+func leastSquares(x [][]float64, y []float64) ([]float64, error) {
+	n := len(x[0])
+	// Augmented matrix [XᵀX | Xᵀy].
+	a := make([][]float64, n)
+	for i := range a {
+		a[i] = make([]float64, n+1)
+	}
+	for r := range x {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				a[i][j] += x[r][i] * x[r][j]
+			}
+			a[i][n] += x[r][i] * y[r]
+		}
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return nil, errors.New("weather does not vary enough to fit a model")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		for r := 0; r < n; r++ {
+			if r == col {
+				continue
+			}
+			factor := a[r][col] / a[col][col]
+			for c := col; c <= n; c++ {
+				a[r][c] -= factor * a[col][c]
+			}
+		}
+	}
+
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = a[i][n] / a[i][i]
+	}
+	return out, nil
+}
+
+// Savings compares a reporting period to what the baseline model expects under the reporting period's weather.
+type Savings struct {
+	Days int
+	// ActualKwh is the usage of the reporting period.
+	ActualKwh float64
+	// AdjustedBaselineKwh is the usage the baseline model expects under the reporting period's weather.
+	AdjustedBaselineKwh float64
+}
+
+// SavingsKwh returns the usage avoided compared to the baseline. It is negative if usage went up.
+func (s *Savings) SavingsKwh() float64 {
+	return s.AdjustedBaselineKwh - s.ActualKwh
+}
+
+// SavingsShare returns the savings as a fraction of the adjusted baseline.
+func (s *Savings) SavingsShare() float64 {
+	if s.AdjustedBaselineKwh == 0 {
+		return 0
+	}
+	return s.SavingsKwh() / s.AdjustedBaselineKwh
+}
+
+// CalculateSavings returns the weather-adjusted savings of the reporting days compared to a model fitted on a
+// baseline period. Days without weather are skipped.
+func CalculateSavings(baseline Model, reporting []analyzer.UsageDay, weather []DegreeDays) Savings {
+	out := Savings{}
+	for _, o := range join(reporting, weather) {
+		out.Days++
+		out.ActualKwh += o.usageKwh
+		out.AdjustedBaselineKwh += baseline.Predict(o.weather)
+	}
+	return out
+}
+
+// Outlier is a day whose usage doesn't match the model.
+type Outlier struct {
+	Day          time.Time
+	ActualKwh    float64
+	PredictedKwh float64
+	// ZScore is the difference between actual and predicted usage in residual standard deviations.
+	ZScore float64
+}
+
+// FindOutliers returns the days whose usage differs from the model's prediction by more than threshold residual
+// standard deviations, such as 3.
+func FindOutliers(m Model, days []analyzer.UsageDay, weather []DegreeDays, threshold float64) []Outlier {
+	out := make([]Outlier, 0)
+	if m.ResidualStdDev == 0 {
+		return out
+	}
+	for _, o := range join(days, weather) {
+		predicted := m.Predict(o.weather)
+		z := (o.usageKwh - predicted) / m.ResidualStdDev
+		if math.Abs(z) > threshold {
+			out = append(out, Outlier{Day: o.day, ActualKwh: o.usageKwh, PredictedKwh: predicted, ZScore: z})
+		}
+	}
+	return out
+}
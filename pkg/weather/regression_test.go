@@ -0,0 +1,107 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kodek/sce-greenbutton/pkg/analyzer"
+	"github.com/stretchr/testify/assert"
+)
+
+var jan1 = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// syntheticYear returns days whose usage is exactly 10 kWh + 0.5 kWh/HDD + 2 kWh/CDD, with mean temperatures
+// cycling between 45°F and 85°F.
+func syntheticYear(start time.Time, n int) ([]analyzer.UsageDay, []DegreeDays) {
+	days := make([]analyzer.UsageDay, 0, n)
+	weather := make([]DegreeDays, 0, n)
+	for i := 0; i < n; i++ {
+		day := start.AddDate(0, 0, i)
+		rows := []CsvRow{{DateTime: DateTime{day}, TemperatureF: 45 + float64(i%41)}}
+		dd := DailyDegreeDays(rows, BaseTemperatureF)[0]
+		weather = append(weather, dd)
+		days = append(days, analyzer.UsageDay{Day: day, UsageKwh: 10 + 0.5*dd.HeatingDegree + 2*dd.CoolingDegree})
+	}
+	return days, weather
+}
+
+func TestFit_RecoversCoefficients(t *testing.T) {
+	days, weather := syntheticYear(jan1, 100)
+
+	got, err := Fit(days, weather)
+	assert.NoError(t, err)
+
+	assert.InDelta(t, 10.0, got.BaseKwh, 0.0001)
+	assert.InDelta(t, 0.5, got.HeatingKwhPerDegree, 0.0001)
+	assert.InDelta(t, 2.0, got.CoolingKwhPerDegree, 0.0001)
+	assert.InDelta(t, 1.0, got.RSquared, 0.0001)
+	assert.Equal(t, 100, got.Days)
+}
+
+func TestFit_NoCoolingDays_CoolingTermIsZero(t *testing.T) {
+	days := make([]analyzer.UsageDay, 0)
+	weather := make([]DegreeDays, 0)
+	for i := 0; i < 10; i++ {
+		day := jan1.AddDate(0, 0, i)
+		weather = append(weather, DegreeDays{Day: day, HeatingDegree: float64(i)})
+		days = append(days, analyzer.UsageDay{Day: day, UsageKwh: 5 + float64(i)})
+	}
+
+	got, err := Fit(days, weather)
+	assert.NoError(t, err)
+
+	assert.InDelta(t, 5.0, got.BaseKwh, 0.0001)
+	assert.InDelta(t, 1.0, got.HeatingKwhPerDegree, 0.0001)
+	assert.Equal(t, 0.0, got.CoolingKwhPerDegree)
+}
+
+func TestFit_TooFewDays_Fails(t *testing.T) {
+	days, weather := syntheticYear(jan1, 3)
+
+	_, err := Fit(days, weather)
+
+	assert.Error(t, err)
+}
+
+func TestFit_ConstantWeather_Fails(t *testing.T) {
+	days, _ := syntheticYear(jan1, 10)
+	weather := make([]DegreeDays, 0)
+	for _, d := range days {
+		weather = append(weather, DegreeDays{Day: d.Day, HeatingDegree: 5})
+	}
+
+	_, err := Fit(days, weather)
+
+	assert.Error(t, err)
+}
+
+func TestCalculateSavings_AdjustsForWeather(t *testing.T) {
+	model := Model{BaseKwh: 10, CoolingKwhPerDegree: 2}
+	reporting := []analyzer.UsageDay{{Day: jan1, UsageKwh: 27}}
+	weather := []DegreeDays{{Day: jan1, CoolingDegree: 10}}
+
+	got := CalculateSavings(model, reporting, weather)
+
+	assert.Equal(t, 1, got.Days)
+	assert.InDelta(t, 30.0, got.AdjustedBaselineKwh, 0.0001)
+	assert.InDelta(t, 3.0, got.SavingsKwh(), 0.0001)
+	assert.InDelta(t, 0.1, got.SavingsShare(), 0.0001)
+}
+
+func TestFindOutliers_FlagsDaysFarFromPrediction(t *testing.T) {
+	model := Model{BaseKwh: 10, ResidualStdDev: 1}
+	days := []analyzer.UsageDay{{Day: jan1, UsageKwh: 11}, {Day: jan1.AddDate(0, 0, 1), UsageKwh: 20}}
+	weather := []DegreeDays{{Day: jan1}, {Day: jan1.AddDate(0, 0, 1)}}
+
+	got := FindOutliers(model, days, weather, 3)
+
+	assert.Equal(t, []Outlier{{Day: jan1.AddDate(0, 0, 1), ActualKwh: 20, PredictedKwh: 10, ZScore: 10}}, got)
+}
+
+func TestModel_NormalizedUsage_SumsPredictions(t *testing.T) {
+	model := Model{BaseKwh: 10, HeatingKwhPerDegree: 1}
+
+	got := model.NormalizedUsage([]DegreeDays{{HeatingDegree: 5}, {}})
+
+	assert.Equal(t, 25.0, got)
+}
@@ -0,0 +1,46 @@
+package weather
+
+import "time"
+
+// Temperatures looks up the temperature at a given time from hourly or daily observations.
+// Lookups use the wall-clock date and hour, so observations and usage don't need to share a time zone.
+type Temperatures struct {
+	hourly   map[string]float64
+	dailySum map[string]float64
+	dailyN   map[string]int
+}
+
+// NewTemperatures indexes the observations. Several observations in the same hour are averaged.
+func NewTemperatures(rows []CsvRow) *Temperatures {
+	out := &Temperatures{
+		hourly:   make(map[string]float64),
+		dailySum: make(map[string]float64),
+		dailyN:   make(map[string]int),
+	}
+	hourlyN := make(map[string]int)
+	for _, r := range rows {
+		hour := r.DateTime.Value.Format("2006-01-02 15")
+		out.hourly[hour] += r.TemperatureF
+		hourlyN[hour]++
+		day := r.DateTime.Value.Format("2006-01-02")
+		out.dailySum[day] += r.TemperatureF
+		out.dailyN[day]++
+	}
+	for hour, n := range hourlyN {
+		out.hourly[hour] /= float64(n)
+	}
+	return out
+}
+
+// At returns the temperature in °F during the hour containing t, or the mean temperature of the day if there is no
+// observation for that hour. It returns false if there is no observation for the day.
+func (t *Temperatures) At(when time.Time) (float64, bool) {
+	if v, ok := t.hourly[when.Format("2006-01-02 15")]; ok {
+		return v, true
+	}
+	day := when.Format("2006-01-02")
+	if n := t.dailyN[day]; n > 0 {
+		return t.dailySum[day] / float64(n), true
+	}
+	return 0, false
+}
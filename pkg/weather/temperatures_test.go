@@ -0,0 +1,27 @@
+package weather
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemperatures_At_FallsBackToDailyMean(t *testing.T) {
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	temps := NewTemperatures([]CsvRow{
+		{DateTime: DateTime{day.Add(6 * time.Hour)}, TemperatureF: 40},
+		{DateTime: DateTime{day.Add(14 * time.Hour)}, TemperatureF: 60},
+	})
+
+	got, ok := temps.At(day.Add(6*time.Hour + 30*time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, 40.0, got)
+
+	got, ok = temps.At(day.Add(20 * time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, 50.0, got)
+
+	_, ok = temps.At(day.AddDate(0, 0, 1))
+	assert.False(t, ok)
+}